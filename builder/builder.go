@@ -0,0 +1,239 @@
+package builder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/EclesioMeloJunior/wasvm/parser"
+)
+
+var ErrImportAfterDefinition = errors.New("import added after a definition of the same kind")
+
+// ModuleBuilder creates modules without the need of the text format and
+// external tools, the module can be retrieved as the parser model or
+// encoded in the binary format. The methods that add an entity return its
+// index so it can be referenced by the instructions of the module
+type ModuleBuilder struct {
+	types []*parser.FunctionSignatureParser
+
+	imports         []*parser.Import
+	importedFuncs   []*parser.Function
+	importedMems    int
+	importedGlobals int
+
+	funcs    []*parser.Function
+	memories []*parser.MemoryType
+	globals  []*parser.Global
+	exports  []*parser.Export
+	data     []*parser.Data
+	start    *int
+
+	// err is the first error found while building, as imports take the
+	// first positions of the index spaces they must be added before the
+	// definitions of the same kind, otherwise the returned indexes are wrong
+	err error
+}
+
+func New() *ModuleBuilder {
+	return &ModuleBuilder{}
+}
+
+// AddType returns the index of the function type, the type
+// is only added when there is no equal type in the module
+func (b *ModuleBuilder) AddType(params, results []parser.Type) uint32 {
+	signature := parser.NewFunctionSignature(params, results)
+	for idx, t := range b.types {
+		if t.Equal(signature) {
+			return uint32(idx)
+		}
+	}
+
+	b.types = append(b.types, signature)
+	return uint32(len(b.types) - 1)
+}
+
+// ImportFunction imports a function provided by the host and returns its function index
+func (b *ModuleBuilder) ImportFunction(module, name string, params, results []parser.Type) uint32 {
+	if len(b.funcs) > 0 {
+		b.setErr(fmt.Errorf("%w: function %s.%s", ErrImportAfterDefinition, module, name))
+	}
+
+	typeIdx := b.AddType(params, results)
+	imported := &parser.Import{
+		Module:    module,
+		Name:      name,
+		Type:      parser.ImportedFunc,
+		TypeIndex: int(typeIdx),
+	}
+
+	b.imports = append(b.imports, imported)
+	b.importedFuncs = append(b.importedFuncs, &parser.Function{
+		TypeIndex: int(typeIdx),
+		Signature: b.types[typeIdx],
+		Import:    imported,
+	})
+
+	return uint32(len(b.importedFuncs) - 1)
+}
+
+// ImportMemory imports a memory provided by the host and returns its memory index
+func (b *ModuleBuilder) ImportMemory(module, name string, limits parser.Limits) uint32 {
+	if len(b.memories) > 0 {
+		b.setErr(fmt.Errorf("%w: memory %s.%s", ErrImportAfterDefinition, module, name))
+	}
+
+	b.imports = append(b.imports, &parser.Import{
+		Module: module,
+		Name:   name,
+		Type:   parser.ImportedMem,
+		Memory: &parser.MemoryType{Limits: limits},
+	})
+
+	b.importedMems++
+	return uint32(b.importedMems - 1)
+}
+
+// ImportGlobal imports a global provided by the host and returns its global index
+func (b *ModuleBuilder) ImportGlobal(module, name string, valType parser.Type, mutable bool) uint32 {
+	if len(b.globals) > 0 {
+		b.setErr(fmt.Errorf("%w: global %s.%s", ErrImportAfterDefinition, module, name))
+	}
+
+	b.imports = append(b.imports, &parser.Import{
+		Module: module,
+		Name:   name,
+		Type:   parser.ImportedGlobal,
+		Global: &parser.GlobalType{ValType: valType, Mutable: mutable},
+	})
+
+	b.importedGlobals++
+	return uint32(b.importedGlobals - 1)
+}
+
+// AddFunction defines a function with the given locals, besides the params, and
+// body, the body must not contain the final `end` as it is added by the builder
+func (b *ModuleBuilder) AddFunction(params, results, locals []parser.Type, body ...Instruction) uint32 {
+	typeIdx := b.AddType(params, results)
+
+	code, err := parser.NewCode(locals, expression(body))
+	if err != nil {
+		b.setErr(fmt.Errorf("function %d: %w", len(b.importedFuncs)+len(b.funcs), err))
+	}
+
+	b.funcs = append(b.funcs, &parser.Function{
+		TypeIndex: int(typeIdx),
+		Signature: b.types[typeIdx],
+		Code:      code,
+	})
+
+	return uint32(len(b.importedFuncs) + len(b.funcs) - 1)
+}
+
+// AddMemory defines a memory and returns its memory index
+func (b *ModuleBuilder) AddMemory(limits parser.Limits) uint32 {
+	b.memories = append(b.memories, &parser.MemoryType{Limits: limits})
+	return uint32(b.importedMems + len(b.memories) - 1)
+}
+
+// AddGlobal defines a global initialized by the constant instruction init,
+// such as i32.const or global.get, and returns its global index
+func (b *ModuleBuilder) AddGlobal(valType parser.Type, mutable bool, init Instruction) uint32 {
+	b.globals = append(b.globals, &parser.Global{
+		Type: &parser.GlobalType{ValType: valType, Mutable: mutable},
+		Init: expression([]Instruction{init}),
+	})
+
+	return uint32(b.importedGlobals + len(b.globals) - 1)
+}
+
+// AddExport exports the entity of the given kind at index
+func (b *ModuleBuilder) AddExport(name string, kind parser.ExportedType, index uint32) {
+	b.exports = append(b.exports, &parser.Export{
+		Name:  name,
+		Type:  kind,
+		Index: int(index),
+	})
+}
+
+// AddData defines an active data segment that is copied to the memory at the
+// position given by the constant instruction offset and returns its data index
+func (b *ModuleBuilder) AddData(memory uint32, offset Instruction, init []byte) uint32 {
+	segment := &parser.Data{
+		Mode:   parser.DataActive,
+		Memory: int(memory),
+		Offset: expression([]Instruction{offset}),
+		Init:   init,
+	}
+
+	if memory != 0 {
+		segment.Flags = 0x02
+	}
+
+	b.data = append(b.data, segment)
+	return uint32(len(b.data) - 1)
+}
+
+// AddPassiveData defines a passive data segment, to be used by
+// memory.init, and returns its data index
+func (b *ModuleBuilder) AddPassiveData(init []byte) uint32 {
+	b.data = append(b.data, &parser.Data{
+		Flags: 0x01,
+		Mode:  parser.DataPassive,
+		Init:  init,
+	})
+
+	return uint32(len(b.data) - 1)
+}
+
+// SetStart defines the function called once the module is instantiated
+func (b *ModuleBuilder) SetStart(funcIdx uint32) {
+	start := int(funcIdx)
+	b.start = &start
+}
+
+func (b *ModuleBuilder) setErr(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// Module returns the module as the parser model, ready to be validated and
+// instantiated, the data count section is defined whenever there are data
+// segments so their instructions, memory.init and data.drop, are valid. The
+// module does not change when more entities are added to the builder later
+func (b *ModuleBuilder) Module() (*parser.BinaryParser, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	bp := parser.NewBinaryReaderParser(bytes.NewReader(nil))
+	bp.Module.Magic = parser.MagicNumber
+	bp.Module.Version = parser.Version
+
+	bp.Module.Types = append([]*parser.FunctionSignatureParser{}, b.types...)
+	bp.Module.Imports = append([]*parser.Import{}, b.imports...)
+	bp.Module.Funcs = append(append([]*parser.Function{}, b.importedFuncs...), b.funcs...)
+	bp.Module.Memories = append([]*parser.MemoryType{}, b.memories...)
+	bp.Module.Globals = append([]*parser.Global{}, b.globals...)
+	bp.Module.Exports = append([]*parser.Export{}, b.exports...)
+	bp.Module.Start = b.start
+	bp.Module.Data = append([]*parser.Data{}, b.data...)
+
+	if len(b.data) > 0 {
+		count := uint32(len(b.data))
+		bp.Module.DataCount = &count
+	}
+
+	return bp, nil
+}
+
+// Bytes returns the module encoded in the binary format
+func (b *ModuleBuilder) Bytes() ([]byte, error) {
+	bp, err := b.Module()
+	if err != nil {
+		return nil, err
+	}
+
+	return parser.Encode(bp)
+}
@@ -0,0 +1,188 @@
+package builder_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/EclesioMeloJunior/wasvm/builder"
+	"github.com/EclesioMeloJunior/wasvm/opcodes"
+	"github.com/EclesioMeloJunior/wasvm/parser"
+	"github.com/EclesioMeloJunior/wasvm/vm"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	i32 = []parser.Type{parser.I32}
+	i64 = []parser.Type{parser.I64}
+)
+
+// the builder must produce the same bytes as wat2wasm for the resources
+func TestBuilder_MatchesResources(t *testing.T) {
+	tests := map[string]func() *builder.ModuleBuilder{
+		"../resources/simple.wasm": func() *builder.ModuleBuilder {
+			b := builder.New()
+			helloWorld := b.AddFunction(nil, i32, nil, builder.I32Const(42))
+			b.AddExport("helloWorld", parser.ExportedFunc, helloWorld)
+			return b
+		},
+		"../resources/import_call.wasm": func() *builder.ModuleBuilder {
+			b := builder.New()
+			b.ImportFunction("env", "unused", i32, nil)
+
+			double := b.AddFunction(i32, i32, nil,
+				builder.LocalGet(0),
+				builder.LocalGet(0),
+				builder.Op(opcodes.I32Add))
+
+			sub := b.AddFunction([]parser.Type{parser.I32, parser.I32}, i32, nil,
+				builder.LocalGet(0),
+				builder.LocalGet(1),
+				builder.Op(opcodes.I32Sub))
+
+			quadrupleMinus := b.AddFunction([]parser.Type{parser.I32, parser.I32}, i32, nil,
+				builder.LocalGet(0),
+				builder.Call(double),
+				builder.Call(double),
+				builder.LocalGet(1),
+				builder.Call(sub))
+
+			b.AddExport("quadruple_minus", parser.ExportedFunc, quadrupleMinus)
+			return b
+		},
+		"../resources/data.wasm": func() *builder.ModuleBuilder {
+			b := builder.New()
+			b.AddMemory(parser.Limits{Min: 1})
+
+			b.AddData(0, builder.I32Const(16), []byte("hello"))
+			greet := b.AddPassiveData([]byte("world!"))
+
+			load8U := b.AddFunction(i32, i32, nil,
+				builder.LocalGet(0),
+				builder.MemoryAccess(opcodes.I32Load8U, 0, 0))
+
+			init := b.AddFunction([]parser.Type{parser.I32, parser.I32, parser.I32}, nil, nil,
+				builder.LocalGet(0),
+				builder.LocalGet(1),
+				builder.LocalGet(2),
+				builder.MemoryInit(greet))
+
+			drop := b.AddFunction(nil, nil, nil, builder.DataDrop(greet))
+
+			b.AddExport("load8_u", parser.ExportedFunc, load8U)
+			b.AddExport("init", parser.ExportedFunc, init)
+			b.AddExport("drop", parser.ExportedFunc, drop)
+			return b
+		},
+	}
+
+	for resource, build := range tests {
+		resource, build := resource, build
+		t.Run(resource, func(t *testing.T) {
+			expected, err := os.ReadFile(resource)
+			require.NoError(t, err)
+
+			wasmBytes, err := build().Bytes()
+			require.NoError(t, err)
+			require.Equal(t, expected, wasmBytes)
+		})
+	}
+}
+
+func TestBuilder_ModuleExecution(t *testing.T) {
+	b := builder.New()
+	base := b.ImportGlobal("env", "base", parser.I64, false)
+	counter := b.AddGlobal(parser.I64, true, builder.GlobalGet(base))
+
+	// stores the param as the new counter and returns the previous one
+	swap := b.AddFunction(i64, i64, i64,
+		builder.GlobalGet(counter),
+		builder.LocalSet(1),
+		builder.LocalGet(0),
+		builder.GlobalSet(counter),
+		builder.LocalGet(1))
+
+	b.AddExport("swap", parser.ExportedFunc, swap)
+	b.AddExport("counter", parser.ExportedGlobal, counter)
+
+	bp, err := b.Module()
+	require.NoError(t, err)
+	require.NoError(t, parser.Validate(bp))
+
+	baseGlobal, err := vm.NewGlobal(parser.GlobalType{ValType: parser.I64}, int64(7))
+	require.NoError(t, err)
+
+	linker := vm.NewLinker()
+	linker.DefineGlobal("env", "base", baseGlobal)
+
+	rt, err := vm.NewRuntimeWithLinker(bp, linker)
+	require.NoError(t, err)
+
+	results, err := rt.Exported["swap"].Call(int64(10))
+	require.NoError(t, err)
+	require.Equal(t, []any{int64(7)}, results)
+
+	results, err = rt.Exported["swap"].Call(int64(20))
+	require.NoError(t, err)
+	require.Equal(t, []any{int64(10)}, results)
+
+	// the encoded module must be decoded to the same module
+	wasmBytes, err := b.Bytes()
+	require.NoError(t, err)
+
+	decoded, err := parser.Decode(wasmBytes)
+	require.NoError(t, err)
+
+	reencoded, err := parser.Encode(decoded)
+	require.NoError(t, err)
+	require.Equal(t, wasmBytes, reencoded)
+}
+
+func TestBuilder_ImportAfterDefinition(t *testing.T) {
+	b := builder.New()
+	b.AddFunction(nil, nil, nil)
+	b.ImportFunction("env", "late", nil, nil)
+
+	_, err := b.Module()
+	require.ErrorIs(t, err, builder.ErrImportAfterDefinition)
+
+	_, err = b.Bytes()
+	require.ErrorIs(t, err, builder.ErrImportAfterDefinition)
+}
+
+func TestBuilder_ActiveDataDefinesDataCount(t *testing.T) {
+	b := builder.New()
+	b.AddMemory(parser.Limits{Min: 1})
+	segment := b.AddData(0, builder.I32Const(0), []byte("wasvm"))
+	b.AddFunction(nil, nil, nil, builder.DataDrop(segment))
+
+	wasm, err := b.Bytes()
+	require.NoError(t, err)
+
+	bp, err := parser.Decode(wasm)
+	require.NoError(t, err)
+	require.NotNil(t, bp.Module.DataCount)
+	require.Equal(t, uint32(1), *bp.Module.DataCount)
+	require.NoError(t, parser.Validate(bp))
+}
+
+func TestBuilder_ModuleIsNotChangedByLaterAdditions(t *testing.T) {
+	b := builder.New()
+	for _, name := range []string{"a", "b", "c"} {
+		b.AddExport(name, parser.ExportedFunc, 0)
+		b.AddPassiveData([]byte(name))
+	}
+
+	bp, err := b.Module()
+	require.NoError(t, err)
+
+	// the module and the builder must not share the room left in their slices
+	bp.Module.Exports = append(bp.Module.Exports, &parser.Export{Name: "module"})
+	bp.Module.Data = append(bp.Module.Data, &parser.Data{Init: []byte("module")})
+
+	b.AddExport("builder", parser.ExportedFunc, 0)
+	b.AddPassiveData([]byte("builder"))
+
+	require.Equal(t, "module", bp.Module.Exports[3].Name)
+	require.Equal(t, []byte("module"), bp.Module.Data[3].Init)
+}
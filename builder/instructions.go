@@ -0,0 +1,161 @@
+package builder
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/EclesioMeloJunior/wasvm/leb128"
+	"github.com/EclesioMeloJunior/wasvm/opcodes"
+	"github.com/EclesioMeloJunior/wasvm/parser"
+)
+
+// Instruction is an encoded instruction, opcode followed by its immediates,
+// used to build the functions bodies and the constant expressions
+type Instruction []byte
+
+// Op encodes an instruction that does not have immediates such as
+// i32.add or drop, prefixed opcodes are encoded with their prefix
+func Op(op opcodes.OpCode) Instruction {
+	if op <= 0xFF {
+		return Instruction{byte(op)}
+	}
+
+	return append(Instruction{byte(op >> 8)}, leb128.EncodeUint(uint(op&0xFF))...)
+}
+
+func withIndex(op opcodes.OpCode, idx uint32) Instruction {
+	return append(Op(op), leb128.EncodeUint(uint(idx))...)
+}
+
+func LocalGet(idx uint32) Instruction  { return withIndex(opcodes.LocalGet, idx) }
+func LocalSet(idx uint32) Instruction  { return withIndex(opcodes.LocalSet, idx) }
+func LocalTee(idx uint32) Instruction  { return withIndex(opcodes.LocalTee, idx) }
+func GlobalGet(idx uint32) Instruction { return withIndex(opcodes.GlobalGet, idx) }
+func GlobalSet(idx uint32) Instruction { return withIndex(opcodes.GlobalSet, idx) }
+func Call(funcIdx uint32) Instruction  { return withIndex(opcodes.Call, funcIdx) }
+func RefFunc(funcIdx uint32) Instruction {
+	return withIndex(opcodes.RefFunc, funcIdx)
+}
+
+func CallIndirect(typeIdx, tableIdx uint32) Instruction {
+	return append(withIndex(opcodes.CallIndirect, typeIdx), leb128.EncodeUint(uint(tableIdx))...)
+}
+
+func I32Const(v int32) Instruction {
+	return append(Op(opcodes.I32Const), leb128.EncodeInt(int(v))...)
+}
+
+func I64Const(v int64) Instruction {
+	return append(Op(opcodes.I64Const), leb128.EncodeInt(int(v))...)
+}
+
+func F32Const(v float32) Instruction {
+	bits := make([]byte, 4)
+	binary.LittleEndian.PutUint32(bits, math.Float32bits(v))
+	return append(Op(opcodes.F32Const), bits...)
+}
+
+func F64Const(v float64) Instruction {
+	bits := make([]byte, 8)
+	binary.LittleEndian.PutUint64(bits, math.Float64bits(v))
+	return append(Op(opcodes.F64Const), bits...)
+}
+
+// Block starts a block without results, it must be closed with End
+func Block() Instruction {
+	return Instruction{byte(opcodes.Block), opcodes.EmptyBlockType}
+}
+
+// BlockResult starts a block that leaves a value of type result in the stack
+func BlockResult(result parser.Type) Instruction {
+	return Instruction{byte(opcodes.Block), result.SpecByte}
+}
+
+// BlockTyped starts a block that takes the params and leaves the results of
+// the function type at typeIdx, such as the ones returned by AddType
+func BlockTyped(typeIdx uint32) Instruction {
+	return append(Op(opcodes.Block), leb128.EncodeInt(int(typeIdx))...)
+}
+
+// Loop starts a loop without results, it must be closed with End
+func Loop() Instruction {
+	return Instruction{byte(opcodes.Loop), opcodes.EmptyBlockType}
+}
+
+// LoopResult starts a loop that leaves a value of type result in the stack
+func LoopResult(result parser.Type) Instruction {
+	return Instruction{byte(opcodes.Loop), result.SpecByte}
+}
+
+// LoopTyped starts a loop with the params and results of the function type at typeIdx
+func LoopTyped(typeIdx uint32) Instruction {
+	return append(Op(opcodes.Loop), leb128.EncodeInt(int(typeIdx))...)
+}
+
+// If starts an if without results, it must be closed with End
+func If() Instruction {
+	return Instruction{byte(opcodes.If), opcodes.EmptyBlockType}
+}
+
+// IfResult starts an if that leaves a value of type result in the stack
+func IfResult(result parser.Type) Instruction {
+	return Instruction{byte(opcodes.If), result.SpecByte}
+}
+
+// IfTyped starts an if with the params and results of the function type at typeIdx
+func IfTyped(typeIdx uint32) Instruction {
+	return append(Op(opcodes.If), leb128.EncodeInt(int(typeIdx))...)
+}
+
+func Else() Instruction { return Op(opcodes.Else) }
+func End() Instruction  { return Op(opcodes.End) }
+
+// Br branches to the label at the given depth, 0 is the innermost block
+func Br(label uint32) Instruction   { return withIndex(opcodes.Br, label) }
+func BrIf(label uint32) Instruction { return withIndex(opcodes.BrIf, label) }
+
+// BrTable branches to the label at the position given by the operand,
+// or to defaultLabel when the operand is out of the labels bounds
+func BrTable(labels []uint32, defaultLabel uint32) Instruction {
+	instr := withIndex(opcodes.BrTable, uint32(len(labels)))
+	for _, label := range labels {
+		instr = append(instr, leb128.EncodeUint(uint(label))...)
+	}
+
+	return append(instr, leb128.EncodeUint(uint(defaultLabel))...)
+}
+
+func Return() Instruction { return Op(opcodes.Return) }
+
+// SelectTyped encodes the select instruction with an explicit operands type
+func SelectTyped(t parser.Type) Instruction {
+	return Instruction{byte(opcodes.SelectTyped), 0x01, t.SpecByte}
+}
+
+func RefNull(t parser.Type) Instruction {
+	return Instruction{byte(opcodes.RefNull), t.SpecByte}
+}
+
+// MemoryAccess encodes a load or a store, align is the
+// log2 of the access alignment in bytes
+func MemoryAccess(op opcodes.OpCode, align, offset uint32) Instruction {
+	return append(withIndex(op, align), leb128.EncodeUint(uint(offset))...)
+}
+
+func MemorySize() Instruction { return Instruction{byte(opcodes.MemorySize), 0x00} }
+func MemoryGrow() Instruction { return Instruction{byte(opcodes.MemoryGrow), 0x00} }
+
+func MemoryInit(dataIdx uint32) Instruction {
+	return append(withIndex(opcodes.MemoryInit, dataIdx), 0x00)
+}
+
+func DataDrop(dataIdx uint32) Instruction { return withIndex(opcodes.DataDrop, dataIdx) }
+
+func expression(instrs []Instruction) []byte {
+	expr := make([]byte, 0)
+	for _, instr := range instrs {
+		expr = append(expr, instr...)
+	}
+
+	return append(expr, byte(opcodes.End))
+}
@@ -1,15 +1,18 @@
 package leb128
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"unsafe"
 )
 
 var (
 	ErrCannotReadNextByte = errors.New("cannot read next byte")
 	ErrOverflow32         = errors.New("overflows a 32-bit integer")
+	ErrOverflow33         = errors.New("overflows a 33-bit integer")
+	ErrOverflow64         = errors.New("overflows a 64-bit integer")
 
 	// cachedLEB128Encoded goes from 0 -> 127 since the LEB128 is the number
 	cachedLEB128Encoded = [0x80][1]byte{
@@ -63,7 +66,27 @@ func EncodeInt(v int) (enc []byte) {
 	return enc
 }
 
-func DecodeUint(reader *bytes.Reader) (read int, result uint, err error) {
+func DecodeUint(reader io.ByteReader) (read int, result uint, err error) {
+	return decodeUint(reader, 10, ErrOverflow64)
+}
+
+// DecodeUint32 decodes an unsigned integer that must fit in 32 bits,
+// the encoding used by the wasm indices and lengths
+func DecodeUint32(reader io.ByteReader) (read int, result uint32, err error) {
+	read, value, err := decodeUint(reader, 5, ErrOverflow32)
+	if err != nil {
+		return read, 0, err
+	}
+
+	if value > math.MaxUint32 {
+		return read, 0, fmt.Errorf("%w: %d", ErrOverflow32, value)
+	}
+
+	return read, uint32(value), nil
+}
+
+// decodeUint fails with overflowErr when the encoding takes more than maxBytes
+func decodeUint(reader io.ByteReader, maxBytes int, overflowErr error) (read int, result uint, err error) {
 	shift := 0
 
 	for {
@@ -73,6 +96,9 @@ func DecodeUint(reader *bytes.Reader) (read int, result uint, err error) {
 		}
 
 		read += 1
+		if read > maxBytes {
+			return read, result, fmt.Errorf("%w: more than %d bytes", overflowErr, maxBytes)
+		}
 
 		result |= (uint(b&0x7f) << shift)
 
@@ -86,9 +112,15 @@ func DecodeUint(reader *bytes.Reader) (read int, result uint, err error) {
 	return read, result, nil
 }
 
-func DecodeInt[T int32 | int64](reader *bytes.Reader) (read int, result T, err error) {
+func DecodeInt[T int32 | int64](reader io.ByteReader) (read int, result T, err error) {
 	shift := 0
 
+	size := int(unsafe.Sizeof(result) * 8)
+	maxBytes, overflowErr := (size+6)/7, ErrOverflow64
+	if size == 32 {
+		overflowErr = ErrOverflow32
+	}
+
 	for {
 		b, err := reader.ReadByte()
 		if err != nil {
@@ -96,12 +128,56 @@ func DecodeInt[T int32 | int64](reader *bytes.Reader) (read int, result T, err e
 		}
 
 		read += 1
+		if read > maxBytes {
+			return read, result, fmt.Errorf("%w: more than %d bytes", overflowErr, maxBytes)
+		}
+
+		// the unused bits of the last byte must be the sign extension
+		if read == maxBytes {
+			usedBits := size - 7*(maxBytes-1)
+			signAndUnused := (b & 0x7f) >> (usedBits - 1)
+			if signAndUnused != 0 && signAndUnused != 0x7f>>(usedBits-1) {
+				return read, 0, fmt.Errorf("%w: unused bits of the last byte 0x%x", overflowErr, b)
+			}
+		}
 
 		result |= T(b&0x7f) << shift
 		shift += 7
 
 		if b&0x80 == 0 {
-			if shift < int(unsafe.Sizeof(result)*8) && (b&0x40) != 0 {
+			if shift < size && (b&0x40) != 0 {
+				result |= ^0 << shift
+			}
+
+			break
+		}
+	}
+
+	return read, result, nil
+}
+
+// DecodeS33 decodes a signed integer that must fit in 33 bits, the encoding
+// of the block types where the non negative values are type indices
+func DecodeS33(reader io.ByteReader) (read int, result int64, err error) {
+	const maxBytes = 5
+	shift := 0
+
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return read, 0, fmt.Errorf("%w: %s", ErrCannotReadNextByte, err.Error())
+		}
+
+		read += 1
+		if read > maxBytes {
+			return read, 0, fmt.Errorf("%w: more than %d bytes", ErrOverflow33, maxBytes)
+		}
+
+		result |= int64(b&0x7f) << shift
+		shift += 7
+
+		if b&0x80 == 0 {
+			if (b & 0x40) != 0 {
 				result |= ^0 << shift
 			}
 
@@ -109,5 +185,10 @@ func DecodeInt[T int32 | int64](reader *bytes.Reader) (read int, result T, err e
 		}
 	}
 
+	// the unused bits of the last byte must be the sign extension
+	if result < -(1<<32) || result >= 1<<32 {
+		return read, 0, fmt.Errorf("%w: %d", ErrOverflow33, result)
+	}
+
 	return read, result, nil
 }
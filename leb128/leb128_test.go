@@ -104,6 +104,11 @@ func TestDecodeInt32(t *testing.T) {
 		{enc: []byte{0x7f}, expected: -1, bytesRead: 1},
 		{enc: []byte{0x81, 0x7f}, expected: -127, bytesRead: 2},
 		{enc: []byte{0xFF, 0x7e}, expected: -129, bytesRead: 2},
+		{enc: []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x07}, expected: math.MaxInt32, bytesRead: 5},
+		{enc: []byte{0x80, 0x80, 0x80, 0x80, 0x78}, expected: math.MinInt32, bytesRead: 5},
+		// the unused bits are not the sign extension
+		{enc: []byte{0x80, 0x80, 0x80, 0x80, 0x70}, bytesRead: 5, wantErr: leb128.ErrOverflow32},
+		{enc: []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x0F}, bytesRead: 5, wantErr: leb128.ErrOverflow32},
 	}
 
 	for _, tt := range tests {
@@ -111,7 +116,7 @@ func TestDecodeInt32(t *testing.T) {
 		assert.Equal(t, tt.bytesRead, n)
 
 		if tt.wantErr != nil {
-			assert.EqualError(t, err, tt.wantErr.Error())
+			assert.ErrorIs(t, err, tt.wantErr)
 		} else {
 			assert.NoError(t, err)
 			assert.Equal(t, tt.expected, result)
@@ -134,15 +139,73 @@ func TestDecodeInt64(t *testing.T) {
 		{enc: []byte{0xFF, 0x7e}, expected: -129},
 		{enc: []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x7f},
 			expected: -9223372036854775808},
+		{enc: []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x00},
+			expected: math.MaxInt64},
+		// the unused bits are not the sign extension
+		{enc: []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01},
+			wantErr: leb128.ErrOverflow64},
+		{enc: []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x70},
+			wantErr: leb128.ErrOverflow64},
 	}
 
 	for _, tt := range tests {
 		_, result, err := leb128.DecodeInt[int64](bytes.NewReader(tt.enc))
 		if tt.wantErr != nil {
-			assert.EqualError(t, err, tt.wantErr.Error())
+			assert.ErrorIs(t, err, tt.wantErr)
 		} else {
 			assert.NoError(t, err)
 			assert.Equal(t, tt.expected, result)
 		}
 	}
 }
+
+func TestDecodeS33(t *testing.T) {
+	tests := []struct {
+		enc       []byte
+		expected  int64
+		bytesRead int
+		wantErr   error
+	}{
+		{enc: []byte{0x40}, expected: -64, bytesRead: 1},
+		{enc: []byte{0x7F}, expected: -1, bytesRead: 1},
+		{enc: []byte{0x00}, expected: 0, bytesRead: 1},
+		{enc: []byte{0x83, 0x01}, expected: 131, bytesRead: 2},
+		{enc: []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x0F}, expected: 0xFFFFFFFF, bytesRead: 5},
+		{enc: []byte{0x80, 0x80, 0x80, 0x80, 0x70}, expected: -(1 << 32), bytesRead: 5},
+		// the unused bits are not the sign extension
+		{enc: []byte{0x80, 0x80, 0x80, 0x80, 0x10}, bytesRead: 5, wantErr: leb128.ErrOverflow33},
+		{enc: []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x00}, bytesRead: 6, wantErr: leb128.ErrOverflow33},
+	}
+
+	for _, tt := range tests {
+		n, result, err := leb128.DecodeS33(bytes.NewReader(tt.enc))
+		assert.Equal(t, tt.bytesRead, n)
+
+		if tt.wantErr != nil {
+			assert.ErrorIs(t, err, tt.wantErr)
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		}
+	}
+}
+
+func TestDecode_TooLongEncodings(t *testing.T) {
+	_, _, err := leb128.DecodeInt[int32](bytes.NewReader([]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x00}))
+	assert.ErrorIs(t, err, leb128.ErrOverflow32)
+
+	_, _, err = leb128.DecodeUint(bytes.NewReader(bytes.Repeat([]byte{0x80}, 11)))
+	assert.ErrorIs(t, err, leb128.ErrOverflow64)
+
+	_, _, err = leb128.DecodeUint32(bytes.NewReader([]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x00}))
+	assert.ErrorIs(t, err, leb128.ErrOverflow32)
+
+	// 2^32 fits in 5 bytes but not in an u32
+	_, _, err = leb128.DecodeUint32(bytes.NewReader([]byte{0x80, 0x80, 0x80, 0x80, 0x10}))
+	assert.ErrorIs(t, err, leb128.ErrOverflow32)
+
+	n, result, err := leb128.DecodeUint32(bytes.NewReader([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0x0F}))
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, uint32(0xFFFFFFFF), result)
+}
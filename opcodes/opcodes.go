@@ -4,52 +4,446 @@ import (
 	"fmt"
 )
 
-type OpCode byte
+// OpCode identifies an instruction, the ones encoded with a prefix
+// byte are represented as prefix<<8 | sub opcode
+type OpCode uint16
+
+// names holds the text format name of the known instructions
+var names = map[OpCode]string{
+	Unreachable:       "unreachable",
+	Nop:               "nop",
+	Block:             "block",
+	Loop:              "loop",
+	Drop:              "drop",
+	Select:            "select",
+	SelectTyped:       "select",
+	LocalGet:          "local.get",
+	LocalSet:          "local.set",
+	LocalTee:          "local.tee",
+	I32Const:          "i32.const",
+	I64Const:          "i64.const",
+	F32Const:          "f32.const",
+	F64Const:          "f64.const",
+	GlobalGet:         "global.get",
+	GlobalSet:         "global.set",
+	RefNull:           "ref.null",
+	RefFunc:           "ref.func",
+	I32Eqz:            "i32.eqz",
+	I32Eq:             "i32.eq",
+	I32Ne:             "i32.ne",
+	I32LtS:            "i32.lt_s",
+	I32LtU:            "i32.lt_u",
+	I32GtS:            "i32.gt_s",
+	I32GtU:            "i32.gt_u",
+	I32LeS:            "i32.le_s",
+	I32LeU:            "i32.le_u",
+	I32GeS:            "i32.ge_s",
+	I32GeU:            "i32.ge_u",
+	I32Clz:            "i32.clz",
+	I32Ctz:            "i32.ctz",
+	I32Popcnt:         "i32.popcnt",
+	I32Add:            "i32.add",
+	I32Sub:            "i32.sub",
+	I32Mul:            "i32.mul",
+	I32DivS:           "i32.div_s",
+	I32DivU:           "i32.div_u",
+	I32RemS:           "i32.rem_s",
+	I32RemU:           "i32.rem_u",
+	I32And:            "i32.and",
+	I32Or:             "i32.or",
+	I32Xor:            "i32.xor",
+	I32Shl:            "i32.shl",
+	I32ShrS:           "i32.shr_s",
+	I32ShrU:           "i32.shr_u",
+	I32Rotl:           "i32.rotl",
+	I32Rotr:           "i32.rotr",
+	I64Eqz:            "i64.eqz",
+	I64Eq:             "i64.eq",
+	I64Ne:             "i64.ne",
+	I64LtS:            "i64.lt_s",
+	I64LtU:            "i64.lt_u",
+	I64GtS:            "i64.gt_s",
+	I64GtU:            "i64.gt_u",
+	I64LeS:            "i64.le_s",
+	I64LeU:            "i64.le_u",
+	I64GeS:            "i64.ge_s",
+	I64GeU:            "i64.ge_u",
+	I64Clz:            "i64.clz",
+	I64Ctz:            "i64.ctz",
+	I64Popcnt:         "i64.popcnt",
+	I64Add:            "i64.add",
+	I64Sub:            "i64.sub",
+	I64Mul:            "i64.mul",
+	I64DivS:           "i64.div_s",
+	I64DivU:           "i64.div_u",
+	I64RemS:           "i64.rem_s",
+	I64RemU:           "i64.rem_u",
+	I64And:            "i64.and",
+	I64Or:             "i64.or",
+	I64Xor:            "i64.xor",
+	I64Shl:            "i64.shl",
+	I64ShrS:           "i64.shr_s",
+	I64ShrU:           "i64.shr_u",
+	I64Rotl:           "i64.rotl",
+	I64Rotr:           "i64.rotr",
+	F32Eq:             "f32.eq",
+	F32Ne:             "f32.ne",
+	F32Lt:             "f32.lt",
+	F32Gt:             "f32.gt",
+	F32Le:             "f32.le",
+	F32Ge:             "f32.ge",
+	F64Eq:             "f64.eq",
+	F64Ne:             "f64.ne",
+	F64Lt:             "f64.lt",
+	F64Gt:             "f64.gt",
+	F64Le:             "f64.le",
+	F64Ge:             "f64.ge",
+	F32Abs:            "f32.abs",
+	F32Neg:            "f32.neg",
+	F32Ceil:           "f32.ceil",
+	F32Floor:          "f32.floor",
+	F32Trunc:          "f32.trunc",
+	F32Nearest:        "f32.nearest",
+	F32Sqrt:           "f32.sqrt",
+	F32Add:            "f32.add",
+	F32Sub:            "f32.sub",
+	F32Mul:            "f32.mul",
+	F32Div:            "f32.div",
+	F32Min:            "f32.min",
+	F32Max:            "f32.max",
+	F32Copysign:       "f32.copysign",
+	F64Abs:            "f64.abs",
+	F64Neg:            "f64.neg",
+	F64Ceil:           "f64.ceil",
+	F64Floor:          "f64.floor",
+	F64Trunc:          "f64.trunc",
+	F64Nearest:        "f64.nearest",
+	F64Sqrt:           "f64.sqrt",
+	F64Add:            "f64.add",
+	F64Sub:            "f64.sub",
+	F64Mul:            "f64.mul",
+	F64Div:            "f64.div",
+	F64Min:            "f64.min",
+	F64Max:            "f64.max",
+	F64Copysign:       "f64.copysign",
+	I32WrapI64:        "i32.wrap_i64",
+	I32TruncF32S:      "i32.trunc_f32_s",
+	I32TruncF32U:      "i32.trunc_f32_u",
+	I32TruncF64S:      "i32.trunc_f64_s",
+	I32TruncF64U:      "i32.trunc_f64_u",
+	I64ExtendI32S:     "i64.extend_i32_s",
+	I64ExtendI32U:     "i64.extend_i32_u",
+	I64TruncF32S:      "i64.trunc_f32_s",
+	I64TruncF32U:      "i64.trunc_f32_u",
+	I64TruncF64S:      "i64.trunc_f64_s",
+	I64TruncF64U:      "i64.trunc_f64_u",
+	F32ConvertI32S:    "f32.convert_i32_s",
+	F32ConvertI32U:    "f32.convert_i32_u",
+	F32ConvertI64S:    "f32.convert_i64_s",
+	F32ConvertI64U:    "f32.convert_i64_u",
+	F32DemoteF64:      "f32.demote_f64",
+	F64ConvertI32S:    "f64.convert_i32_s",
+	F64ConvertI32U:    "f64.convert_i32_u",
+	F64ConvertI64S:    "f64.convert_i64_s",
+	F64ConvertI64U:    "f64.convert_i64_u",
+	F64PromoteF32:     "f64.promote_f32",
+	I32ReinterpretF32: "i32.reinterpret_f32",
+	I64ReinterpretF64: "i64.reinterpret_f64",
+	F32ReinterpretI32: "f32.reinterpret_i32",
+	F64ReinterpretI64: "f64.reinterpret_i64",
+	I32Extend8S:       "i32.extend8_s",
+	I32Extend16S:      "i32.extend16_s",
+	I64Extend8S:       "i64.extend8_s",
+	I64Extend16S:      "i64.extend16_s",
+	I64Extend32S:      "i64.extend32_s",
+	I32TruncSatF32S:   "i32.trunc_sat_f32_s",
+	I32TruncSatF32U:   "i32.trunc_sat_f32_u",
+	I32TruncSatF64S:   "i32.trunc_sat_f64_s",
+	I32TruncSatF64U:   "i32.trunc_sat_f64_u",
+	I64TruncSatF32S:   "i64.trunc_sat_f32_s",
+	I64TruncSatF32U:   "i64.trunc_sat_f32_u",
+	I64TruncSatF64S:   "i64.trunc_sat_f64_s",
+	I64TruncSatF64U:   "i64.trunc_sat_f64_u",
+	If:                "if",
+	Else:              "else",
+	End:               "end",
+	Br:                "br",
+	BrIf:              "br_if",
+	BrTable:           "br_table",
+	Call:              "call",
+	CallIndirect:      "call_indirect",
+	Return:            "return",
+	I32Load:           "i32.load",
+	I64Load:           "i64.load",
+	F32Load:           "f32.load",
+	F64Load:           "f64.load",
+	I32Load8S:         "i32.load8_s",
+	I32Load8U:         "i32.load8_u",
+	I32Load16S:        "i32.load16_s",
+	I32Load16U:        "i32.load16_u",
+	I64Load8S:         "i64.load8_s",
+	I64Load8U:         "i64.load8_u",
+	I64Load16S:        "i64.load16_s",
+	I64Load16U:        "i64.load16_u",
+	I64Load32S:        "i64.load32_s",
+	I64Load32U:        "i64.load32_u",
+	I32Store:          "i32.store",
+	I64Store:          "i64.store",
+	F32Store:          "f32.store",
+	F64Store:          "f64.store",
+	I32Store8:         "i32.store8",
+	I32Store16:        "i32.store16",
+	I64Store8:         "i64.store8",
+	I64Store16:        "i64.store16",
+	I64Store32:        "i64.store32",
+	MemorySize:        "memory.size",
+	MemoryGrow:        "memory.grow",
+	MemoryInit:        "memory.init",
+	DataDrop:          "data.drop",
+}
+
+// byName is the reverse of names, select is the untyped one
+// as the typed select is told apart by its result type
+var byName = make(map[string]OpCode, len(names))
+
+func init() {
+	for op, name := range names {
+		if op != SelectTyped {
+			byName[name] = op
+		}
+	}
+}
 
 func (i OpCode) String() string {
-	switch i {
-	case I32Const:
-		return "i32.const"
-	case I32Add:
-		return "i32.add"
-	case I32Sub:
-		return "i32.sub"
-	case I32Mul:
-		return "i32.mul"
-	case I32LowerThanSigned:
-		return "i32.lt_s"
-	case If:
-		return "if"
-	case Else:
-		return "else"
-	case End:
-		return "end"
-	case Call:
-		return "call"
-	case Return:
-		return "return"
-	case EmptyBlockType:
-		return "empty(blocktype)"
-	default:
-		return fmt.Sprintf("%x", byte(i))
+	if name, ok := names[i]; ok {
+		return name
 	}
+
+	return fmt.Sprintf("%x", uint16(i))
+}
+
+// ByName returns the instruction with the given text format name
+func ByName(name string) (OpCode, bool) {
+	op, ok := byName[name]
+	return op, ok
 }
 
 const (
-	LocalGet OpCode = 0x20
+	Unreachable OpCode = 0x00
+	Nop         OpCode = 0x01
+	Block       OpCode = 0x02
+	Loop        OpCode = 0x03
+
+	Drop        OpCode = 0x1A
+	Select      OpCode = 0x1B
+	SelectTyped OpCode = 0x1C
+
+	LocalGet  OpCode = 0x20
+	LocalSet  OpCode = 0x21
+	LocalTee  OpCode = 0x22
+	GlobalGet OpCode = 0x23
+	GlobalSet OpCode = 0x24
+
+	I32Const OpCode = 0x41
+	I64Const OpCode = 0x42
+	F32Const OpCode = 0x43
+	F64Const OpCode = 0x44
+
+	I32Eqz OpCode = 0x45
+	I32Eq  OpCode = 0x46
+	I32Ne  OpCode = 0x47
+	I32LtS OpCode = 0x48
+	I32LtU OpCode = 0x49
+	I32GtS OpCode = 0x4A
+	I32GtU OpCode = 0x4B
+	I32LeS OpCode = 0x4C
+	I32LeU OpCode = 0x4D
+	I32GeS OpCode = 0x4E
+	I32GeU OpCode = 0x4F
+
+	// Deprecated: use I32LtS
+	I32LowerThanSigned = I32LtS
+
+	I32Clz    OpCode = 0x67
+	I32Ctz    OpCode = 0x68
+	I32Popcnt OpCode = 0x69
+	I32Add    OpCode = 0x6A
+	I32Sub    OpCode = 0x6B
+	I32Mul    OpCode = 0x6C
+	I32DivS   OpCode = 0x6D
+	I32DivU   OpCode = 0x6E
+	I32RemS   OpCode = 0x6F
+	I32RemU   OpCode = 0x70
+	I32And    OpCode = 0x71
+	I32Or     OpCode = 0x72
+	I32Xor    OpCode = 0x73
+	I32Shl    OpCode = 0x74
+	I32ShrS   OpCode = 0x75
+	I32ShrU   OpCode = 0x76
+	I32Rotl   OpCode = 0x77
+	I32Rotr   OpCode = 0x78
+
+	I64Eqz OpCode = 0x50
+	I64Eq  OpCode = 0x51
+	I64Ne  OpCode = 0x52
+	I64LtS OpCode = 0x53
+	I64LtU OpCode = 0x54
+	I64GtS OpCode = 0x55
+	I64GtU OpCode = 0x56
+	I64LeS OpCode = 0x57
+	I64LeU OpCode = 0x58
+	I64GeS OpCode = 0x59
+	I64GeU OpCode = 0x5A
+
+	I64Clz    OpCode = 0x79
+	I64Ctz    OpCode = 0x7A
+	I64Popcnt OpCode = 0x7B
+	I64Add    OpCode = 0x7C
+	I64Sub    OpCode = 0x7D
+	I64Mul    OpCode = 0x7E
+	I64DivS   OpCode = 0x7F
+	I64DivU   OpCode = 0x80
+	I64RemS   OpCode = 0x81
+	I64RemU   OpCode = 0x82
+	I64And    OpCode = 0x83
+	I64Or     OpCode = 0x84
+	I64Xor    OpCode = 0x85
+	I64Shl    OpCode = 0x86
+	I64ShrS   OpCode = 0x87
+	I64ShrU   OpCode = 0x88
+	I64Rotl   OpCode = 0x89
+	I64Rotr   OpCode = 0x8A
+
+	F32Eq OpCode = 0x5B
+	F32Ne OpCode = 0x5C
+	F32Lt OpCode = 0x5D
+	F32Gt OpCode = 0x5E
+	F32Le OpCode = 0x5F
+	F32Ge OpCode = 0x60
+
+	F64Eq OpCode = 0x61
+	F64Ne OpCode = 0x62
+	F64Lt OpCode = 0x63
+	F64Gt OpCode = 0x64
+	F64Le OpCode = 0x65
+	F64Ge OpCode = 0x66
+
+	F32Abs      OpCode = 0x8B
+	F32Neg      OpCode = 0x8C
+	F32Ceil     OpCode = 0x8D
+	F32Floor    OpCode = 0x8E
+	F32Trunc    OpCode = 0x8F
+	F32Nearest  OpCode = 0x90
+	F32Sqrt     OpCode = 0x91
+	F32Add      OpCode = 0x92
+	F32Sub      OpCode = 0x93
+	F32Mul      OpCode = 0x94
+	F32Div      OpCode = 0x95
+	F32Min      OpCode = 0x96
+	F32Max      OpCode = 0x97
+	F32Copysign OpCode = 0x98
+
+	F64Abs      OpCode = 0x99
+	F64Neg      OpCode = 0x9A
+	F64Ceil     OpCode = 0x9B
+	F64Floor    OpCode = 0x9C
+	F64Trunc    OpCode = 0x9D
+	F64Nearest  OpCode = 0x9E
+	F64Sqrt     OpCode = 0x9F
+	F64Add      OpCode = 0xA0
+	F64Sub      OpCode = 0xA1
+	F64Mul      OpCode = 0xA2
+	F64Div      OpCode = 0xA3
+	F64Min      OpCode = 0xA4
+	F64Max      OpCode = 0xA5
+	F64Copysign OpCode = 0xA6
+
+	I32WrapI64        OpCode = 0xA7
+	I32TruncF32S      OpCode = 0xA8
+	I32TruncF32U      OpCode = 0xA9
+	I32TruncF64S      OpCode = 0xAA
+	I32TruncF64U      OpCode = 0xAB
+	I64ExtendI32S     OpCode = 0xAC
+	I64ExtendI32U     OpCode = 0xAD
+	I64TruncF32S      OpCode = 0xAE
+	I64TruncF32U      OpCode = 0xAF
+	I64TruncF64S      OpCode = 0xB0
+	I64TruncF64U      OpCode = 0xB1
+	F32ConvertI32S    OpCode = 0xB2
+	F32ConvertI32U    OpCode = 0xB3
+	F32ConvertI64S    OpCode = 0xB4
+	F32ConvertI64U    OpCode = 0xB5
+	F32DemoteF64      OpCode = 0xB6
+	F64ConvertI32S    OpCode = 0xB7
+	F64ConvertI32U    OpCode = 0xB8
+	F64ConvertI64S    OpCode = 0xB9
+	F64ConvertI64U    OpCode = 0xBA
+	F64PromoteF32     OpCode = 0xBB
+	I32ReinterpretF32 OpCode = 0xBC
+	I64ReinterpretF64 OpCode = 0xBD
+	F32ReinterpretI32 OpCode = 0xBE
+	F64ReinterpretI64 OpCode = 0xBF
+
+	I32Extend8S  OpCode = 0xC0
+	I32Extend16S OpCode = 0xC1
+	I64Extend8S  OpCode = 0xC2
+	I64Extend16S OpCode = 0xC3
+	I64Extend32S OpCode = 0xC4
+
+	If      OpCode = 0x04
+	Else    OpCode = 0x05
+	End     OpCode = 0x0B
+	Br      OpCode = 0x0C
+	BrIf    OpCode = 0x0D
+	BrTable OpCode = 0x0E
+	Return  OpCode = 0x0F
+
+	Call         OpCode = 0x10
+	CallIndirect OpCode = 0x11
+
+	I32Load    OpCode = 0x28
+	I64Load    OpCode = 0x29
+	F32Load    OpCode = 0x2A
+	F64Load    OpCode = 0x2B
+	I32Load8S  OpCode = 0x2C
+	I32Load8U  OpCode = 0x2D
+	I32Load16S OpCode = 0x2E
+	I32Load16U OpCode = 0x2F
+	I64Load8S  OpCode = 0x30
+	I64Load8U  OpCode = 0x31
+	I64Load16S OpCode = 0x32
+	I64Load16U OpCode = 0x33
+	I64Load32S OpCode = 0x34
+	I64Load32U OpCode = 0x35
+	I32Store   OpCode = 0x36
+	I64Store   OpCode = 0x37
+	F32Store   OpCode = 0x38
+	F64Store   OpCode = 0x39
+	I32Store8  OpCode = 0x3A
+	I32Store16 OpCode = 0x3B
+	I64Store8  OpCode = 0x3C
+	I64Store16 OpCode = 0x3D
+	I64Store32 OpCode = 0x3E
+	MemorySize OpCode = 0x3F
+	MemoryGrow OpCode = 0x40
 
-	I32Const           OpCode = 0x41
-	I32Add             OpCode = 0x6A
-	I32Sub             OpCode = 0x6B
-	I32Mul             OpCode = 0x6C
-	I32LowerThanSigned OpCode = 0x48
+	// MiscPrefix precedes the instructions that are
+	// identified by an u32 sub opcode
+	MiscPrefix OpCode = 0xFC
 
-	If     OpCode = 0x04
-	Else   OpCode = 0x05
-	End    OpCode = 0x0B
-	Return OpCode = 0x0F
+	I32TruncSatF32S OpCode = MiscPrefix<<8 | 0x00
+	I32TruncSatF32U OpCode = MiscPrefix<<8 | 0x01
+	I32TruncSatF64S OpCode = MiscPrefix<<8 | 0x02
+	I32TruncSatF64U OpCode = MiscPrefix<<8 | 0x03
+	I64TruncSatF32S OpCode = MiscPrefix<<8 | 0x04
+	I64TruncSatF32U OpCode = MiscPrefix<<8 | 0x05
+	I64TruncSatF64S OpCode = MiscPrefix<<8 | 0x06
+	I64TruncSatF64U OpCode = MiscPrefix<<8 | 0x07
+	MemoryInit      OpCode = MiscPrefix<<8 | 0x08
+	DataDrop        OpCode = MiscPrefix<<8 | 0x09
 
-	Call OpCode = 0x10
+	RefNull OpCode = 0xD0
+	RefFunc OpCode = 0xD2
 
 	EmptyBlockType = 0x40
 )
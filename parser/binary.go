@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/binary"
 	"errors"
@@ -12,39 +13,102 @@ import (
 )
 
 const (
-	TypeSection     byte = 0x01
-	ImportsSection  byte = 0x02
-	FunctionSection byte = 0x03
-	ExportSection   byte = 0x07
-	StartSection    byte = 0x08
-	CodeSection     byte = 0x0A
+	CustomSection    byte = 0x00
+	TypeSection      byte = 0x01
+	ImportsSection   byte = 0x02
+	FunctionSection  byte = 0x03
+	TableSection     byte = 0x04
+	MemorySection    byte = 0x05
+	GlobalSection    byte = 0x06
+	ExportSection    byte = 0x07
+	StartSection     byte = 0x08
+	ElementSection   byte = 0x09
+	CodeSection      byte = 0x0A
+	DataSection      byte = 0x0B
+	DataCountSection byte = 0x0C
 )
 
 var (
 	ErrBytesLen = errors.New("unexpected bytes len")
 )
 
+// sectionsOrder is the order of the non custom sections, a module must
+// define them in this order and at most once while the custom sections
+// can appear any amount of times anywhere in the module. It is also the
+// order the sections are written by Encode
+var sectionsOrder = []byte{
+	TypeSection, ImportsSection, FunctionSection, TableSection,
+	MemorySection, GlobalSection, ExportSection, StartSection,
+	ElementSection, DataCountSection, CodeSection, DataSection,
+}
+
+// sectionPosition returns the position of the section in sectionsOrder
+func sectionPosition(sectionID byte) (int, bool) {
+	for position, id := range sectionsOrder {
+		if id == sectionID {
+			return position, true
+		}
+	}
+
+	return 0, false
+}
+
 type Parser interface {
-	Parse(*bytes.Reader) error
+	Parse(BinaryReader) error
 }
 
+// BinaryReader is the minimum set of methods a source
+// needs to implement in order to be decoded as a wasm module
 type BinaryReader interface {
 	io.Reader
 	io.ByteReader
 }
 
-type Module struct {
-	Magic   uint32
-	Version uint32
+// NewBinaryReader returns the reader itself when it already implements
+// BinaryReader otherwise it is wrapped into a buffered reader so it can be
+// consumed byte by byte as the bytes arrive
+func NewBinaryReader(r io.Reader) BinaryReader {
+	if br, ok := r.(BinaryReader); ok {
+		return br
+	}
+
+	return bufio.NewReader(r)
+}
+
+// offsetReader keeps track of how many bytes were read so
+// decode errors can tell where in the module they happened
+type offsetReader struct {
+	reader BinaryReader
+	offset int64
+}
+
+func (r *offsetReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+func (r *offsetReader) ReadByte() (byte, error) {
+	b, err := r.reader.ReadByte()
+	if err == nil {
+		r.offset++
+	}
+
+	return b, err
 }
 
 type BinaryParser struct {
 	filepath string
-	reader   BinaryReader
+	reader   *offsetReader
 
 	Module *Module
 
-	Parsers map[byte]Parser
+	// bodies is the amount of function bodies in the code section
+	bodies int
+
+	// sections keeps the ids of the decoded sections in the order they
+	// were found, so the custom sections are encoded back in place
+	sections []byte
 }
 
 func NewBinaryParser(filepath string) (*BinaryParser, error) {
@@ -53,28 +117,61 @@ func NewBinaryParser(filepath string) (*BinaryParser, error) {
 		return nil, fmt.Errorf("cannot read file: %w", err)
 	}
 
+	bp := NewBinaryReaderParser(bytes.NewReader(fbytes))
+	bp.filepath = filepath
+	return bp, nil
+}
+
+// NewBinaryReaderParser creates a parser that reads the module
+// from any BinaryReader instead of a file in the filesystem
+func NewBinaryReaderParser(reader BinaryReader) *BinaryParser {
 	return &BinaryParser{
-		Module:   new(Module),
-		filepath: filepath,
-		reader:   bytes.NewReader(fbytes),
+		Module: new(Module),
+		reader: &offsetReader{reader: reader},
+	}
+}
+
+// newSectionParser returns the parser of the section, a new parser
+// is used for each section as custom sections can appear many times
+func newSectionParser(sectionID byte) (Parser, bool) {
+	switch sectionID {
+	case CustomSection:
+		return new(CustomSectionParser), true
+	case TypeSection:
+		return new(TypeSectionParser), true
+	case ImportsSection:
+		return new(ImportsSectionParser), true
+	case FunctionSection:
+		return new(FunctionSectionParser), true
+	case TableSection:
+		return new(TableSectionParser), true
+	case MemorySection:
+		return new(MemorySectionParser), true
+	case GlobalSection:
+		return new(GlobalSectionParser), true
+	case ExportSection:
+		return new(ExportSectionParser), true
+	case StartSection:
+		return new(StartSectionParser), true
+	case ElementSection:
+		return new(ElementSectionParser), true
+	case DataCountSection:
+		return new(DataCountSectionParser), true
+	case CodeSection:
+		return new(CodeSectionParser), true
+	case DataSection:
+		return new(DataSectionParser), true
+	}
 
-		Parsers: map[byte]Parser{
-			TypeSection:     new(TypeSectionParser),
-			ImportsSection:  new(ImportsSectionParser),
-			FunctionSection: new(FunctionSectionParser),
-			ExportSection:   new(ExportSectionParser),
-			CodeSection:     new(CodeSectionParser),
-			StartSection:    new(StartSectionParser),
-		},
-	}, nil
+	return nil, false
 }
 
 func (bp *BinaryParser) ParseMagicNumber() error {
 	const magicNumberLen = 4
 	magicBytes := make([]byte, magicNumberLen)
 
-	n, err := bp.reader.Read(magicBytes)
-	if err != nil {
+	n, err := io.ReadFull(bp.reader, magicBytes)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
 		return fmt.Errorf("cannot read magic number: %w", err)
 	}
 
@@ -84,6 +181,10 @@ func (bp *BinaryParser) ParseMagicNumber() error {
 	}
 
 	bp.Module.Magic = binary.LittleEndian.Uint32(magicBytes)
+	if bp.Module.Magic != MagicNumber {
+		return fmt.Errorf("%w: 0x%x", ErrMagicNumber, bp.Module.Magic)
+	}
+
 	return nil
 }
 
@@ -91,8 +192,8 @@ func (bp *BinaryParser) ParseVersion() error {
 	const versionBytesLen = 4
 	versionBytes := make([]byte, versionBytesLen)
 
-	n, err := bp.reader.Read(versionBytes)
-	if err != nil {
+	n, err := io.ReadFull(bp.reader, versionBytes)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
 		return fmt.Errorf("cannot read version: %w", err)
 	}
 
@@ -102,50 +203,114 @@ func (bp *BinaryParser) ParseVersion() error {
 	}
 
 	bp.Module.Version = binary.LittleEndian.Uint32(versionBytes)
+	if bp.Module.Version != Version {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, bp.Module.Version)
+	}
+
 	return nil
 }
 
+// ParseSection decodes the sections until the end of the module, errors
+// are returned as *DecodeError telling where the module is malformed
 func (bp *BinaryParser) ParseSection() error {
-	sectionByte, err := bp.reader.ReadByte()
-	if err != nil {
-		return fmt.Errorf("reading section byte: %w", err)
-	}
+	lastPosition := -1
 
-	_, sectionsLen, err := leb128.DecodeUint(bp.reader.(*bytes.Reader))
-	if err != nil {
-		return fmt.Errorf("reading section len: %w", err)
-	}
+	for {
+		sectionStart := bp.reader.offset
+		sectionByte, err := bp.reader.ReadByte()
+		if errors.Is(err, io.EOF) {
+			// the module ends right after a section, any other
+			// end of the module is reported as unexpected
+			return nil
+		} else if err != nil {
+			return newDecodeError(NoSection, sectionStart, fmt.Errorf("reading section byte: %w", err))
+		}
+
+		sectionID := int(sectionByte)
+		if sectionByte != CustomSection {
+			position, ok := sectionPosition(sectionByte)
+			switch {
+			case !ok:
+				return newDecodeError(sectionID, sectionStart,
+					fmt.Errorf("unknown section ID 0x%x", sectionByte))
+			case position == lastPosition:
+				return newDecodeError(sectionID, sectionStart,
+					fmt.Errorf("%w: 0x%x", ErrDuplicateSection, sectionByte))
+			case position < lastPosition:
+				return newDecodeError(sectionID, sectionStart,
+					fmt.Errorf("%w: 0x%x after 0x%x", ErrSectionOutOfOrder, sectionByte, bp.lastSection()))
+			}
+
+			lastPosition = position
+		}
 
-	if sectionsLen > 0 {
-		fmt.Println(sectionByte, "sections:", sectionsLen)
-		err := bp.parseSectionContents(sectionByte, sectionsLen)
+		_, sectionLen, err := leb128.DecodeUint32(bp.reader)
 		if err != nil {
-			return fmt.Errorf("parsing section contents: %w", err)
+			return newDecodeError(sectionID, bp.reader.offset, fmt.Errorf("reading section len: %w", err))
+		}
+
+		if err := bp.parseSectionContents(sectionByte, sectionLen); err != nil {
+			return err
+		}
+
+		bp.sections = append(bp.sections, sectionByte)
+	}
+}
+
+// lastSection returns the id of the last non custom section decoded
+func (bp *BinaryParser) lastSection() byte {
+	for idx := len(bp.sections) - 1; idx >= 0; idx-- {
+		if bp.sections[idx] != CustomSection {
+			return bp.sections[idx]
 		}
 	}
 
-	return bp.ParseSection()
+	return CustomSection
 }
 
-func (bp *BinaryParser) parseSectionContents(sectionID byte, sectionLen uint) error {
-	contents := make([]byte, sectionLen)
-	n, err := bp.reader.Read(contents)
+func (bp *BinaryParser) parseSectionContents(sectionID byte, sectionLen uint32) error {
+	contentsStart := bp.reader.offset
 
+	// the contents are read as they arrive instead of allocating
+	// the whole section length upfront, as it may be malformed
+	contents, err := io.ReadAll(io.LimitReader(bp.reader, int64(sectionLen)))
 	if err != nil {
-		return fmt.Errorf("cannot read section contents: %w", err)
-	} else if n != int(sectionLen) {
-		return fmt.Errorf("expected %d bytes. read %d bytes", sectionLen, n)
+		return newDecodeError(int(sectionID), bp.reader.offset,
+			fmt.Errorf("cannot read section contents: %w", err))
 	}
 
-	parser, ok := bp.Parsers[sectionID]
+	if len(contents) != int(sectionLen) {
+		return newDecodeError(int(sectionID), bp.reader.offset,
+			fmt.Errorf("%w: expected %d bytes. read %d bytes", io.ErrUnexpectedEOF, sectionLen, len(contents)))
+	}
+
+	parser, ok := newSectionParser(sectionID)
 	if !ok {
-		return fmt.Errorf("empty parser for section ID 0x%x", sectionID)
+		return newDecodeError(int(sectionID), contentsStart,
+			fmt.Errorf("empty parser for section ID 0x%x", sectionID))
 	}
 
-	err = parser.Parse(bytes.NewReader(contents))
-	if err != nil {
-		return fmt.Errorf("failed while parsing section 0x%x: %w", sectionID, err)
+	reader := bytes.NewReader(contents)
+	err = parser.Parse(reader)
+	if err == nil && reader.Len() != 0 {
+		// the section declares more bytes than its entries use
+		err = fmt.Errorf("%w: %d bytes left", ErrSectionSize, reader.Len())
 	}
 
-	return nil
+	if err == nil {
+		bp.addSection(parser)
+		return nil
+	}
+
+	decodeErr := newDecodeError(int(sectionID),
+		contentsStart+int64(len(contents)-reader.Len()),
+		fmt.Errorf("failed while parsing section 0x%x: %w", sectionID, err))
+
+	var bodyErr *functionBodyError
+	if errors.As(err, &bodyErr) {
+		decodeErr.FuncIndex = len(bp.Module.ImportedFunctions()) + bodyErr.index
+		decodeErr.Offset = contentsStart + int64(bodyErr.offset)
+	}
+
+	return decodeErr
 }
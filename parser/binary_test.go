@@ -4,9 +4,11 @@ import (
 	"encoding/binary"
 	"testing"
 
+	"github.com/EclesioMeloJunior/wasvm/opcodes"
 	"github.com/EclesioMeloJunior/wasvm/parser"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const simpleWasm = "../resources/simple.wasm"
@@ -43,13 +45,10 @@ func TestSimpleWasm_BinaryParse_Sections(t *testing.T) {
 	err = wasm.ParseSection()
 	assert.NoError(t, err)
 
-	typeSection := wasm.Parsers[parser.TypeSection].(*parser.TypeSectionParser)
-	assert.Len(t, typeSection.Types, 1)
-
-	for _, ttype := range typeSection.Types {
-		fs, ok := ttype.(*parser.FunctionSignatureParser)
-		assert.True(t, ok)
+	module := wasm.Module
+	assert.Len(t, module.Types, 1)
 
+	for _, fs := range module.Types {
 		assert.Len(t, fs.ParamsTypes, 0)
 		assert.Len(t, fs.ResultsTypes, 1)
 
@@ -58,26 +57,22 @@ func TestSimpleWasm_BinaryParse_Sections(t *testing.T) {
 		assert.Equal(t, rt.SpecType, parser.NumType)
 	}
 
-	functionSection := wasm.Parsers[parser.FunctionSection].(*parser.FunctionSectionParser)
-	assert.Len(t, functionSection.Funcs, 1)
+	assert.Len(t, module.Funcs, 1)
 
-	for _, f := range functionSection.Funcs {
+	for _, f := range module.Funcs {
 		assert.Equal(t, f.TypeIndex, 0)
 	}
 
-	exportSection := wasm.Parsers[parser.ExportSection].(*parser.ExportSectionParser)
-	assert.Len(t, exportSection.Exports, 1)
+	assert.Len(t, module.Exports, 1)
 
-	for _, exported := range exportSection.Exports {
+	for _, exported := range module.Exports {
 		assert.Equal(t, exported.Name, "helloWorld")
 		assert.Equal(t, exported.Index, 0)
 		assert.Equal(t, exported.Type, parser.ExportedFunc)
 	}
 
-	codeSection := wasm.Parsers[parser.CodeSection].(*parser.CodeSectionParser)
-	assert.Len(t, codeSection.FunctionsCode, 1)
-
-	for _, code := range codeSection.FunctionsCode {
+	for _, f := range module.Funcs {
+		code := f.Code
 		assert.Len(t, code.Locals, 0)
 		assert.Len(t, code.Body, 3)
 
@@ -85,5 +80,215 @@ func TestSimpleWasm_BinaryParse_Sections(t *testing.T) {
 		for idx, instr := range expectedBody {
 			assert.Equal(t, code.Body[idx], instr)
 		}
+
+		expectedInstructions := []parser.Instruction{
+			{Opcode: opcodes.I32Const, Offset: 0, I32: 42},
+			{Opcode: opcodes.End, Offset: 2},
+		}
+		assert.Equal(t, expectedInstructions, code.Instructions)
+	}
+}
+
+func TestSimpleImportWasm_ImportsAndFunctionIndexSpace(t *testing.T) {
+	wasm, err := parser.BinaryFormat("../resources/simple_import.wasm")
+	require.NoError(t, err)
+
+	module := wasm.Module
+	require.Len(t, module.Imports, 1)
+
+	imported := module.Imports[0]
+	assert.Equal(t, "console", imported.Module)
+	assert.Equal(t, "log", imported.Name)
+	assert.Equal(t, parser.ImportedFunc, imported.Type)
+	assert.Equal(t, 0, imported.TypeIndex)
+
+	require.Len(t, module.Funcs, 2)
+	require.Len(t, module.DefinedFuncs(), 1)
+
+	logFunc, err := module.Function(0)
+	require.NoError(t, err)
+	assert.Equal(t, imported, logFunc.Import)
+	assert.Nil(t, logFunc.Code)
+	assert.Len(t, logFunc.Signature.ParamsTypes, 1)
+
+	mainFunc, err := module.Function(1)
+	require.NoError(t, err)
+	assert.Nil(t, mainFunc.Import)
+	assert.Equal(t, 1, mainFunc.TypeIndex)
+	assert.NotNil(t, mainFunc.Code)
+
+	_, err = module.Function(2)
+	require.ErrorIs(t, err, parser.ErrFunctionIndexOutOfBounds)
+}
+
+func TestMultiValueWasm_ModuleLookups(t *testing.T) {
+	wasm, err := parser.BinaryFormat("../resources/multi_value.wasm")
+	require.NoError(t, err)
+
+	module := wasm.Module
+	export, ok := module.Export("sum_and_diff")
+	require.True(t, ok)
+
+	_, ok = module.Export("missing")
+	require.False(t, ok)
+
+	function, err := module.Function(export.Index)
+	require.NoError(t, err)
+
+	pair, err := module.Signature(0)
+	require.NoError(t, err)
+	require.True(t, pair.Equal(function.Signature))
+
+	block := function.Code.Instructions[2]
+	require.Equal(t, opcodes.Block, block.Opcode)
+
+	params, results, err := module.BlockSignature(&block)
+	require.NoError(t, err)
+	require.Equal(t, pair.ParamsTypes, params)
+	require.Equal(t, pair.ResultsTypes, results)
+
+	_, err = module.Signature(len(module.Types))
+	require.ErrorIs(t, err, parser.ErrUnknownType)
+}
+
+func TestStartWasm_StartSection(t *testing.T) {
+	wasm, err := parser.BinaryFormat("../resources/start.wasm")
+	require.NoError(t, err)
+
+	require.NotNil(t, wasm.Module.Start)
+	require.Equal(t, 1, *wasm.Module.Start)
+
+	wasm, err = parser.BinaryFormat(simpleWasm)
+	require.NoError(t, err)
+
+	require.Nil(t, wasm.Module.Start)
+}
+
+func TestMemoryWasm_MemorySection(t *testing.T) {
+	wasm, err := parser.BinaryFormat("../resources/memory.wasm")
+	require.NoError(t, err)
+
+	require.Len(t, wasm.Module.Memories, 1)
+	require.Equal(t, parser.Limits{Min: 1, Max: 2, HasMax: true}, wasm.Module.Memories[0].Limits)
+}
+
+func TestGlobalWasm_GlobalSection(t *testing.T) {
+	wasm, err := parser.BinaryFormat("../resources/global.wasm")
+	require.NoError(t, err)
+
+	module := wasm.Module
+	require.Len(t, module.ImportedGlobals(), 1)
+	require.Equal(t, &parser.GlobalType{ValType: parser.I32}, module.ImportedGlobals()[0].Global)
+
+	require.Len(t, module.Globals, 2)
+
+	require.Equal(t, &parser.GlobalType{ValType: parser.I32, Mutable: true}, module.Globals[0].Type)
+	require.Equal(t, []byte{0x23, 0x00, 0x0B}, module.Globals[0].Init)
+
+	require.Equal(t, &parser.GlobalType{ValType: parser.I64}, module.Globals[1].Type)
+	require.Equal(t, byte(0x0B), module.Globals[1].Init[len(module.Globals[1].Init)-1])
+}
+
+func TestTableWasm_TableAndElementSections(t *testing.T) {
+	wasm, err := parser.BinaryFormat("../resources/table.wasm")
+	require.NoError(t, err)
+
+	module := wasm.Module
+	require.Len(t, module.Tables, 1)
+	require.Equal(t, parser.FUNC_REF_TYPE, module.Tables[0].ElemType.SpecByte)
+	require.Equal(t, parser.Limits{Min: 4}, module.Tables[0].Limits)
+
+	require.Len(t, module.Elements, 4)
+
+	active := module.Elements[0]
+	require.Equal(t, parser.ElementActive, active.Mode)
+	require.Equal(t, []byte{0x41, 0x00, 0x0B}, active.Offset)
+	require.Equal(t, []int{0, 1}, active.FuncIndices)
+
+	passive := module.Elements[1]
+	require.Equal(t, parser.ElementPassive, passive.Mode)
+	require.Equal(t, []int{2}, passive.FuncIndices)
+
+	declarative := module.Elements[2]
+	require.Equal(t, parser.ElementDeclarative, declarative.Mode)
+	require.Equal(t, []int{3}, declarative.FuncIndices)
+
+	expressions := module.Elements[3]
+	require.Equal(t, parser.ElementActive, expressions.Mode)
+	require.Equal(t, uint32(6), expressions.Flags)
+	require.Equal(t, 0, expressions.Table)
+	require.Equal(t, [][]byte{{0xD2, 0x03, 0x0B}, {0xD0, 0x70, 0x0B}}, expressions.Init)
+}
+
+func TestDataWasm_DataAndDataCountSections(t *testing.T) {
+	wasm, err := parser.BinaryFormat("../resources/data.wasm")
+	require.NoError(t, err)
+
+	module := wasm.Module
+	require.NotNil(t, module.DataCount)
+	require.Equal(t, uint32(2), *module.DataCount)
+
+	require.Len(t, module.Data, 2)
+
+	active := module.Data[0]
+	require.Equal(t, parser.DataActive, active.Mode)
+	require.Equal(t, []byte{0x41, 0x10, 0x0B}, active.Offset)
+	require.Equal(t, []byte("hello"), active.Init)
+
+	passive := module.Data[1]
+	require.Equal(t, parser.DataPassive, passive.Mode)
+	require.Nil(t, passive.Offset)
+	require.Equal(t, []byte("world!"), passive.Init)
+}
+
+func TestNamesWasm_CustomAndNameSections(t *testing.T) {
+	wasm, err := parser.BinaryFormat("../resources/names.wasm")
+	require.NoError(t, err)
+
+	module := wasm.Module
+	require.Len(t, module.Customs, 2)
+
+	custom, ok := module.Custom("wasvm")
+	require.True(t, ok)
+	require.Equal(t, []byte("custom section contents"), custom.Data)
+
+	_, ok = module.Custom("name")
+	require.True(t, ok)
+
+	names := module.Names
+	require.NotNil(t, names)
+	require.Equal(t, "math", names.Module)
+
+	for idx, expected := range []string{"fac", "oob", "outer"} {
+		name, ok := names.FunctionName(idx)
+		require.True(t, ok)
+		require.Equal(t, expected, name)
 	}
+
+	_, ok = names.FunctionName(3)
+	require.False(t, ok)
+
+	local, ok := names.LocalName(0, 0)
+	require.True(t, ok)
+	require.Equal(t, "n", local)
+
+	_, ok = names.LocalName(1, 0)
+	require.False(t, ok)
+}
+
+func TestMalformedNameSection_IsKeptAsRawBytes(t *testing.T) {
+	wasm, err := parser.Decode([]byte{
+		0x00, 0x61, 0x73, 0x6D, 0x01, 0x00, 0x00, 0x00,
+		// custom section "name" with a truncated function names subsection
+		0x00, 0x08, 0x04, 'n', 'a', 'm', 'e', 0x01, 0x05, 0x01,
+	})
+	require.NoError(t, err)
+
+	module := wasm.Module
+	require.Len(t, module.Customs, 1)
+	require.Equal(t, []byte{0x01, 0x05, 0x01}, module.Customs[0].Data)
+	require.Nil(t, module.Names)
+
+	_, ok := module.Names.FunctionName(0)
+	require.False(t, ok)
 }
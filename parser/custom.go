@@ -0,0 +1,177 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const nameSectionName = "name"
+
+// name section subsections ids
+const (
+	moduleNameSubsection   byte = 0x00
+	functionNameSubsection byte = 0x01
+	localNameSubsection    byte = 0x02
+)
+
+var ErrMalformedNameSection = errors.New("malformed name section")
+
+type Custom struct {
+	Name string
+	Data []byte
+}
+
+// NameSection holds the debug names of the module, its functions and their locals
+type NameSection struct {
+	Module    string
+	Functions map[int]string
+	Locals    map[int]map[int]string
+}
+
+// FunctionName returns the name of the function at funcIdx of the function index space
+func (n *NameSection) FunctionName(funcIdx int) (string, bool) {
+	if n == nil {
+		return "", false
+	}
+
+	name, ok := n.Functions[funcIdx]
+	return name, ok
+}
+
+// LocalName returns the name of the local at localIdx of the function funcIdx
+func (n *NameSection) LocalName(funcIdx, localIdx int) (string, bool) {
+	if n == nil {
+		return "", false
+	}
+
+	name, ok := n.Locals[funcIdx][localIdx]
+	return name, ok
+}
+
+// CustomSectionParser keeps a custom section as raw bytes, the `name` section is
+// also decoded into Names. As custom sections do not change the module semantics
+// a malformed name section is kept only as raw bytes
+type CustomSectionParser struct {
+	Custom *Custom
+	Names  *NameSection
+}
+
+func (c *CustomSectionParser) Parse(b BinaryReader) error {
+	name, err := readName(b)
+	if err != nil {
+		return fmt.Errorf("cannot read custom section name: %w", err)
+	}
+
+	data, err := io.ReadAll(b)
+	if err != nil {
+		return fmt.Errorf("cannot read custom section %s contents: %w", name, err)
+	}
+
+	c.Custom = &Custom{
+		Name: name,
+		Data: data,
+	}
+
+	if name == nameSectionName {
+		names, err := parseNameSection(bytes.NewReader(data))
+		if err == nil {
+			c.Names = names
+		}
+	}
+
+	return nil
+}
+
+func parseNameSection(b *bytes.Reader) (*NameSection, error) {
+	names := &NameSection{
+		Functions: make(map[int]string),
+		Locals:    make(map[int]map[int]string),
+	}
+
+	for b.Len() > 0 {
+		subsectionID, err := b.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("%w: cannot read subsection id: %s", ErrMalformedNameSection, err)
+		}
+
+		subsectionLen, err := decodeLength(b)
+		if err != nil {
+			return nil, fmt.Errorf("%w: cannot read subsection length: %s", ErrMalformedNameSection, err)
+		}
+
+		contents := make([]byte, subsectionLen)
+		if _, err := io.ReadFull(b, contents); err != nil {
+			return nil, fmt.Errorf("%w: cannot read subsection: %s", ErrMalformedNameSection, err)
+		}
+
+		subsection := bytes.NewReader(contents)
+
+		switch subsectionID {
+		case moduleNameSubsection:
+			names.Module, err = readName(subsection)
+		case functionNameSubsection:
+			names.Functions, err = parseNameMap(subsection)
+		case localNameSubsection:
+			names.Locals, err = parseIndirectNameMap(subsection)
+		default:
+			// other subsections (labels, types, ...) are not used yet
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: subsection %d: %s", ErrMalformedNameSection, subsectionID, err)
+		}
+	}
+
+	return names, nil
+}
+
+func parseNameMap(b BinaryReader) (map[int]string, error) {
+	namesLen, err := decodeLength(b)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read number of names: %w", err)
+	}
+
+	nameMap := make(map[int]string)
+	for i := 0; i < int(namesLen); i++ {
+		idx, err := decodeIndex(b)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read name index at %d: %w", i, err)
+		}
+
+		name, err := readName(b)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read name at %d: %w", i, err)
+		}
+
+		nameMap[int(idx)] = name
+	}
+
+	return nameMap, nil
+}
+
+func parseIndirectNameMap(b BinaryReader) (map[int]map[int]string, error) {
+	mapsLen, err := decodeLength(b)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read number of name maps: %w", err)
+	}
+
+	indirectNameMap := make(map[int]map[int]string)
+	for i := 0; i < int(mapsLen); i++ {
+		idx, err := decodeIndex(b)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read name map index at %d: %w", i, err)
+		}
+
+		nameMap, err := parseNameMap(b)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read name map at %d: %w", i, err)
+		}
+
+		indirectNameMap[int(idx)] = nameMap
+	}
+
+	return indirectNameMap, nil
+}
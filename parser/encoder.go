@@ -0,0 +1,483 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/EclesioMeloJunior/wasvm/leb128"
+	"github.com/EclesioMeloJunior/wasvm/opcodes"
+)
+
+const (
+	// MagicNumber is the little endian value of `\0asm`
+	MagicNumber uint32 = 0x6d736100
+	Version     uint32 = 0x01
+)
+
+var (
+	ErrUnknownSectionParser = errors.New("unknown section parser")
+	ErrUnknownImportDesc    = errors.New("import without descriptor")
+)
+
+// Encode writes the module back to the binary format, the non empty sections
+// are written in the order the binary format defines while each custom
+// section is kept after the section it was decoded after. The function
+// bodies are written from their instructions, so decoding and encoding a
+// module only gives back the same bytes when it is canonically encoded:
+// minimal LEB128 integers, no empty sections and locals of the same type
+// declared together
+func Encode(bp *BinaryParser) ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	magic, version := MagicNumber, Version
+	if bp.Module != nil && bp.Module.Magic != 0 {
+		magic, version = bp.Module.Magic, bp.Module.Version
+	}
+
+	_ = binary.Write(buf, binary.LittleEndian, magic)
+	_ = binary.Write(buf, binary.LittleEndian, version)
+
+	module := bp.Module
+	if module == nil {
+		module = new(Module)
+	}
+
+	anchors := customAnchors(bp.sections)
+	nextCustom := 0
+
+	// writeCustoms writes the custom sections that were decoded right
+	// after the section at the given position of sectionsOrder
+	writeCustoms := func(position int) {
+		for nextCustom < len(module.Customs) && nextCustom < len(anchors) &&
+			anchors[nextCustom] == position {
+			contents := new(bytes.Buffer)
+			encodeCustom(contents, module.Customs[nextCustom])
+			writeSection(buf, CustomSection, contents)
+			nextCustom++
+		}
+	}
+
+	writeCustoms(-1)
+	for position, sectionID := range sectionsOrder {
+		if !sectionIsEmpty(module, sectionID) {
+			contents := new(bytes.Buffer)
+			if err := encodeSection(contents, module, sectionID); err != nil {
+				return nil, fmt.Errorf("cannot encode section 0x%x: %w", sectionID, err)
+			}
+
+			writeSection(buf, sectionID, contents)
+		}
+
+		writeCustoms(position)
+	}
+
+	// the custom sections added after the decoding go at the end
+	for ; nextCustom < len(module.Customs); nextCustom++ {
+		contents := new(bytes.Buffer)
+		encodeCustom(contents, module.Customs[nextCustom])
+		writeSection(buf, CustomSection, contents)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// customAnchors returns, for each custom section in the decoded order, the
+// position in sectionsOrder of the non custom section found before it or
+// -1 when the custom section comes before every other section
+func customAnchors(decoded []byte) []int {
+	anchors := make([]int, 0)
+	last := -1
+
+	for _, sectionID := range decoded {
+		if sectionID == CustomSection {
+			anchors = append(anchors, last)
+			continue
+		}
+
+		if position, ok := sectionPosition(sectionID); ok {
+			last = position
+		}
+	}
+
+	return anchors
+}
+
+func writeSection(buf *bytes.Buffer, sectionID byte, contents *bytes.Buffer) {
+	buf.WriteByte(sectionID)
+	writeUint(buf, uint32(contents.Len()))
+	buf.Write(contents.Bytes())
+}
+
+func sectionIsEmpty(module *Module, sectionID byte) bool {
+	switch sectionID {
+	case TypeSection:
+		return len(module.Types) == 0
+	case ImportsSection:
+		return len(module.Imports) == 0
+	case FunctionSection, CodeSection:
+		return len(module.DefinedFuncs()) == 0
+	case TableSection:
+		return len(module.Tables) == 0
+	case MemorySection:
+		return len(module.Memories) == 0
+	case GlobalSection:
+		return len(module.Globals) == 0
+	case ExportSection:
+		return len(module.Exports) == 0
+	case StartSection:
+		return module.Start == nil
+	case ElementSection:
+		return len(module.Elements) == 0
+	case DataCountSection:
+		return module.DataCount == nil
+	case DataSection:
+		return len(module.Data) == 0
+	}
+
+	return true
+}
+
+func encodeSection(buf *bytes.Buffer, module *Module, sectionID byte) error {
+	switch sectionID {
+	case TypeSection:
+		encodeTypes(buf, module.Types)
+	case ImportsSection:
+		return encodeImports(buf, module.Imports)
+	case FunctionSection:
+		defined := module.DefinedFuncs()
+		writeUint(buf, uint32(len(defined)))
+		for _, function := range defined {
+			writeUint(buf, uint32(function.TypeIndex))
+		}
+	case TableSection:
+		writeUint(buf, uint32(len(module.Tables)))
+		for _, table := range module.Tables {
+			encodeTableType(buf, table)
+		}
+	case MemorySection:
+		writeUint(buf, uint32(len(module.Memories)))
+		for _, memory := range module.Memories {
+			encodeLimits(buf, memory.Limits)
+		}
+	case GlobalSection:
+		writeUint(buf, uint32(len(module.Globals)))
+		for _, global := range module.Globals {
+			encodeGlobalType(buf, global.Type)
+			buf.Write(global.Init)
+		}
+	case ExportSection:
+		writeUint(buf, uint32(len(module.Exports)))
+		for _, export := range module.Exports {
+			writeName(buf, export.Name)
+			buf.WriteByte(byte(export.Type))
+			writeUint(buf, uint32(export.Index))
+		}
+	case StartSection:
+		if module.Start == nil {
+			return fmt.Errorf("%w: start function not defined", ErrInvalidStartFunction)
+		}
+		writeUint(buf, uint32(*module.Start))
+	case ElementSection:
+		writeUint(buf, uint32(len(module.Elements)))
+		for idx, element := range module.Elements {
+			if err := encodeElement(buf, element); err != nil {
+				return fmt.Errorf("element segment at %d: %w", idx, err)
+			}
+		}
+	case DataCountSection:
+		if module.DataCount == nil {
+			return fmt.Errorf("%w: data count not defined", ErrDataCountRequired)
+		}
+		writeUint(buf, *module.DataCount)
+	case CodeSection:
+		defined := module.DefinedFuncs()
+		writeUint(buf, uint32(len(defined)))
+		for idx, function := range defined {
+			if function.Code == nil {
+				return fmt.Errorf("%w: %d", ErrFunctionWithouCode, len(module.Funcs)-len(defined)+idx)
+			}
+			if err := encodeCode(buf, function.Code); err != nil {
+				return fmt.Errorf("function %d: %w", len(module.Funcs)-len(defined)+idx, err)
+			}
+		}
+	case DataSection:
+		writeUint(buf, uint32(len(module.Data)))
+		for idx, segment := range module.Data {
+			if err := encodeData(buf, segment); err != nil {
+				return fmt.Errorf("data segment at %d: %w", idx, err)
+			}
+		}
+	default:
+		return fmt.Errorf("%w: 0x%x", ErrUnknownSectionParser, sectionID)
+	}
+
+	return nil
+}
+
+func encodeTypes(buf *bytes.Buffer, types []*FunctionSignatureParser) {
+	writeUint(buf, uint32(len(types)))
+	for _, signature := range types {
+		buf.WriteByte(FunctionTag)
+		encodeValueTypes(buf, signature.ParamsTypes)
+		encodeValueTypes(buf, signature.ResultsTypes)
+	}
+}
+
+func encodeImports(buf *bytes.Buffer, imports []*Import) error {
+	writeUint(buf, uint32(len(imports)))
+	for idx, imported := range imports {
+		writeName(buf, imported.Module)
+		writeName(buf, imported.Name)
+		buf.WriteByte(byte(imported.Type))
+
+		switch {
+		case imported.Type == ImportedFunc:
+			writeUint(buf, uint32(imported.TypeIndex))
+		case imported.Type == ImportedTable && imported.Table != nil:
+			encodeTableType(buf, imported.Table)
+		case imported.Type == ImportedMem && imported.Memory != nil:
+			encodeLimits(buf, imported.Memory.Limits)
+		case imported.Type == ImportedGlobal && imported.Global != nil:
+			encodeGlobalType(buf, imported.Global)
+		default:
+			return fmt.Errorf("%w: type 0x%x at %d", ErrUnknownImportDesc, imported.Type, idx)
+		}
+	}
+
+	return nil
+}
+
+// encodeCode writes the function body from its instructions, the locals are
+// written back as declarations of consecutive locals of the same type
+func encodeCode(buf *bytes.Buffer, code *CodeParser) error {
+	type declaration struct {
+		amount    uint32
+		localType Type
+	}
+
+	declarations := make([]declaration, 0, len(code.Locals))
+	for _, local := range code.Locals {
+		last := len(declarations) - 1
+		if last >= 0 && declarations[last].localType == local {
+			declarations[last].amount++
+			continue
+		}
+
+		declarations = append(declarations, declaration{amount: 1, localType: local})
+	}
+
+	// the code created from its encoded body alone is decoded first
+	instructions := code.Instructions
+	if instructions == nil {
+		decoded, err := DecodeInstructions(code.Body)
+		if err != nil {
+			return err
+		}
+
+		instructions = decoded
+	}
+
+	body := new(bytes.Buffer)
+	writeUint(body, uint32(len(declarations)))
+	for _, decl := range declarations {
+		writeUint(body, decl.amount)
+		body.WriteByte(decl.localType.SpecByte)
+	}
+
+	for idx := range instructions {
+		if err := encodeInstruction(body, &instructions[idx]); err != nil {
+			return fmt.Errorf("instruction %d: %w", idx, err)
+		}
+	}
+
+	writeUint(buf, uint32(body.Len()))
+	buf.Write(body.Bytes())
+	return nil
+}
+
+// encodeInstruction writes the opcode followed by its immediates, it is
+// the inverse of decodeInstruction
+func encodeInstruction(buf *bytes.Buffer, inst *Instruction) error {
+	if inst.Opcode > 0xFF {
+		buf.WriteByte(byte(inst.Opcode >> 8))
+		writeUint(buf, uint32(inst.Opcode&0xFF))
+	} else {
+		buf.WriteByte(byte(inst.Opcode))
+	}
+
+	switch inst.Opcode {
+	case opcodes.Unreachable, opcodes.Nop, opcodes.Else, opcodes.End, opcodes.Return,
+		opcodes.Drop, opcodes.Select:
+	case opcodes.Block, opcodes.Loop, opcodes.If:
+		switch {
+		case inst.TypeIndexed:
+			buf.Write(leb128.EncodeInt(int(inst.Index)))
+		case len(inst.Results) == 0:
+			buf.WriteByte(opcodes.EmptyBlockType)
+		case len(inst.Results) == 1:
+			buf.WriteByte(inst.Results[0].SpecByte)
+		default:
+			return fmt.Errorf("%s: %d results must use a type index", inst.Opcode, len(inst.Results))
+		}
+	case opcodes.LocalGet, opcodes.LocalSet, opcodes.LocalTee, opcodes.GlobalGet, opcodes.GlobalSet,
+		opcodes.Call, opcodes.RefFunc, opcodes.DataDrop, opcodes.Br, opcodes.BrIf:
+		writeUint(buf, inst.Index)
+	case opcodes.BrTable:
+		writeUint(buf, uint32(len(inst.Labels)))
+		for _, label := range inst.Labels {
+			writeUint(buf, label)
+		}
+		writeUint(buf, inst.Index)
+	case opcodes.CallIndirect:
+		writeUint(buf, inst.Index)
+		writeUint(buf, inst.Table)
+	case opcodes.SelectTyped:
+		encodeValueTypes(buf, inst.Types)
+	case opcodes.RefNull:
+		if len(inst.Types) != 1 {
+			return fmt.Errorf("%s: expected 1 reference type, got %d", inst.Opcode, len(inst.Types))
+		}
+		buf.WriteByte(inst.Types[0].SpecByte)
+	case opcodes.I32Load, opcodes.I64Load, opcodes.F32Load, opcodes.F64Load,
+		opcodes.I32Load8S, opcodes.I32Load8U, opcodes.I32Load16S, opcodes.I32Load16U,
+		opcodes.I64Load8S, opcodes.I64Load8U, opcodes.I64Load16S, opcodes.I64Load16U,
+		opcodes.I64Load32S, opcodes.I64Load32U,
+		opcodes.I32Store, opcodes.I64Store, opcodes.F32Store, opcodes.F64Store,
+		opcodes.I32Store8, opcodes.I32Store16,
+		opcodes.I64Store8, opcodes.I64Store16, opcodes.I64Store32:
+		writeUint(buf, inst.MemArg.Align)
+		writeUint(buf, inst.MemArg.Offset)
+	case opcodes.MemorySize, opcodes.MemoryGrow:
+		buf.WriteByte(0x00)
+	case opcodes.MemoryInit:
+		writeUint(buf, inst.Index)
+		buf.WriteByte(0x00)
+	case opcodes.I32Const:
+		buf.Write(leb128.EncodeInt(int(inst.I32)))
+	case opcodes.I64Const:
+		buf.Write(leb128.EncodeInt(int(inst.I64)))
+	case opcodes.F32Const:
+		_ = binary.Write(buf, binary.LittleEndian, inst.F32)
+	case opcodes.F64Const:
+		_ = binary.Write(buf, binary.LittleEndian, inst.F64)
+	default:
+		if _, ok := numericInstructions[inst.Opcode]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownInstruction, inst.Opcode)
+		}
+	}
+
+	return nil
+}
+
+// encodeElement writes the segment using the same encoding variant it was decoded
+// with, see parseElement for the meaning of each flags bit
+func encodeElement(buf *bytes.Buffer, element *Element) error {
+	if element.Flags > 7 {
+		return fmt.Errorf("%w: 0x%x", ErrUnknownElementFlags, element.Flags)
+	}
+
+	flags := element.Flags
+	writeUint(buf, flags)
+
+	if flags&0x01 == 0 {
+		if flags&0x02 != 0 {
+			writeUint(buf, uint32(element.Table))
+		}
+		buf.Write(element.Offset)
+	}
+
+	usesExpressions := flags&0x04 != 0
+
+	if flags&0x03 != 0 {
+		if usesExpressions {
+			buf.WriteByte(element.Type.SpecByte)
+		} else {
+			// the only element kind is funcref
+			buf.WriteByte(0x00)
+		}
+	}
+
+	if usesExpressions {
+		writeUint(buf, uint32(len(element.Init)))
+		for _, expr := range element.Init {
+			buf.Write(expr)
+		}
+
+		return nil
+	}
+
+	writeUint(buf, uint32(len(element.FuncIndices)))
+	for _, funcIdx := range element.FuncIndices {
+		writeUint(buf, uint32(funcIdx))
+	}
+
+	return nil
+}
+
+func encodeData(buf *bytes.Buffer, segment *Data) error {
+	writeUint(buf, segment.Flags)
+
+	switch segment.Flags {
+	case 0x00:
+		buf.Write(segment.Offset)
+	case 0x01:
+	case 0x02:
+		writeUint(buf, uint32(segment.Memory))
+		buf.Write(segment.Offset)
+	default:
+		return fmt.Errorf("%w: 0x%x", ErrUnknownDataFlags, segment.Flags)
+	}
+
+	writeUint(buf, uint32(len(segment.Init)))
+	buf.Write(segment.Init)
+	return nil
+}
+
+func encodeCustom(buf *bytes.Buffer, custom *Custom) {
+	writeName(buf, custom.Name)
+	buf.Write(custom.Data)
+}
+
+func encodeValueTypes(buf *bytes.Buffer, types []Type) {
+	writeUint(buf, uint32(len(types)))
+	for _, t := range types {
+		buf.WriteByte(t.SpecByte)
+	}
+}
+
+func encodeLimits(buf *bytes.Buffer, limits Limits) {
+	if !limits.HasMax {
+		buf.WriteByte(0x00)
+		writeUint(buf, limits.Min)
+		return
+	}
+
+	buf.WriteByte(0x01)
+	writeUint(buf, limits.Min)
+	writeUint(buf, limits.Max)
+}
+
+func encodeTableType(buf *bytes.Buffer, table *TableType) {
+	buf.WriteByte(table.ElemType.SpecByte)
+	encodeLimits(buf, table.Limits)
+}
+
+func encodeGlobalType(buf *bytes.Buffer, global *GlobalType) {
+	buf.WriteByte(global.ValType.SpecByte)
+	if global.Mutable {
+		buf.WriteByte(0x01)
+	} else {
+		buf.WriteByte(0x00)
+	}
+}
+
+func writeUint(buf *bytes.Buffer, v uint32) {
+	buf.Write(leb128.EncodeUint(uint(v)))
+}
+
+func writeName(buf *bytes.Buffer, name string) {
+	writeUint(buf, uint32(len(name)))
+	buf.WriteString(name)
+}
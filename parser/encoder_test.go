@@ -0,0 +1,115 @@
+package parser_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/EclesioMeloJunior/wasvm/parser"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode_RoundTripResources(t *testing.T) {
+	resources, err := filepath.Glob("../resources/*.wasm")
+	require.NoError(t, err)
+	require.NotEmpty(t, resources)
+
+	for _, resource := range resources {
+		resource := resource
+		t.Run(filepath.Base(resource), func(t *testing.T) {
+			wasmBytes, err := os.ReadFile(resource)
+			require.NoError(t, err)
+
+			bp, err := parser.Decode(wasmBytes)
+			require.NoError(t, err)
+
+			encoded, err := parser.Encode(bp)
+			require.NoError(t, err)
+			require.Equal(t, wasmBytes, encoded)
+		})
+	}
+}
+
+func TestEncode_KeepsCustomSectionsPlacement(t *testing.T) {
+	module := singleFunctionModule(nil, []byte{0x7F}, []byte{0x41, 0x2A, 0x0B})
+
+	// a custom section between the type and the function sections
+	custom := section(parser.CustomSection, 0x04, 'm', 'e', 't', 'a', 0x01, 0x02)
+	typeSectionEnd := 8 + 2 + int(module[9])
+
+	wasmBytes := append([]byte{}, module[:typeSectionEnd]...)
+	wasmBytes = append(wasmBytes, custom...)
+	wasmBytes = append(wasmBytes, module[typeSectionEnd:]...)
+	wasmBytes = append(wasmBytes, section(parser.CustomSection, 0x03, 'e', 'n', 'd')...)
+
+	bp, err := parser.Decode(wasmBytes)
+	require.NoError(t, err)
+
+	encoded, err := parser.Encode(bp)
+	require.NoError(t, err)
+	require.Equal(t, wasmBytes, encoded)
+}
+
+func TestEncode_ModuleNotDecoded(t *testing.T) {
+	count := uint32(1)
+
+	bp := parser.NewBinaryReaderParser(nil)
+	bp.Module.Types = []*parser.FunctionSignatureParser{
+		parser.NewFunctionSignature([]parser.Type{parser.I32}, []parser.Type{parser.I32}),
+	}
+	bp.Module.Funcs = []*parser.Function{{
+		TypeIndex: 0,
+		Code: &parser.CodeParser{
+			Locals: []parser.Type{parser.I64, parser.I64, parser.I32},
+			Body:   []byte{0x20, 0x00, 0x0B},
+		},
+	}}
+	bp.Module.Memories = []*parser.MemoryType{{Limits: parser.Limits{Min: 1}}}
+	bp.Module.Exports = []*parser.Export{{Name: "id", Type: parser.ExportedFunc, Index: 0}}
+	bp.Module.DataCount = &count
+	bp.Module.Data = []*parser.Data{{Flags: 0x01, Mode: parser.DataPassive, Init: []byte("wasvm")}}
+
+	encoded, err := parser.Encode(bp)
+	require.NoError(t, err)
+
+	expected := []byte{0x00, 0x61, 0x73, 0x6D, 0x01, 0x00, 0x00, 0x00}
+	expected = append(expected, section(parser.TypeSection, 0x01, 0x60, 0x01, 0x7F, 0x01, 0x7F)...)
+	expected = append(expected, section(parser.FunctionSection, 0x01, 0x00)...)
+	expected = append(expected, section(parser.MemorySection, 0x01, 0x00, 0x01)...)
+	expected = append(expected, section(parser.ExportSection, 0x01, 0x02, 'i', 'd', 0x00, 0x00)...)
+	expected = append(expected, section(parser.DataCountSection, 0x01)...)
+	expected = append(expected, section(parser.CodeSection, 0x01, 0x08, 0x02, 0x02, 0x7E, 0x01, 0x7F, 0x20, 0x00, 0x0B)...)
+	expected = append(expected, section(parser.DataSection, 0x01, 0x01, 0x05, 'w', 'a', 's', 'v', 'm')...)
+	require.Equal(t, expected, encoded)
+
+	decoded, err := parser.Decode(encoded)
+	require.NoError(t, err)
+	require.NoError(t, parser.Validate(decoded))
+}
+
+func TestEncode_ChangesMadeAfterDecoding(t *testing.T) {
+	module := singleFunctionModule(nil, nil, []byte{0x41, 0x2A, 0x1A, 0x0B})
+	module = append(module, section(parser.CustomSection, 0x03, 'e', 'n', 'd')...)
+
+	bp, err := parser.Decode(module)
+	require.NoError(t, err)
+
+	// i32.const 42 becomes i32.const 7 and the function is exported as start
+	bp.Module.Funcs[0].Code.Instructions[0].I32 = 7
+	start := 0
+	bp.Module.Start = &start
+	bp.Module.Exports = []*parser.Export{{Name: "f", Type: parser.ExportedFunc, Index: 0}}
+
+	encoded, err := parser.Encode(bp)
+	require.NoError(t, err)
+
+	expected := []byte{0x00, 0x61, 0x73, 0x6D, 0x01, 0x00, 0x00, 0x00}
+	expected = append(expected, section(parser.TypeSection, 0x01, 0x60, 0x00, 0x00)...)
+	expected = append(expected, section(parser.FunctionSection, 0x01, 0x00)...)
+	expected = append(expected, section(parser.ExportSection, 0x01, 0x01, 'f', 0x00, 0x00)...)
+	expected = append(expected, section(parser.StartSection, 0x00)...)
+	expected = append(expected, section(parser.CodeSection, 0x01, 0x05, 0x00, 0x41, 0x07, 0x1A, 0x0B)...)
+	expected = append(expected, section(parser.CustomSection, 0x03, 'e', 'n', 'd')...)
+	require.Equal(t, expected, encoded)
+}
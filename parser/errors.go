@@ -0,0 +1,144 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/EclesioMeloJunior/wasvm/leb128"
+)
+
+// the kinds of DecodeError, they can be checked with errors.Is
+var (
+	ErrUnexpectedEnd     = errors.New("unexpected end")
+	ErrMalformedInteger  = errors.New("malformed integer")
+	ErrLengthOutOfBounds = errors.New("length out of bounds")
+	ErrTooManyLocals     = errors.New("too many locals")
+	ErrMalformedModule   = errors.New("malformed module")
+	ErrMagicNumber       = errors.New("magic header not detected")
+	ErrUnknownVersion    = errors.New("unknown binary version")
+	ErrSectionOutOfOrder = errors.New("section out of order")
+	ErrDuplicateSection  = errors.New("duplicate section")
+	ErrSectionSize       = errors.New("section size mismatch")
+)
+
+// NoSection is the DecodeError section of the errors found outside
+// of a section, such as while reading the magic number and version
+const NoSection = -1
+
+// DecodeError tells where and why a module could not be decoded
+type DecodeError struct {
+	// Section is the id of the section being decoded or NoSection
+	Section int
+	// Offset is the absolute position in the module where decoding failed
+	Offset int64
+	// FuncIndex is the function index space position of the body
+	// being decoded, -1 when the error is not inside a function body
+	FuncIndex int
+	// Kind is one of the decode error kinds
+	Kind error
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	location := fmt.Sprintf("offset %d", e.Offset)
+	if e.Section != NoSection {
+		location += fmt.Sprintf(", section 0x%x", e.Section)
+	}
+
+	if e.FuncIndex >= 0 {
+		location += fmt.Sprintf(", function %d", e.FuncIndex)
+	}
+
+	return fmt.Sprintf("%s at %s: %s", e.Kind, location, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DecodeError) Is(target error) bool {
+	return e.Kind == target
+}
+
+func newDecodeError(section int, offset int64, err error) *DecodeError {
+	return &DecodeError{
+		Section:   section,
+		Offset:    offset,
+		FuncIndex: -1,
+		Kind:      decodeErrorKind(err),
+		Err:       err,
+	}
+}
+
+func decodeErrorKind(err error) error {
+	switch {
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, leb128.ErrCannotReadNextByte), errors.Is(err, ErrBytesLen):
+		return ErrUnexpectedEnd
+	case errors.Is(err, leb128.ErrOverflow32), errors.Is(err, leb128.ErrOverflow33),
+		errors.Is(err, leb128.ErrOverflow64):
+		return ErrMalformedInteger
+	case errors.Is(err, ErrLengthOutOfBounds):
+		return ErrLengthOutOfBounds
+	case errors.Is(err, ErrTooManyLocals):
+		return ErrTooManyLocals
+	case errors.Is(err, ErrMagicNumber):
+		return ErrMagicNumber
+	case errors.Is(err, ErrUnknownVersion):
+		return ErrUnknownVersion
+	case errors.Is(err, ErrSectionOutOfOrder):
+		return ErrSectionOutOfOrder
+	case errors.Is(err, ErrDuplicateSection):
+		return ErrDuplicateSection
+	case errors.Is(err, ErrSectionSize):
+		return ErrSectionSize
+	}
+
+	return ErrMalformedModule
+}
+
+// functionBodyError is returned by the code section parser so the
+// decode error can tell which function body is malformed, offset
+// is relative to the start of the section contents
+type functionBodyError struct {
+	index  int
+	offset int
+	err    error
+}
+
+func (e *functionBodyError) Error() string {
+	return fmt.Sprintf("function body %d: %s", e.index, e.err)
+}
+
+func (e *functionBodyError) Unwrap() error {
+	return e.err
+}
+
+// remainingReader is implemented by the readers that know how many bytes are
+// left, which is always the case for the section contents as they are buffered
+type remainingReader interface {
+	Len() int
+}
+
+// decodeLength reads the length of a vector, it cannot be greater than the
+// remaining bytes as every element takes at least one byte, this avoids
+// allocating huge slices because of a malformed length
+func decodeLength(b BinaryReader) (int, error) {
+	_, length, err := leb128.DecodeUint32(b)
+	if err != nil {
+		return 0, err
+	}
+
+	if r, ok := b.(remainingReader); ok && int64(length) > int64(r.Len()) {
+		return 0, fmt.Errorf("%w: %d, only %d bytes left", ErrLengthOutOfBounds, length, r.Len())
+	}
+
+	return int(length), nil
+}
+
+// decodeIndex reads an u32 encoded index or flag
+func decodeIndex(b io.ByteReader) (uint32, error) {
+	_, idx, err := leb128.DecodeUint32(b)
+	return idx, err
+}
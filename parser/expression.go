@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/EclesioMeloJunior/wasvm/leb128"
+	"github.com/EclesioMeloJunior/wasvm/opcodes"
+)
+
+var ErrInvalidConstantExpression = errors.New("invalid constant expression")
+
+// recordingReader keeps every byte read from the underlying reader
+type recordingReader struct {
+	reader   BinaryReader
+	recorded []byte
+}
+
+func (r *recordingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.recorded = append(r.recorded, p[:n]...)
+	return n, err
+}
+
+func (r *recordingReader) ReadByte() (byte, error) {
+	b, err := r.reader.ReadByte()
+	if err == nil {
+		r.recorded = append(r.recorded, b)
+	}
+
+	return b, err
+}
+
+// parseConstantExpression reads a constant expression, used by globals initializers
+// and segments offsets, returning its raw bytes including the `end` opcode
+func parseConstantExpression(b BinaryReader) ([]byte, error) {
+	reader := &recordingReader{reader: b}
+
+	for {
+		op, err := reader.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read constant expression opcode: %w", err)
+		}
+
+		switch opcodes.OpCode(op) {
+		case opcodes.End:
+			return reader.recorded, nil
+		case opcodes.I32Const:
+			_, _, err = leb128.DecodeInt[int32](reader)
+		case opcodes.I64Const:
+			_, _, err = leb128.DecodeInt[int64](reader)
+		case opcodes.F32Const:
+			_, err = io.ReadFull(reader, make([]byte, 4))
+		case opcodes.F64Const:
+			_, err = io.ReadFull(reader, make([]byte, 8))
+		case opcodes.GlobalGet, opcodes.RefFunc:
+			_, _, err = leb128.DecodeUint(reader)
+		case opcodes.RefNull:
+			_, err = reader.ReadByte()
+		default:
+			return nil, fmt.Errorf("%w: unexpected instruction %s",
+				ErrInvalidConstantExpression, opcodes.OpCode(op))
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %s immediate: %w", opcodes.OpCode(op), err)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/EclesioMeloJunior/wasvm/leb128"
+	"github.com/EclesioMeloJunior/wasvm/opcodes"
+)
+
+var ErrUnknownInstruction = errors.New("unknown instruction")
+
+// MemArg is the immediate of the load and store instructions,
+// Align is the log2 of the access alignment in bytes
+type MemArg struct {
+	Align  uint32
+	Offset uint32
+}
+
+// Instruction is a single decoded instruction of a function
+// body or constant expression together with its immediates
+type Instruction struct {
+	Opcode opcodes.OpCode
+	// Offset is where the instruction starts within the decoded bytes
+	Offset int
+
+	// Index holds the local, global, function, type or data
+	// index of the instructions that refer to one of them
+	Index uint32
+	// Table is the table index of call_indirect
+	Table uint32
+	// Labels are the label indices of br_table, its default label
+	// is kept at Index as it is the label of br and br_if
+	Labels []uint32
+
+	// Results is the block type of structured instructions when it is empty or
+	// a single value type, TypeIndexed tells the block type is instead the
+	// function type at Index, use BlockSignature to get the params and results
+	Results     []Type
+	TypeIndexed bool
+	// Types holds the operand type of typed select and ref.null
+	Types []Type
+
+	MemArg MemArg
+
+	I32 int32
+	I64 int64
+	F32 uint32
+	F64 uint64
+}
+
+// instructionError tells the offset, within the decoded
+// bytes, of the instruction that could not be decoded
+type instructionError struct {
+	offset int
+	err    error
+}
+
+func (e *instructionError) Error() string {
+	return fmt.Sprintf("at offset %d: %s", e.offset, e.err)
+}
+
+func (e *instructionError) Unwrap() error {
+	return e.err
+}
+
+// DecodeInstructions decodes every instruction of a function
+// body or constant expression, including the final end
+func DecodeInstructions(body []byte) ([]Instruction, error) {
+	reader := bytes.NewReader(body)
+	instructions := make([]Instruction, 0)
+
+	for reader.Len() > 0 {
+		offset := len(body) - reader.Len()
+		inst, err := decodeInstruction(reader, offset)
+		if err != nil {
+			return nil, &instructionError{offset: offset, err: err}
+		}
+
+		instructions = append(instructions, *inst)
+	}
+
+	return instructions, nil
+}
+
+// decodeInstruction reads the instruction that starts at the reader
+// current position, offset is used to tell where it was found
+func decodeInstruction(reader *bytes.Reader, offset int) (*Instruction, error) {
+	op, err := reader.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read opcode: %w", err)
+	}
+
+	inst := &Instruction{Opcode: opcodes.OpCode(op), Offset: offset}
+	if inst.Opcode == opcodes.MiscPrefix {
+		subOpCode, err := decodeIndex(reader)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read sub opcode: %w", err)
+		}
+
+		// the known sub opcodes fit in a byte, combining a greater
+		// one with the prefix would alias it with a known instruction
+		if subOpCode > 0xFF {
+			return nil, fmt.Errorf("%w: 0x%x 0x%x", ErrUnknownInstruction, byte(opcodes.MiscPrefix), subOpCode)
+		}
+
+		inst.Opcode = opcodes.MiscPrefix<<8 | opcodes.OpCode(subOpCode)
+	}
+
+	switch inst.Opcode {
+	case opcodes.Unreachable, opcodes.Nop, opcodes.Else, opcodes.End, opcodes.Return,
+		opcodes.Drop, opcodes.Select:
+	case opcodes.Block, opcodes.Loop, opcodes.If:
+		err = decodeBlockType(reader, inst)
+	case opcodes.LocalGet, opcodes.LocalSet, opcodes.LocalTee, opcodes.GlobalGet, opcodes.GlobalSet,
+		opcodes.Call, opcodes.RefFunc, opcodes.DataDrop, opcodes.Br, opcodes.BrIf:
+		inst.Index, err = decodeIndex(reader)
+	case opcodes.BrTable:
+		var labelsLen uint32
+		labelsLen, err = decodeIndex(reader)
+		for i := uint32(0); err == nil && i < labelsLen; i++ {
+			var label uint32
+			label, err = decodeIndex(reader)
+			inst.Labels = append(inst.Labels, label)
+		}
+
+		if err == nil {
+			inst.Index, err = decodeIndex(reader)
+		}
+	case opcodes.CallIndirect:
+		inst.Index, err = decodeIndex(reader)
+		if err == nil {
+			inst.Table, err = decodeIndex(reader)
+		}
+	case opcodes.SelectTyped:
+		var typesLen uint32
+		typesLen, err = decodeIndex(reader)
+		for i := uint32(0); err == nil && i < typesLen; i++ {
+			var valueType Type
+			valueType, err = parseValueType(reader)
+			inst.Types = append(inst.Types, valueType)
+		}
+	case opcodes.RefNull:
+		var refType Type
+		refType, err = parseRefType(reader)
+		inst.Types = []Type{refType}
+	case opcodes.I32Load, opcodes.I64Load, opcodes.F32Load, opcodes.F64Load,
+		opcodes.I32Load8S, opcodes.I32Load8U, opcodes.I32Load16S, opcodes.I32Load16U,
+		opcodes.I64Load8S, opcodes.I64Load8U, opcodes.I64Load16S, opcodes.I64Load16U,
+		opcodes.I64Load32S, opcodes.I64Load32U,
+		opcodes.I32Store, opcodes.I64Store, opcodes.F32Store, opcodes.F64Store,
+		opcodes.I32Store8, opcodes.I32Store16,
+		opcodes.I64Store8, opcodes.I64Store16, opcodes.I64Store32:
+		inst.MemArg.Align, err = decodeIndex(reader)
+		if err == nil {
+			inst.MemArg.Offset, err = decodeIndex(reader)
+		}
+	case opcodes.MemorySize, opcodes.MemoryGrow:
+		err = decodeReservedByte(reader)
+	case opcodes.MemoryInit:
+		inst.Index, err = decodeIndex(reader)
+		if err == nil {
+			err = decodeReservedByte(reader)
+		}
+	case opcodes.I32Const:
+		_, inst.I32, err = leb128.DecodeInt[int32](reader)
+	case opcodes.I64Const:
+		_, inst.I64, err = leb128.DecodeInt[int64](reader)
+	case opcodes.F32Const:
+		raw := make([]byte, 4)
+		_, err = io.ReadFull(reader, raw)
+		inst.F32 = binary.LittleEndian.Uint32(raw)
+	case opcodes.F64Const:
+		raw := make([]byte, 8)
+		_, err = io.ReadFull(reader, raw)
+		inst.F64 = binary.LittleEndian.Uint64(raw)
+	default:
+		if _, ok := numericInstructions[inst.Opcode]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownInstruction, inst.Opcode)
+		}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s immediate: %w", inst.Opcode, err)
+	}
+
+	return inst, nil
+}
+
+// decodeBlockType reads the block type of a structured instruction, it
+// is 0x40 for no results, a value type for a single result or a s33
+// type index that refers to a function type with params and results
+func decodeBlockType(reader *bytes.Reader, inst *Instruction) error {
+	blockType, err := reader.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	if blockType == opcodes.EmptyBlockType {
+		return nil
+	}
+
+	if err := reader.UnreadByte(); err != nil {
+		return err
+	}
+
+	// the value types are encoded as negative s33 numbers
+	if blockType&0xC0 == 0x40 {
+		valueType, err := parseValueType(reader)
+		if err != nil {
+			return err
+		}
+
+		inst.Results = []Type{valueType}
+		return nil
+	}
+
+	_, typeIdx, err := leb128.DecodeS33(reader)
+	if err != nil {
+		return err
+	}
+
+	if typeIdx < 0 {
+		return fmt.Errorf("%w: block type %d", ErrUnknownType, typeIdx)
+	}
+
+	inst.Index, inst.TypeIndexed = uint32(typeIdx), true
+	return nil
+}
+
+// decodeReservedByte reads the memory index placeholder that
+// must be zero while a module can have only one memory
+func decodeReservedByte(reader *bytes.Reader) error {
+	reserved, err := reader.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	if reserved != 0x00 {
+		return fmt.Errorf("expected reserved byte 0x00, got 0x%x", reserved)
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package parser
+
+import "fmt"
+
+// Module is the decoded module, each section is kept as a typed field so it
+// can be inspected without knowing how the sections were decoded. The
+// imported functions take the first positions of Funcs, as they do in the
+// function index space, while Tables, Memories and Globals only hold the
+// ones defined in the module, the imported ones are kept in Imports
+type Module struct {
+	Magic   uint32
+	Version uint32
+
+	Types   []*FunctionSignatureParser
+	Imports []*Import
+
+	// Funcs is the function index space, the imported functions
+	// only have a signature while the defined ones also have code
+	Funcs    []*Function
+	Tables   []*TableType
+	Memories []*MemoryType
+	Globals  []*Global
+	Exports  []*Export
+
+	// Start is nil when the module does not define a start function
+	Start    *int
+	Elements []*Element
+
+	// DataCount is nil when the module does not have a data count section
+	DataCount *uint32
+	Data      []*Data
+
+	// Customs keeps the custom sections in the order they were found,
+	// Names is the decoded `name` section, if the module has a valid one
+	Customs []*Custom
+	Names   *NameSection
+}
+
+// Function returns the function at the position idx of the function index space
+func (m *Module) Function(idx int) (*Function, error) {
+	if idx < 0 || idx >= len(m.Funcs) {
+		return nil, fmt.Errorf("%w: %d", ErrFunctionIndexOutOfBounds, idx)
+	}
+
+	return m.Funcs[idx], nil
+}
+
+// DefinedFuncs returns the functions defined in the module, the ones
+// with code, which come right after the imported functions
+func (m *Module) DefinedFuncs() []*Function {
+	for idx, function := range m.Funcs {
+		if function.Import == nil {
+			return m.Funcs[idx:]
+		}
+	}
+
+	return nil
+}
+
+// ImportedFunctions returns only the imported functions, in the order they appear
+func (m *Module) ImportedFunctions() []*Import {
+	return m.importsOf(ImportedFunc)
+}
+
+// ImportedGlobals returns only the imported globals, in the order they appear
+func (m *Module) ImportedGlobals() []*Import {
+	return m.importsOf(ImportedGlobal)
+}
+
+func (m *Module) importsOf(importedType ImportedType) []*Import {
+	imports := make([]*Import, 0, len(m.Imports))
+	for _, imported := range m.Imports {
+		if imported.Type == importedType {
+			imports = append(imports, imported)
+		}
+	}
+
+	return imports
+}
+
+// Signature returns the function type declared at typeIdx
+func (m *Module) Signature(typeIdx int) (*FunctionSignatureParser, error) {
+	if typeIdx < 0 || typeIdx >= len(m.Types) {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownType, typeIdx)
+	}
+
+	return m.Types[typeIdx], nil
+}
+
+// BlockSignature returns the params and results of the
+// block type of a block, loop or if instruction
+func (m *Module) BlockSignature(inst *Instruction) (params, results []Type, err error) {
+	if !inst.TypeIndexed {
+		return nil, inst.Results, nil
+	}
+
+	signature, err := m.Signature(int(inst.Index))
+	if err != nil {
+		return nil, nil, fmt.Errorf("block type: %w", err)
+	}
+
+	return signature.ParamsTypes, signature.ResultsTypes, nil
+}
+
+// Export returns the export with the given name
+func (m *Module) Export(name string) (*Export, bool) {
+	for _, export := range m.Exports {
+		if export.Name == name {
+			return export, true
+		}
+	}
+
+	return nil, false
+}
+
+// Custom returns the first custom section with the given name
+func (m *Module) Custom(name string) (*Custom, bool) {
+	for _, custom := range m.Customs {
+		if custom.Name == name {
+			return custom, true
+		}
+	}
+
+	return nil, false
+}
+
+// addSection moves the contents of a decoded section into the module, as
+// the function section comes before the code section the bodies are given
+// to their functions right away, the amount of bodies is kept so a body
+// without function is reported once the whole module is decoded
+func (bp *BinaryParser) addSection(section Parser) {
+	m := bp.Module
+
+	switch p := section.(type) {
+	case *CustomSectionParser:
+		m.Customs = append(m.Customs, p.Custom)
+		if m.Names == nil {
+			m.Names = p.Names
+		}
+	case *TypeSectionParser:
+		m.Types = p.Types
+	case *ImportsSectionParser:
+		m.Imports = p.Imports
+		for _, imported := range m.ImportedFunctions() {
+			m.Funcs = append(m.Funcs, &Function{
+				TypeIndex: imported.TypeIndex,
+				Import:    imported,
+			})
+		}
+	case *FunctionSectionParser:
+		m.Funcs = append(m.Funcs, p.Funcs...)
+	case *TableSectionParser:
+		m.Tables = p.Tables
+	case *MemorySectionParser:
+		m.Memories = p.Memories
+	case *GlobalSectionParser:
+		m.Globals = p.Globals
+	case *ExportSectionParser:
+		m.Exports = p.Exports
+	case *StartSectionParser:
+		m.Start = p.FuncIndex
+	case *ElementSectionParser:
+		m.Elements = p.Elements
+	case *DataCountSectionParser:
+		m.DataCount = p.Count
+	case *CodeSectionParser:
+		bp.bodies = len(p.FunctionsCode)
+		for idx, function := range m.DefinedFuncs() {
+			if idx < len(p.FunctionsCode) {
+				function.Code = p.FunctionsCode[idx]
+			}
+		}
+	case *DataSectionParser:
+		m.Data = p.Data
+	}
+}
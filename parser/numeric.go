@@ -0,0 +1,87 @@
+package parser
+
+import "github.com/EclesioMeloJunior/wasvm/opcodes"
+
+// numericSignature holds the operands and the result of a numeric instruction
+type numericSignature struct {
+	operands []Type
+	result   Type
+}
+
+// numericInstructions are the instructions without immediates that only
+// pop their operands and push a single result, the tests and the
+// comparisons push an i32 that is 1 when true and 0 otherwise
+var numericInstructions = make(map[opcodes.OpCode]numericSignature)
+
+func init() {
+	addNumeric([]Type{I32}, I32, opcodes.I32Eqz, opcodes.I32Clz, opcodes.I32Ctz, opcodes.I32Popcnt)
+
+	addNumeric([]Type{I32, I32}, I32,
+		opcodes.I32Eq, opcodes.I32Ne,
+		opcodes.I32LtS, opcodes.I32LtU, opcodes.I32GtS, opcodes.I32GtU,
+		opcodes.I32LeS, opcodes.I32LeU, opcodes.I32GeS, opcodes.I32GeU,
+		opcodes.I32Add, opcodes.I32Sub, opcodes.I32Mul,
+		opcodes.I32DivS, opcodes.I32DivU, opcodes.I32RemS, opcodes.I32RemU,
+		opcodes.I32And, opcodes.I32Or, opcodes.I32Xor,
+		opcodes.I32Shl, opcodes.I32ShrS, opcodes.I32ShrU, opcodes.I32Rotl, opcodes.I32Rotr)
+
+	addNumeric([]Type{I64}, I32, opcodes.I64Eqz)
+	addNumeric([]Type{I64}, I64, opcodes.I64Clz, opcodes.I64Ctz, opcodes.I64Popcnt)
+
+	addNumeric([]Type{I64, I64}, I32,
+		opcodes.I64Eq, opcodes.I64Ne,
+		opcodes.I64LtS, opcodes.I64LtU, opcodes.I64GtS, opcodes.I64GtU,
+		opcodes.I64LeS, opcodes.I64LeU, opcodes.I64GeS, opcodes.I64GeU)
+
+	addNumeric([]Type{I64, I64}, I64,
+		opcodes.I64Add, opcodes.I64Sub, opcodes.I64Mul,
+		opcodes.I64DivS, opcodes.I64DivU, opcodes.I64RemS, opcodes.I64RemU,
+		opcodes.I64And, opcodes.I64Or, opcodes.I64Xor,
+		opcodes.I64Shl, opcodes.I64ShrS, opcodes.I64ShrU, opcodes.I64Rotl, opcodes.I64Rotr)
+
+	addNumeric([]Type{F32, F32}, I32,
+		opcodes.F32Eq, opcodes.F32Ne, opcodes.F32Lt, opcodes.F32Gt, opcodes.F32Le, opcodes.F32Ge)
+	addNumeric([]Type{F32}, F32,
+		opcodes.F32Abs, opcodes.F32Neg, opcodes.F32Ceil, opcodes.F32Floor,
+		opcodes.F32Trunc, opcodes.F32Nearest, opcodes.F32Sqrt)
+	addNumeric([]Type{F32, F32}, F32,
+		opcodes.F32Add, opcodes.F32Sub, opcodes.F32Mul, opcodes.F32Div,
+		opcodes.F32Min, opcodes.F32Max, opcodes.F32Copysign)
+
+	addNumeric([]Type{F64, F64}, I32,
+		opcodes.F64Eq, opcodes.F64Ne, opcodes.F64Lt, opcodes.F64Gt, opcodes.F64Le, opcodes.F64Ge)
+	addNumeric([]Type{F64}, F64,
+		opcodes.F64Abs, opcodes.F64Neg, opcodes.F64Ceil, opcodes.F64Floor,
+		opcodes.F64Trunc, opcodes.F64Nearest, opcodes.F64Sqrt)
+	addNumeric([]Type{F64, F64}, F64,
+		opcodes.F64Add, opcodes.F64Sub, opcodes.F64Mul, opcodes.F64Div,
+		opcodes.F64Min, opcodes.F64Max, opcodes.F64Copysign)
+
+	addNumeric([]Type{I32}, I32, opcodes.I32Extend8S, opcodes.I32Extend16S)
+	addNumeric([]Type{I64}, I32, opcodes.I32WrapI64)
+	addNumeric([]Type{F32}, I32, opcodes.I32TruncF32S, opcodes.I32TruncF32U,
+		opcodes.I32TruncSatF32S, opcodes.I32TruncSatF32U, opcodes.I32ReinterpretF32)
+	addNumeric([]Type{F64}, I32, opcodes.I32TruncF64S, opcodes.I32TruncF64U,
+		opcodes.I32TruncSatF64S, opcodes.I32TruncSatF64U)
+
+	addNumeric([]Type{I32}, I64, opcodes.I64ExtendI32S, opcodes.I64ExtendI32U)
+	addNumeric([]Type{I64}, I64, opcodes.I64Extend8S, opcodes.I64Extend16S, opcodes.I64Extend32S)
+	addNumeric([]Type{F32}, I64, opcodes.I64TruncF32S, opcodes.I64TruncF32U,
+		opcodes.I64TruncSatF32S, opcodes.I64TruncSatF32U)
+	addNumeric([]Type{F64}, I64, opcodes.I64TruncF64S, opcodes.I64TruncF64U,
+		opcodes.I64TruncSatF64S, opcodes.I64TruncSatF64U, opcodes.I64ReinterpretF64)
+
+	addNumeric([]Type{I32}, F32, opcodes.F32ConvertI32S, opcodes.F32ConvertI32U, opcodes.F32ReinterpretI32)
+	addNumeric([]Type{I64}, F32, opcodes.F32ConvertI64S, opcodes.F32ConvertI64U)
+	addNumeric([]Type{F64}, F32, opcodes.F32DemoteF64)
+
+	addNumeric([]Type{I32}, F64, opcodes.F64ConvertI32S, opcodes.F64ConvertI32U)
+	addNumeric([]Type{I64}, F64, opcodes.F64ConvertI64S, opcodes.F64ConvertI64U, opcodes.F64ReinterpretI64)
+	addNumeric([]Type{F32}, F64, opcodes.F64PromoteF32)
+}
+
+func addNumeric(operands []Type, result Type, ops ...opcodes.OpCode) {
+	for _, op := range ops {
+		numericInstructions[op] = numericSignature{operands: operands, result: result}
+	}
+}
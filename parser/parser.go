@@ -1,8 +1,11 @@
 package parser
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 )
 
 var (
@@ -10,19 +13,37 @@ var (
 	ErrFunctionWithouCode      = errors.New("function does not have a respective code")
 )
 
+// BinaryFormat reads and decodes the wasm module located at filepath
 func BinaryFormat(filepath string) (*BinaryParser, error) {
-	bp, err := NewBinaryParser(filepath)
+	fbytes, err := os.ReadFile(filepath)
 	if err != nil {
-		return nil, fmt.Errorf("cannot instantiate a binary parser: %w", err)
+		return nil, fmt.Errorf("cannot read file: %w", err)
 	}
 
+	return Decode(fbytes)
+}
+
+// Decode decodes a wasm module that is already in memory
+func Decode(wasm []byte) (*BinaryParser, error) {
+	return decode(NewBinaryReaderParser(bytes.NewReader(wasm)))
+}
+
+// DecodeReader decodes a wasm module from any io.Reader, the sections
+// are decoded incrementally as their bytes arrive from the reader
+func DecodeReader(r io.Reader) (*BinaryParser, error) {
+	return decode(NewBinaryReaderParser(NewBinaryReader(r)))
+}
+
+func decode(bp *BinaryParser) (*BinaryParser, error) {
 	// starting parsing the `wasm header` values
 	if err := bp.ParseMagicNumber(); err != nil {
-		return nil, fmt.Errorf("cannot parse magic number: %w", err)
+		return nil, fmt.Errorf("cannot parse magic number: %w",
+			newDecodeError(NoSection, bp.reader.offset, err))
 	}
 
 	if err := bp.ParseVersion(); err != nil {
-		return nil, fmt.Errorf("cannot parse version: %w", err)
+		return nil, fmt.Errorf("cannot parse version: %w",
+			newDecodeError(NoSection, bp.reader.offset, err))
 	}
 
 	if err := bp.ParseSection(); err != nil {
@@ -30,7 +51,8 @@ func BinaryFormat(filepath string) (*BinaryParser, error) {
 	}
 
 	if err := bondFunctionSignatureAndCode(bp); err != nil {
-		return nil, fmt.Errorf("cannot bond function parts: %w", err)
+		return nil, fmt.Errorf("cannot bond function parts: %w",
+			newDecodeError(int(FunctionSection), bp.reader.offset, err))
 	}
 
 	return bp, nil
@@ -40,44 +62,55 @@ var ErrNoTypesToBound = errors.New("no types to bound")
 var ErrNoCodeToBound = errors.New("no code to bound")
 
 func bondFunctionSignatureAndCode(bp *BinaryParser) error {
-	functionSection := bp.Parsers[FunctionSection].(*FunctionSectionParser)
-	if len(functionSection.Funcs) < 1 {
+	module := bp.Module
+	defined := module.DefinedFuncs()
+
+	if bp.bodies > len(defined) {
+		return fmt.Errorf("%w: %d functions, %d bodies",
+			ErrFunctionCodeMismatch, len(defined), bp.bodies)
+	}
+
+	if len(module.Funcs) < 1 {
 		return nil
 	}
 
-	typeSection := bp.Parsers[TypeSection].(*TypeSectionParser)
-	if len(typeSection.Types) < 1 {
+	if len(module.Types) < 1 {
 		return ErrNoTypesToBound
 	}
 
-	codeSection := bp.Parsers[CodeSection].(*CodeSectionParser)
-	if len(typeSection.Types) < 1 {
+	if bp.bodies < len(defined) {
 		return ErrNoCodeToBound
 	}
 
-	for idx, function := range functionSection.Funcs {
-		if len(typeSection.Types) < function.TypeIndex {
-			return fmt.Errorf("%w: %d", ErrFunctionWithouSignature, function.TypeIndex)
+	// imported functions take the first indexes of the function
+	// index space, they only have a signature as the code is
+	// provided by the host
+	for _, function := range module.Funcs[:len(module.Funcs)-len(defined)] {
+		signature, err := functionSignature(module, function.TypeIndex)
+		if err != nil {
+			return fmt.Errorf("imported function %s.%s: %w", function.Import.Module, function.Import.Name, err)
 		}
 
-		ttype := typeSection.Types[function.TypeIndex]
-		signature, ok := ttype.(*FunctionSignatureParser)
-		if !ok {
-			return fmt.Errorf("%w: expected *FunctionSignatureParser, got: %T",
-				ErrFunctionWithouSignature, ttype)
-		}
+		function.Signature = signature
+	}
 
-		// each index correspond to a code in the code section, if there is no
-		// code for the current index then we must return an error
-		if len(codeSection.FunctionsCode) < idx {
-			return fmt.Errorf("%w: %d", ErrFunctionWithouCode, function.TypeIndex)
+	for _, function := range defined {
+		signature, err := functionSignature(module, function.TypeIndex)
+		if err != nil {
+			return err
 		}
 
-		code := codeSection.FunctionsCode[idx]
-
-		functionSection.Funcs[idx].Code = code
-		functionSection.Funcs[idx].Signature = signature
+		function.Signature = signature
 	}
 
 	return nil
 }
+
+func functionSignature(module *Module, typeIndex int) (*FunctionSignatureParser, error) {
+	signature, err := module.Signature(typeIndex)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %d", ErrFunctionWithouSignature, typeIndex)
+	}
+
+	return signature, nil
+}
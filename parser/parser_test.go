@@ -0,0 +1,249 @@
+package parser_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+
+	"github.com/EclesioMeloJunior/wasvm/parser"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode_InMemoryAndStreaming(t *testing.T) {
+	wasmBytes, err := os.ReadFile(simpleWasm)
+	require.NoError(t, err)
+
+	fromBytes, err := parser.Decode(wasmBytes)
+	require.NoError(t, err)
+
+	// OneByteReader does not implement io.ByteReader and returns
+	// a single byte per Read call, simulating bytes arriving over time
+	fromReader, err := parser.DecodeReader(iotest.OneByteReader(&sliceReader{wasmBytes}))
+	require.NoError(t, err)
+
+	fromFile, err := parser.BinaryFormat(simpleWasm)
+	require.NoError(t, err)
+
+	for _, bp := range []*parser.BinaryParser{fromBytes, fromReader, fromFile} {
+		require.Equal(t, uint32(1), bp.Module.Version)
+
+		require.Len(t, bp.Module.Funcs, 1)
+		require.Equal(t, []byte{0x41, 0x2A, 0x0B}, bp.Module.Funcs[0].Code.Body)
+	}
+}
+
+func TestDecode_TruncatedModule(t *testing.T) {
+	wasmBytes, err := os.ReadFile(simpleWasm)
+	require.NoError(t, err)
+
+	_, err = parser.Decode(wasmBytes[:len(wasmBytes)-2])
+	require.ErrorIs(t, err, parser.ErrUnexpectedEnd)
+
+	var decodeErr *parser.DecodeError
+	require.True(t, errors.As(err, &decodeErr))
+	require.Equal(t, int(parser.CodeSection), decodeErr.Section)
+	require.Equal(t, int64(len(wasmBytes)-2), decodeErr.Offset)
+
+	_, err = parser.DecodeReader(iotest.OneByteReader(&sliceReader{wasmBytes[:6]}))
+	require.Error(t, err)
+}
+
+// sliceReader only implements io.Reader
+type sliceReader struct {
+	data []byte
+}
+
+func (s *sliceReader) Read(p []byte) (int, error) {
+	if len(s.data) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(p, s.data)
+	s.data = s.data[n:]
+	return n, nil
+}
+
+func TestDecode_DecodeErrors(t *testing.T) {
+	header := []byte{0x00, 0x61, 0x73, 0x6D, 0x01, 0x00, 0x00, 0x00}
+	functype := section(parser.TypeSection, 0x01, 0x60, 0x00, 0x00)
+	// (import "m" "f" (func (type 0)))
+	imports := section(parser.ImportsSection, 0x01, 0x01, 'm', 0x01, 'f', 0x00, 0x00)
+	function := section(parser.FunctionSection, 0x01, 0x00)
+
+	concat := func(parts ...[]byte) []byte {
+		return bytes.Join(parts, nil)
+	}
+
+	tests := map[string]struct {
+		module            []byte
+		expectedKind      error
+		expectedSection   int
+		expectedFuncIndex int
+		// expectedOffset is where the reader was when the decoding failed
+		expectedOffset int64
+	}{
+		"truncated header": {
+			module:            header[:6],
+			expectedKind:      parser.ErrUnexpectedEnd,
+			expectedSection:   parser.NoSection,
+			expectedFuncIndex: -1,
+			expectedOffset:    6,
+		},
+		"vector length greater than the section": {
+			module:            concat(header, section(parser.TypeSection, 0xFF, 0xFF, 0xFF, 0xFF, 0x0F)),
+			expectedKind:      parser.ErrLengthOutOfBounds,
+			expectedSection:   int(parser.TypeSection),
+			expectedFuncIndex: -1,
+			expectedOffset:    15,
+		},
+		"integer encoded with too many bytes": {
+			module:            concat(header, functype, section(parser.FunctionSection, 0x81, 0x80, 0x80, 0x80, 0x80, 0x00)),
+			expectedKind:      parser.ErrMalformedInteger,
+			expectedSection:   int(parser.FunctionSection),
+			expectedFuncIndex: -1,
+			expectedOffset:    22,
+		},
+		"unknown local type after an imported function": {
+			// (func (local 0x55)), the body is at the function index 1
+			module:            concat(header, functype, imports, function, section(parser.CodeSection, 0x01, 0x04, 0x01, 0x01, 0x55, 0x0B)),
+			expectedKind:      parser.ErrMalformedModule,
+			expectedSection:   int(parser.CodeSection),
+			expectedFuncIndex: 1,
+			expectedOffset:    34,
+		},
+		"too many locals": {
+			module: concat(header, functype, function,
+				section(parser.CodeSection, 0x01, 0x08, 0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0x0F, 0x7F, 0x0B)),
+			expectedKind:      parser.ErrTooManyLocals,
+			expectedSection:   int(parser.CodeSection),
+			expectedFuncIndex: 0,
+			expectedOffset:    29,
+		},
+		"wrong magic number": {
+			module:            []byte{0x00, 0x61, 0x73, 0x6E, 0x01, 0x00, 0x00, 0x00},
+			expectedKind:      parser.ErrMagicNumber,
+			expectedSection:   parser.NoSection,
+			expectedFuncIndex: -1,
+			expectedOffset:    4,
+		},
+		"unknown version": {
+			module:            []byte{0x00, 0x61, 0x73, 0x6D, 0x02, 0x00, 0x00, 0x00},
+			expectedKind:      parser.ErrUnknownVersion,
+			expectedSection:   parser.NoSection,
+			expectedFuncIndex: -1,
+			expectedOffset:    8,
+		},
+		"section out of order": {
+			module:            concat(header, function, functype),
+			expectedKind:      parser.ErrSectionOutOfOrder,
+			expectedSection:   int(parser.TypeSection),
+			expectedFuncIndex: -1,
+			expectedOffset:    12,
+		},
+		"duplicate section": {
+			module:            concat(header, functype, functype),
+			expectedKind:      parser.ErrDuplicateSection,
+			expectedSection:   int(parser.TypeSection),
+			expectedFuncIndex: -1,
+			expectedOffset:    14,
+		},
+		"unknown type form": {
+			module:            concat(header, section(parser.TypeSection, 0x01, 0x5F, 0x00, 0x00)),
+			expectedKind:      parser.ErrMalformedModule,
+			expectedSection:   int(parser.TypeSection),
+			expectedFuncIndex: -1,
+			expectedOffset:    12,
+		},
+		"section with trailing bytes": {
+			module:            concat(header, section(parser.TypeSection, 0x01, 0x60, 0x00, 0x00, 0xFF, 0xFF)),
+			expectedKind:      parser.ErrSectionSize,
+			expectedSection:   int(parser.TypeSection),
+			expectedFuncIndex: -1,
+			expectedOffset:    14,
+		},
+		"empty section": {
+			module:            concat(header, section(parser.TypeSection)),
+			expectedKind:      parser.ErrUnexpectedEnd,
+			expectedSection:   int(parser.TypeSection),
+			expectedFuncIndex: -1,
+			expectedOffset:    10,
+		},
+		"unknown section": {
+			module:            concat(header, functype, section(0x0D, 0x00)),
+			expectedKind:      parser.ErrMalformedModule,
+			expectedSection:   0x0D,
+			expectedFuncIndex: -1,
+			expectedOffset:    14,
+		},
+		"unknown instruction": {
+			// (func nop 0xFF), the offset is the one of the unknown opcode
+			module:            concat(header, functype, function, section(parser.CodeSection, 0x01, 0x04, 0x00, 0x01, 0xFF, 0x0B)),
+			expectedKind:      parser.ErrMalformedModule,
+			expectedSection:   int(parser.CodeSection),
+			expectedFuncIndex: 0,
+			expectedOffset:    24,
+		},
+	}
+
+	for tname, tt := range tests {
+		tt := tt
+		t.Run(tname, func(t *testing.T) {
+			_, err := parser.Decode(tt.module)
+			require.ErrorIs(t, err, tt.expectedKind)
+
+			var decodeErr *parser.DecodeError
+			require.True(t, errors.As(err, &decodeErr))
+			require.Equal(t, tt.expectedKind, decodeErr.Kind)
+			require.Equal(t, tt.expectedSection, decodeErr.Section)
+			require.Equal(t, tt.expectedFuncIndex, decodeErr.FuncIndex)
+			require.Equal(t, tt.expectedOffset, decodeErr.Offset)
+		})
+	}
+}
+
+// FuzzDecode makes sure malformed modules are reported as decode or
+// validation errors instead of panics and decoded modules can be encoded
+func FuzzDecode(f *testing.F) {
+	resources, err := filepath.Glob("../resources/*.wasm")
+	require.NoError(f, err)
+
+	for _, resource := range resources {
+		wasmBytes, err := os.ReadFile(resource)
+		require.NoError(f, err)
+		f.Add(wasmBytes)
+	}
+
+	f.Fuzz(func(t *testing.T, wasmBytes []byte) {
+		bp, err := parser.Decode(wasmBytes)
+		if err != nil {
+			var decodeErr *parser.DecodeError
+			require.True(t, errors.As(err, &decodeErr), err.Error())
+			return
+		}
+
+		_ = parser.Validate(bp)
+
+		// the encoding may differ from the input, such as for non minimal
+		// integers, but encoding it again must produce the same bytes
+		encoded, err := parser.Encode(bp)
+		require.NoError(t, err)
+
+		decoded, err := parser.Decode(encoded)
+		require.NoError(t, err)
+
+		reencoded, err := parser.Encode(decoded)
+		require.NoError(t, err)
+		require.Equal(t, encoded, reencoded)
+	})
+}
+
+func TestDecodeInstructions_UnknownMiscSubOpcode(t *testing.T) {
+	// 0xFC 0x10008, the low byte of the sub opcode is the one of memory.init
+	_, err := parser.DecodeInstructions([]byte{0xFC, 0x88, 0x80, 0x04, 0x00, 0x00, 0x0B})
+	require.ErrorIs(t, err, parser.ErrUnknownInstruction)
+}
@@ -5,8 +5,20 @@ import (
 	"errors"
 	"fmt"
 	"io"
+)
+
+var (
+	ErrUnknownImportType = errors.New("unknown import type")
+	ErrUnknownTypeForm   = errors.New("unknown type form")
+	ErrUnknownValueType  = errors.New("unknown value type")
+	ErrUnknownLimitsFlag = errors.New("unknown limits flag")
+	ErrUnknownMutability = errors.New("unknown global mutability")
+
+	ErrUnknownElementFlags = errors.New("unknown element segment flags")
+	ErrUnknownElementKind  = errors.New("unknown element kind")
+	ErrUnknownDataFlags    = errors.New("unknown data segment flags")
 
-	"github.com/EclesioMeloJunior/wasvm/leb128"
+	ErrFunctionIndexOutOfBounds = errors.New("function index out of bounds")
 )
 
 type FunctionSignatureParser struct {
@@ -24,63 +36,82 @@ func (f FunctionSignatureParser) String() string {
 	}
 	result += ")"
 
-	if len(f.ResultsTypes) > 0 {
-		result += " -> ("
+	if len(f.ResultsTypes) == 0 {
+		return result
 	}
 
+	result += " -> ("
 	for idx, p := range f.ResultsTypes {
 		result += p.String()
-		if idx < len(f.ParamsTypes)-1 {
+		if idx < len(f.ResultsTypes)-1 {
 			result += ", "
 		}
 	}
-
 	result += ")"
 
 	return result
 }
 
-func (f *FunctionSignatureParser) Parse(b *bytes.Reader) error {
-	_, paramsLen, err := leb128.DecodeUint(b)
+// NewFunctionSignature creates a function signature, useful
+// to declare the signature of functions provided by the host
+func NewFunctionSignature(params, results []Type) *FunctionSignatureParser {
+	return &FunctionSignatureParser{
+		ParamsTypes:  params,
+		ResultsTypes: results,
+	}
+}
+
+// Equal returns true if both signatures have the same params and results types
+func (f *FunctionSignatureParser) Equal(other *FunctionSignatureParser) bool {
+	if len(f.ParamsTypes) != len(other.ParamsTypes) ||
+		len(f.ResultsTypes) != len(other.ResultsTypes) {
+		return false
+	}
+
+	for idx, p := range f.ParamsTypes {
+		if p.SpecByte != other.ParamsTypes[idx].SpecByte {
+			return false
+		}
+	}
+
+	for idx, r := range f.ResultsTypes {
+		if r.SpecByte != other.ResultsTypes[idx].SpecByte {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (f *FunctionSignatureParser) Parse(b BinaryReader) error {
+	paramsLen, err := decodeLength(b)
 	if err != nil {
 		return fmt.Errorf("cannot read params length: %w", err)
 	}
 
 	paramsTypes := make([]Type, paramsLen)
 	for i := 0; i < int(paramsLen); i++ {
-		paramType, err := b.ReadByte()
+		paramType, err := parseValueType(b)
 		if err != nil {
 			return fmt.Errorf("cannot read param type at %d: %w", i, err)
 		}
 
-		switch paramType {
-		case I32_NUM_TYPE, I64_NUM_TYPE, F32_NUM_TYPE, F64_NUM_TYPE:
-			paramsTypes[i] = Type{
-				SpecType: NumType,
-				SpecByte: paramType,
-			}
-		}
+		paramsTypes[i] = paramType
 	}
 
-	_, resultsLen, err := leb128.DecodeUint(b)
+	resultsLen, err := decodeLength(b)
 	if err != nil {
 		return fmt.Errorf("cannot read results length: %w", err)
 	}
 
 	resultsTypes := make([]Type, resultsLen)
 	for i := 0; i < int(resultsLen); i++ {
-		resultType, err := b.ReadByte()
+		resultType, err := parseValueType(b)
 		if err != nil {
 			return fmt.Errorf("cannot read result type at %d: %w", i, err)
 		}
 
-		switch resultType {
-		case I32_NUM_TYPE, I64_NUM_TYPE, F32_NUM_TYPE, F64_NUM_TYPE:
-			resultsTypes[i] = Type{
-				SpecType: NumType,
-				SpecByte: resultType,
-			}
-		}
+		resultsTypes[i] = resultType
 	}
 
 	f.ParamsTypes = paramsTypes
@@ -90,16 +121,16 @@ func (f *FunctionSignatureParser) Parse(b *bytes.Reader) error {
 }
 
 type TypeSectionParser struct {
-	Types []Parser
+	Types []*FunctionSignatureParser
 }
 
-func (t *TypeSectionParser) Parse(b *bytes.Reader) error {
-	_, typeSectionLen, err := leb128.DecodeUint(b)
+func (t *TypeSectionParser) Parse(b BinaryReader) error {
+	typeSectionLen, err := decodeLength(b)
 	if err != nil {
 		return fmt.Errorf("cannot read type section length: %w", err)
 	}
 
-	funcSignatureTypes := make([]Parser, 0, typeSectionLen)
+	funcSignatureTypes := make([]*FunctionSignatureParser, 0, typeSectionLen)
 
 	for i := 0; i < int(typeSectionLen); i++ {
 		typeTag, err := b.ReadByte()
@@ -107,14 +138,18 @@ func (t *TypeSectionParser) Parse(b *bytes.Reader) error {
 			return fmt.Errorf("failed to read tag at index %d: %w", i, err)
 		}
 
-		if typeTag == FunctionTag {
-			functionSigParser := &FunctionSignatureParser{}
-			if err := functionSigParser.Parse(b); err != nil {
-				return fmt.Errorf("cannot parse function signature at index %d: %w", i, err)
-			}
+		// function types are the only form of type, skipping an unknown
+		// one would shift the indices of the types that follow it
+		if typeTag != FunctionTag {
+			return fmt.Errorf("%w: 0x%x at index %d", ErrUnknownTypeForm, typeTag, i)
+		}
 
-			funcSignatureTypes = append(funcSignatureTypes, functionSigParser)
+		functionSigParser := &FunctionSignatureParser{}
+		if err := functionSigParser.Parse(b); err != nil {
+			return fmt.Errorf("cannot parse function signature at index %d: %w", i, err)
 		}
+
+		funcSignatureTypes = append(funcSignatureTypes, functionSigParser)
 	}
 
 	t.Types = funcSignatureTypes
@@ -125,21 +160,27 @@ type Function struct {
 	TypeIndex int
 	Signature *FunctionSignatureParser
 	Code      *CodeParser
+
+	// Import is only defined for functions that
+	// are provided by the host instead of the module
+	Import *Import
 }
 
+// FunctionSectionParser holds the type indexes of the functions defined in the
+// module, their signatures and code are bound once the whole module is decoded
 type FunctionSectionParser struct {
 	Funcs []*Function
 }
 
-func (f *FunctionSectionParser) Parse(b *bytes.Reader) error {
-	_, funcsLen, err := leb128.DecodeUint(b)
+func (f *FunctionSectionParser) Parse(b BinaryReader) error {
+	funcsLen, err := decodeLength(b)
 	if err != nil {
 		return fmt.Errorf("cannot read function amount: %w", err)
 	}
 
 	funcs := make([]*Function, funcsLen)
 	for i := 0; i < int(funcsLen); i++ {
-		_, typeIndex, err := leb128.DecodeUint(b)
+		typeIndex, err := decodeIndex(b)
 		if err != nil {
 			return fmt.Errorf("cannot read function type index at %d: %w", i, err)
 		}
@@ -153,6 +194,94 @@ func (f *FunctionSectionParser) Parse(b *bytes.Reader) error {
 	return nil
 }
 
+type TableSectionParser struct {
+	Tables []*TableType
+}
+
+func (t *TableSectionParser) Parse(b BinaryReader) error {
+	tablesLen, err := decodeLength(b)
+	if err != nil {
+		return fmt.Errorf("cannot read number of tables: %w", err)
+	}
+
+	tables := make([]*TableType, tablesLen)
+	for i := 0; i < int(tablesLen); i++ {
+		tableType, err := parseTableType(b)
+		if err != nil {
+			return fmt.Errorf("cannot read table type at %d: %w", i, err)
+		}
+
+		tables[i] = tableType
+	}
+
+	t.Tables = tables
+	return nil
+}
+
+type MemorySectionParser struct {
+	Memories []*MemoryType
+}
+
+func (m *MemorySectionParser) Parse(b BinaryReader) error {
+	memoriesLen, err := decodeLength(b)
+	if err != nil {
+		return fmt.Errorf("cannot read number of memories: %w", err)
+	}
+
+	memories := make([]*MemoryType, memoriesLen)
+	for i := 0; i < int(memoriesLen); i++ {
+		memoryType, err := parseMemoryType(b)
+		if err != nil {
+			return fmt.Errorf("cannot read memory type at %d: %w", i, err)
+		}
+
+		memories[i] = memoryType
+	}
+
+	m.Memories = memories
+	return nil
+}
+
+type Global struct {
+	Type *GlobalType
+
+	// Init is the constant expression that produces the global
+	// initial value, it is evaluated during the instantiation
+	Init []byte
+}
+
+type GlobalSectionParser struct {
+	Globals []*Global
+}
+
+func (g *GlobalSectionParser) Parse(b BinaryReader) error {
+	globalsLen, err := decodeLength(b)
+	if err != nil {
+		return fmt.Errorf("cannot read number of globals: %w", err)
+	}
+
+	globals := make([]*Global, globalsLen)
+	for i := 0; i < int(globalsLen); i++ {
+		globalType, err := parseGlobalType(b)
+		if err != nil {
+			return fmt.Errorf("cannot read global type at %d: %w", i, err)
+		}
+
+		init, err := parseConstantExpression(b)
+		if err != nil {
+			return fmt.Errorf("cannot read global initializer at %d: %w", i, err)
+		}
+
+		globals[i] = &Global{
+			Type: globalType,
+			Init: init,
+		}
+	}
+
+	g.Globals = globals
+	return nil
+}
+
 // Type tells us what is being exported
 // 0x00 funcidx
 // 0x01 tableidx
@@ -177,8 +306,8 @@ type ExportSectionParser struct {
 	Exports []*Export
 }
 
-func (e *ExportSectionParser) Parse(b *bytes.Reader) error {
-	_, exportsLen, err := leb128.DecodeUint(b)
+func (e *ExportSectionParser) Parse(b BinaryReader) error {
+	exportsLen, err := decodeLength(b)
 	if err != nil {
 		return fmt.Errorf("cannot read number of exports: %w", err)
 	}
@@ -186,17 +315,9 @@ func (e *ExportSectionParser) Parse(b *bytes.Reader) error {
 	exports := make([]*Export, exportsLen)
 
 	for i := 0; i < int(exportsLen); i++ {
-		_, nameLen, err := leb128.DecodeUint(b)
-		if err != nil {
-			return fmt.Errorf("cannot read exported name length at %d: %w", i, err)
-		}
-
-		nameBytes := make([]byte, nameLen)
-		n, err := b.Read(nameBytes)
+		name, err := readName(b)
 		if err != nil {
-			return fmt.Errorf("cannot read exported name bytes at %d: %w", i, err)
-		} else if n != int(nameLen) {
-			return fmt.Errorf("expected name bytes length %d. got %d", nameLen, n)
+			return fmt.Errorf("cannot read exported name at %d: %w", i, err)
 		}
 
 		exportType, err := b.ReadByte()
@@ -204,7 +325,7 @@ func (e *ExportSectionParser) Parse(b *bytes.Reader) error {
 			return fmt.Errorf("cannot read exported type at %d: %w", i, err)
 		}
 
-		_, exportIdx, err := leb128.DecodeUint(b)
+		exportIdx, err := decodeIndex(b)
 		if err != nil {
 			return fmt.Errorf("cannot read exported index at %d: %w", i, err)
 		}
@@ -212,7 +333,7 @@ func (e *ExportSectionParser) Parse(b *bytes.Reader) error {
 		exports[i] = &Export{
 			Index: int(exportIdx),
 			Type:  ExportedType(exportType),
-			Name:  string(nameBytes),
+			Name:  name,
 		}
 	}
 
@@ -220,23 +341,44 @@ func (e *ExportSectionParser) Parse(b *bytes.Reader) error {
 	return nil
 }
 
+// MaxFunctionLocals is the maximum amount of locals, besides the params,
+// a function can declare so a small body cannot demand a huge allocation
+const MaxFunctionLocals = 50000
+
 type CodeParser struct {
-	Body   []byte
-	Locals []Type
+	// Body is the encoded function body as it was decoded, the
+	// module is encoded back from the instructions instead
+	Body []byte
+	// Instructions is the decoded body, it ends with the function end
+	Instructions []Instruction
+	Locals       []Type
+}
+
+// NewCode creates the code of a function from its locals, besides
+// the params, and its encoded body that must include the final end
+func NewCode(locals []Type, body []byte) (*CodeParser, error) {
+	instructions, err := DecodeInstructions(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CodeParser{Body: body, Instructions: instructions, Locals: locals}, nil
 }
 
-func (c *CodeParser) Parse(b *bytes.Reader, len uint) error {
-	_, localsLen, err := leb128.DecodeUint(b)
+func (c *CodeParser) Parse(b BinaryReader, size uint) error {
+	localsLen, err := decodeLength(b)
 	if err != nil {
 		return fmt.Errorf("cannot read local length: %w", err)
 	}
 
 	if localsLen > 0 {
-		c.parseLocals(b, localsLen)
+		if err := c.parseLocals(b, localsLen); err != nil {
+			return fmt.Errorf("cannot parse locals: %w", err)
+		}
 	}
 
 	body := make([]byte, 0)
-	for i := 0; i < int(len); i++ {
+	for i := 0; i < int(size); i++ {
 		b, err := b.ReadByte()
 		if errors.Is(err, io.EOF) {
 			break
@@ -248,31 +390,32 @@ func (c *CodeParser) Parse(b *bytes.Reader, len uint) error {
 	}
 
 	c.Body = body
-	return nil
+	c.Instructions, err = DecodeInstructions(body)
+	return err
 }
 
-func (c *CodeParser) parseLocals(b *bytes.Reader, len uint) error {
-	c.Locals = make([]Type, len)
+// parseLocals reads the locals declarations, each declaration
+// is a pair of (amount, type) that is expanded into c.Locals
+func (c *CodeParser) parseLocals(b BinaryReader, declarations int) error {
+	c.Locals = make([]Type, 0, declarations)
 
-	for i := uint(0); i < len; i++ {
-		localType, err := b.ReadByte()
+	for i := 0; i < declarations; i++ {
+		amount, err := decodeIndex(b)
 		if err != nil {
-			return fmt.Errorf("while reading local type byte: %w", err)
+			return fmt.Errorf("while reading locals amount: %w", err)
 		}
 
-		switch localType {
-		case I32_NUM_TYPE, I64_NUM_TYPE:
-			c.Locals[i] = Type{
-				SpecType: NumType,
-				SpecByte: localType,
-			}
-		default:
-			unsupportedType := Type{
-				SpecByte: localType,
-			}
+		localType, err := parseValueType(b)
+		if err != nil {
+			return fmt.Errorf("while reading local type: %w", err)
+		}
+
+		if uint64(len(c.Locals))+uint64(amount) > MaxFunctionLocals {
+			return fmt.Errorf("%w: more than %d", ErrTooManyLocals, MaxFunctionLocals)
+		}
 
-			panic(fmt.Sprintf("local type not supported yet: %s (%v)",
-				unsupportedType.String(), localType))
+		for j := uint32(0); j < amount; j++ {
+			c.Locals = append(c.Locals, localType)
 		}
 	}
 
@@ -283,31 +426,49 @@ type CodeSectionParser struct {
 	FunctionsCode []*CodeParser
 }
 
-func (c *CodeSectionParser) Parse(b *bytes.Reader) error {
-	_, amount, err := leb128.DecodeUint(b)
+func (c *CodeSectionParser) Parse(b BinaryReader) error {
+	// the bodies positions are used to tell where a malformed body is
+	position := func() int { return 0 }
+	if r, ok := b.(remainingReader); ok {
+		sectionLen := r.Len()
+		position = func() int { return sectionLen - r.Len() }
+	}
+
+	amount, err := decodeLength(b)
 	if err != nil {
 		return fmt.Errorf("cannot read number of functions: %w", err)
 	}
 
 	codes := make([]*CodeParser, amount)
-	for i := 0; i < int(amount); i++ {
-		_, totalCodeSize, err := leb128.DecodeUint(b)
+	for i := 0; i < amount; i++ {
+		totalCodeSize, err := decodeLength(b)
 		if err != nil {
-			return fmt.Errorf("cannot read the code length at %d: %w", i, err)
+			return &functionBodyError{index: i, offset: position(),
+				err: fmt.Errorf("cannot read the code length: %w", err)}
 		}
 
+		bodyStart := position()
 		code := make([]byte, totalCodeSize)
-		n, err := b.Read(code)
-		if err != nil {
-			return fmt.Errorf("cannot read the code at %d: %w", i, err)
-		} else if n != int(totalCodeSize) {
-			return fmt.Errorf("expected code bytes length %d. got %d", totalCodeSize, n)
+		if _, err := io.ReadFull(b, code); err != nil {
+			return &functionBodyError{index: i, offset: position(),
+				err: fmt.Errorf("cannot read the code: %w", err)}
 		}
 
+		reader := bytes.NewReader(code)
 		codeParser := &CodeParser{}
-		err = codeParser.Parse(bytes.NewReader(code), totalCodeSize)
+		err = codeParser.Parse(reader, uint(totalCodeSize))
 		if err != nil {
-			return fmt.Errorf("cannot parse code instructions at %d: %w", i, err)
+			offset := bodyStart + totalCodeSize - reader.Len()
+
+			// the body is read before being decoded so the reader is
+			// at its end, the instruction tells where it really failed
+			var instErr *instructionError
+			if errors.As(err, &instErr) {
+				offset = bodyStart + totalCodeSize - len(codeParser.Body) + instErr.offset
+			}
+
+			return &functionBodyError{index: i, offset: offset,
+				err: fmt.Errorf("cannot parse code instructions: %w", err)}
 		}
 
 		codes[i] = codeParser
@@ -318,14 +479,503 @@ func (c *CodeSectionParser) Parse(b *bytes.Reader) error {
 	return nil
 }
 
-type StartSectionParser struct{}
+type ElementMode byte
+
+const (
+	// ElementActive segments are copied into a table during the instantiation
+	ElementActive ElementMode = iota
+	// ElementPassive segments are kept to be used by table.init
+	ElementPassive
+	// ElementDeclarative segments only forward declare function references
+	ElementDeclarative
+)
+
+type Element struct {
+	// Flags is the segment encoding variant (0 to 7)
+	Flags uint32
+	Mode  ElementMode
+	Type  Type
+
+	// Table and Offset are only meaningful for active segments
+	Table  int
+	Offset []byte
+
+	// FuncIndices is used when the segment is encoded as function
+	// indices (flags 0 to 3) otherwise Init holds one constant
+	// expression per element
+	FuncIndices []int
+	Init        [][]byte
+}
+
+// Len returns the amount of references the segment holds
+func (e *Element) Len() int {
+	if e.Init != nil {
+		return len(e.Init)
+	}
+
+	return len(e.FuncIndices)
+}
+
+type ElementSectionParser struct {
+	Elements []*Element
+}
+
+func (e *ElementSectionParser) Parse(b BinaryReader) error {
+	elementsLen, err := decodeLength(b)
+	if err != nil {
+		return fmt.Errorf("cannot read number of element segments: %w", err)
+	}
+
+	elements := make([]*Element, elementsLen)
+	for i := 0; i < int(elementsLen); i++ {
+		element, err := parseElement(b)
+		if err != nil {
+			return fmt.Errorf("cannot read element segment at %d: %w", i, err)
+		}
+
+		elements[i] = element
+	}
+
+	e.Elements = elements
+	return nil
+}
+
+// parseElement decodes an element segment, the flags bits tells us:
+// bit 0: passive or declarative (when set) instead of active
+// bit 1: explicit table index for active or declarative for non active
+// bit 2: elements are encoded as expressions instead of function indices
+func parseElement(b BinaryReader) (*Element, error) {
+	flags, err := decodeIndex(b)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read element flags: %w", err)
+	}
+
+	if flags > 7 {
+		return nil, fmt.Errorf("%w: 0x%x", ErrUnknownElementFlags, flags)
+	}
+
+	element := &Element{
+		Flags: uint32(flags),
+		Type:  Type{SpecType: RefType, SpecByte: FUNC_REF_TYPE},
+	}
+
+	switch {
+	case flags&0x01 == 0:
+		element.Mode = ElementActive
+	case flags&0x02 == 0:
+		element.Mode = ElementPassive
+	default:
+		element.Mode = ElementDeclarative
+	}
+
+	if element.Mode == ElementActive {
+		if flags&0x02 != 0 {
+			tableIdx, err := decodeIndex(b)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read element table index: %w", err)
+			}
+			element.Table = int(tableIdx)
+		}
+
+		element.Offset, err = parseConstantExpression(b)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read element offset: %w", err)
+		}
+	}
+
+	usesExpressions := flags&0x04 != 0
+
+	// flags 0 and 4 have an implicit funcref type
+	if flags&0x03 != 0 {
+		if usesExpressions {
+			element.Type, err = parseRefType(b)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read element reference type: %w", err)
+			}
+		} else {
+			elemKind, err := b.ReadByte()
+			if err != nil {
+				return nil, fmt.Errorf("cannot read element kind: %w", err)
+			}
+
+			if elemKind != 0x00 {
+				return nil, fmt.Errorf("%w: 0x%x", ErrUnknownElementKind, elemKind)
+			}
+		}
+	}
+
+	initLen, err := decodeLength(b)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read number of elements: %w", err)
+	}
+
+	if usesExpressions {
+		element.Init = make([][]byte, initLen)
+		for i := 0; i < int(initLen); i++ {
+			element.Init[i], err = parseConstantExpression(b)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read element expression at %d: %w", i, err)
+			}
+		}
+
+		return element, nil
+	}
+
+	element.FuncIndices = make([]int, initLen)
+	for i := 0; i < int(initLen); i++ {
+		funcIdx, err := decodeIndex(b)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read element function index at %d: %w", i, err)
+		}
+
+		element.FuncIndices[i] = int(funcIdx)
+	}
+
+	return element, nil
+}
+
+type DataMode byte
+
+const (
+	// DataActive segments are copied into a memory during the instantiation
+	DataActive DataMode = iota
+	// DataPassive segments are kept to be used by memory.init
+	DataPassive
+)
+
+type Data struct {
+	// Flags is the segment encoding variant (0 to 2)
+	Flags uint32
+	Mode  DataMode
+
+	// Memory and Offset are only meaningful for active segments
+	Memory int
+	Offset []byte
+
+	Init []byte
+}
+
+type DataSectionParser struct {
+	Data []*Data
+}
+
+func (d *DataSectionParser) Parse(b BinaryReader) error {
+	dataLen, err := decodeLength(b)
+	if err != nil {
+		return fmt.Errorf("cannot read number of data segments: %w", err)
+	}
+
+	segments := make([]*Data, dataLen)
+	for i := 0; i < int(dataLen); i++ {
+		segment, err := parseData(b)
+		if err != nil {
+			return fmt.Errorf("cannot read data segment at %d: %w", i, err)
+		}
+
+		segments[i] = segment
+	}
+
+	d.Data = segments
+	return nil
+}
+
+// parseData decodes a data segment, where flags 0 is an active segment for
+// memory 0, flags 1 is a passive segment and flags 2 is an active segment
+// with an explicit memory index
+func parseData(b BinaryReader) (*Data, error) {
+	flags, err := decodeIndex(b)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read data flags: %w", err)
+	}
+
+	segment := &Data{Flags: uint32(flags)}
+
+	switch flags {
+	case 0x00:
+		segment.Mode = DataActive
+	case 0x01:
+		segment.Mode = DataPassive
+	case 0x02:
+		segment.Mode = DataActive
+
+		memIdx, err := decodeIndex(b)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read data memory index: %w", err)
+		}
+		segment.Memory = int(memIdx)
+	default:
+		return nil, fmt.Errorf("%w: 0x%x", ErrUnknownDataFlags, flags)
+	}
+
+	if segment.Mode == DataActive {
+		segment.Offset, err = parseConstantExpression(b)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read data offset: %w", err)
+		}
+	}
+
+	initLen, err := decodeLength(b)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read data length: %w", err)
+	}
+
+	segment.Init = make([]byte, initLen)
+	n, err := io.ReadFull(b, segment.Init)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("cannot read data bytes: %w", err)
+	} else if n != int(initLen) {
+		return nil, fmt.Errorf("expected data bytes length %d. got %d", initLen, n)
+	}
+
+	return segment, nil
+}
+
+type DataCountSectionParser struct {
+	// Count is nil when the module does not have a data count section
+	Count *uint32
+}
+
+func (d *DataCountSectionParser) Parse(b BinaryReader) error {
+	count, err := decodeIndex(b)
+	if err != nil {
+		return fmt.Errorf("cannot read data count: %w", err)
+	}
 
-func (i *StartSectionParser) Parse(b *bytes.Reader) error {
+	dataCount := uint32(count)
+	d.Count = &dataCount
 	return nil
 }
 
-type ImportsSectionParser struct{}
+type StartSectionParser struct {
+	// FuncIndex is nil when the module does not define a start function
+	FuncIndex *int
+}
+
+func (s *StartSectionParser) Parse(b BinaryReader) error {
+	funcIndex, err := decodeIndex(b)
+	if err != nil {
+		return fmt.Errorf("cannot read start function index: %w", err)
+	}
 
-func (i *ImportsSectionParser) Parse(b *bytes.Reader) error {
+	startFuncIndex := int(funcIndex)
+	s.FuncIndex = &startFuncIndex
 	return nil
 }
+
+// ImportedType tells us what is being imported
+// 0x00 typeidx
+// 0x01 tabletype
+// 0x02 memtype
+// 0x03 globaltype
+type ImportedType byte
+
+const (
+	ImportedFunc   ImportedType = 0x00
+	ImportedTable  ImportedType = 0x01
+	ImportedMem    ImportedType = 0x02
+	ImportedGlobal ImportedType = 0x03
+)
+
+type Import struct {
+	Module string
+	Name   string
+	Type   ImportedType
+
+	// only one of the descriptors below is filled
+	// depending on the import type
+	TypeIndex int
+	Table     *TableType
+	Memory    *MemoryType
+	Global    *GlobalType
+}
+
+type ImportsSectionParser struct {
+	Imports []*Import
+}
+
+func (i *ImportsSectionParser) Parse(b BinaryReader) error {
+	importsLen, err := decodeLength(b)
+	if err != nil {
+		return fmt.Errorf("cannot read number of imports: %w", err)
+	}
+
+	imports := make([]*Import, importsLen)
+	for idx := 0; idx < int(importsLen); idx++ {
+		moduleName, err := readName(b)
+		if err != nil {
+			return fmt.Errorf("cannot read module name at %d: %w", idx, err)
+		}
+
+		name, err := readName(b)
+		if err != nil {
+			return fmt.Errorf("cannot read import name at %d: %w", idx, err)
+		}
+
+		importType, err := b.ReadByte()
+		if err != nil {
+			return fmt.Errorf("cannot read import type at %d: %w", idx, err)
+		}
+
+		imported := &Import{
+			Module: moduleName,
+			Name:   name,
+			Type:   ImportedType(importType),
+		}
+
+		switch imported.Type {
+		case ImportedFunc:
+			typeIndex, err := decodeIndex(b)
+			if err != nil {
+				return fmt.Errorf("cannot read imported function type index at %d: %w", idx, err)
+			}
+			imported.TypeIndex = int(typeIndex)
+		case ImportedTable:
+			tableType, err := parseTableType(b)
+			if err != nil {
+				return fmt.Errorf("cannot read imported table type at %d: %w", idx, err)
+			}
+			imported.Table = tableType
+		case ImportedMem:
+			memoryType, err := parseMemoryType(b)
+			if err != nil {
+				return fmt.Errorf("cannot read imported memory type at %d: %w", idx, err)
+			}
+			imported.Memory = memoryType
+		case ImportedGlobal:
+			globalType, err := parseGlobalType(b)
+			if err != nil {
+				return fmt.Errorf("cannot read imported global type at %d: %w", idx, err)
+			}
+			imported.Global = globalType
+		default:
+			return fmt.Errorf("%w: 0x%x at %d", ErrUnknownImportType, importType, idx)
+		}
+
+		imports[idx] = imported
+	}
+
+	i.Imports = imports
+	return nil
+}
+
+func readName(b BinaryReader) (string, error) {
+	nameLen, err := decodeLength(b)
+	if err != nil {
+		return "", fmt.Errorf("cannot read name length: %w", err)
+	}
+
+	nameBytes := make([]byte, nameLen)
+	n, err := io.ReadFull(b, nameBytes)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", fmt.Errorf("cannot read name bytes: %w", err)
+	} else if n != int(nameLen) {
+		return "", fmt.Errorf("expected name bytes length %d. got %d", nameLen, n)
+	}
+
+	return string(nameBytes), nil
+}
+
+func parseValueType(b BinaryReader) (Type, error) {
+	valueType, err := b.ReadByte()
+	if err != nil {
+		return Type{}, fmt.Errorf("cannot read value type: %w", err)
+	}
+
+	switch valueType {
+	case I32_NUM_TYPE, I64_NUM_TYPE, F32_NUM_TYPE, F64_NUM_TYPE:
+		return Type{SpecType: NumType, SpecByte: valueType}, nil
+	case VEC_TYPE:
+		return Type{SpecType: VecType, SpecByte: valueType}, nil
+	case FUNC_REF_TYPE, EXTERN_REF_TYPE:
+		return Type{SpecType: RefType, SpecByte: valueType}, nil
+	}
+
+	return Type{}, fmt.Errorf("%w: 0x%x", ErrUnknownValueType, valueType)
+}
+
+func parseRefType(b BinaryReader) (Type, error) {
+	refType, err := parseValueType(b)
+	if err != nil {
+		return Type{}, err
+	}
+
+	if refType.SpecType != RefType {
+		return Type{}, fmt.Errorf("%w: expected reference type, got %s",
+			ErrUnknownValueType, refType)
+	}
+
+	return refType, nil
+}
+
+// parseLimits reads the limits flag, 0x00 means only min
+// is present while 0x01 means both min and max are present
+func parseLimits(b BinaryReader) (Limits, error) {
+	flag, err := b.ReadByte()
+	if err != nil {
+		return Limits{}, fmt.Errorf("cannot read limits flag: %w", err)
+	}
+
+	min, err := decodeIndex(b)
+	if err != nil {
+		return Limits{}, fmt.Errorf("cannot read limits min: %w", err)
+	}
+
+	limits := Limits{Min: uint32(min)}
+
+	switch flag {
+	case 0x00:
+	case 0x01:
+		max, err := decodeIndex(b)
+		if err != nil {
+			return Limits{}, fmt.Errorf("cannot read limits max: %w", err)
+		}
+
+		limits.Max = uint32(max)
+		limits.HasMax = true
+	default:
+		return Limits{}, fmt.Errorf("%w: 0x%x", ErrUnknownLimitsFlag, flag)
+	}
+
+	return limits, nil
+}
+
+func parseTableType(b BinaryReader) (*TableType, error) {
+	elemType, err := parseRefType(b)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read table element type: %w", err)
+	}
+
+	limits, err := parseLimits(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TableType{ElemType: elemType, Limits: limits}, nil
+}
+
+func parseMemoryType(b BinaryReader) (*MemoryType, error) {
+	limits, err := parseLimits(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MemoryType{Limits: limits}, nil
+}
+
+func parseGlobalType(b BinaryReader) (*GlobalType, error) {
+	valType, err := parseValueType(b)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read global value type: %w", err)
+	}
+
+	mutability, err := b.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read global mutability: %w", err)
+	}
+
+	if mutability > 0x01 {
+		return nil, fmt.Errorf("%w: 0x%x", ErrUnknownMutability, mutability)
+	}
+
+	return &GlobalType{ValType: valType, Mutable: mutability == 0x01}, nil
+}
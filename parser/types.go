@@ -25,6 +25,13 @@ type Type struct {
 	SpecByte byte
 }
 
+var (
+	I32 = Type{SpecType: NumType, SpecByte: I32_NUM_TYPE}
+	I64 = Type{SpecType: NumType, SpecByte: I64_NUM_TYPE}
+	F32 = Type{SpecType: NumType, SpecByte: F32_NUM_TYPE}
+	F64 = Type{SpecType: NumType, SpecByte: F64_NUM_TYPE}
+)
+
 func (v Type) String() string {
 	switch v.SpecByte {
 	case I32_NUM_TYPE:
@@ -35,7 +42,33 @@ func (v Type) String() string {
 		return "f32"
 	case F64_NUM_TYPE:
 		return "f64"
+	case FUNC_REF_TYPE:
+		return "funcref"
+	case EXTERN_REF_TYPE:
+		return "externref"
 	}
 
 	return "?"
 }
+
+// Limits defines the min and the optional max size
+// of memories (in pages) and tables (in elements)
+type Limits struct {
+	Min    uint32
+	Max    uint32
+	HasMax bool
+}
+
+type TableType struct {
+	ElemType Type
+	Limits   Limits
+}
+
+type MemoryType struct {
+	Limits Limits
+}
+
+type GlobalType struct {
+	ValType Type
+	Mutable bool
+}
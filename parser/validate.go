@@ -0,0 +1,940 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/EclesioMeloJunior/wasvm/opcodes"
+)
+
+// maxMemoryPages is the maximum amount of 64KiB pages a memory can declare
+const maxMemoryPages = 65536
+
+var (
+	ErrTypeMismatch               = errors.New("type mismatch")
+	ErrUnknownLocal               = errors.New("unknown local")
+	ErrUnknownGlobal              = errors.New("unknown global")
+	ErrUnknownFunction            = errors.New("unknown function")
+	ErrUnknownType                = errors.New("unknown type")
+	ErrUnknownTable               = errors.New("unknown table")
+	ErrUnknownMemory              = errors.New("unknown memory")
+	ErrUnknownDataSegment         = errors.New("unknown data segment")
+	ErrUnknownLabel               = errors.New("unknown label")
+	ErrImmutableGlobal            = errors.New("global is immutable")
+	ErrAlignmentTooLarge          = errors.New("alignment must not be larger than natural")
+	ErrMalformedBody              = errors.New("malformed function body")
+	ErrDuplicateExport            = errors.New("duplicate export name")
+	ErrUnknownExportKind          = errors.New("unknown export kind")
+	ErrInvalidLimits              = errors.New("size minimum must not be greater than maximum")
+	ErrMemoryTooLarge             = errors.New("memory size must be at most 65536 pages (4GiB)")
+	ErrMultipleMemories           = errors.New("multiple memories")
+	ErrInvalidStartFunction       = errors.New("start function must have type func()")
+	ErrConstantExpressionRequired = errors.New("constant expression required")
+	ErrDataCountRequired          = errors.New("data count section required")
+	ErrDataCountMismatch          = errors.New("data count and data section have inconsistent lengths")
+	ErrFunctionCodeMismatch       = errors.New("function and code section have inconsistent lengths")
+)
+
+// unknownType is the type of the values popped from
+// an empty stack after an unconditional branch
+var unknownType = Type{}
+
+// ValidationError tells why a module is not valid, FuncIndex is the function
+// index space position of the invalid function and Offset is the position of
+// the invalid instruction within its body, both are -1 when the error
+// is not related to a function body
+type ValidationError struct {
+	FuncIndex int
+	Offset    int
+	Err       error
+}
+
+func (e *ValidationError) Error() string {
+	if e.FuncIndex < 0 {
+		return fmt.Sprintf("invalid module: %s", e.Err)
+	}
+
+	return fmt.Sprintf("invalid function %d at offset %d: %s", e.FuncIndex, e.Offset, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+func moduleError(format string, args ...any) error {
+	return &ValidationError{FuncIndex: -1, Offset: -1, Err: fmt.Errorf(format, args...)}
+}
+
+// moduleContext holds every definition of the module that
+// instructions and segments are allowed to refer to
+type moduleContext struct {
+	types     []*FunctionSignatureParser
+	functions []*Function
+	tables    []*TableType
+	memories  []*MemoryType
+	globals   []*GlobalType
+
+	// importedGlobals is the amount of globals that
+	// constant expressions are allowed to read
+	importedGlobals int
+	dataCount       *uint32
+}
+
+func newModuleContext(module *Module) (*moduleContext, error) {
+	m := &moduleContext{
+		types:     module.Types,
+		functions: module.Funcs,
+		dataCount: module.DataCount,
+	}
+
+	for idx, function := range m.functions {
+		if function.TypeIndex < 0 || function.TypeIndex >= len(m.types) {
+			return nil, moduleError("%w: function %d refers to type %d",
+				ErrUnknownType, idx, function.TypeIndex)
+		}
+	}
+
+	for _, imported := range module.Imports {
+		switch imported.Type {
+		case ImportedTable:
+			m.tables = append(m.tables, imported.Table)
+		case ImportedMem:
+			m.memories = append(m.memories, imported.Memory)
+		case ImportedGlobal:
+			m.globals = append(m.globals, imported.Global)
+		}
+	}
+
+	m.importedGlobals = len(m.globals)
+	m.tables = append(m.tables, module.Tables...)
+	m.memories = append(m.memories, module.Memories...)
+	for _, global := range module.Globals {
+		m.globals = append(m.globals, global.Type)
+	}
+
+	return m, nil
+}
+
+// Validate checks the module follows the validation rules of the spec, so it can
+// be executed without type checking the operands at runtime, the returned error
+// is always a *ValidationError
+func Validate(bp *BinaryParser) error {
+	module, err := newModuleContext(bp.Module)
+	if err != nil {
+		return err
+	}
+
+	validations := []func(*Module) error{
+		module.validateLimits,
+		module.validateGlobals,
+		module.validateElements,
+		module.validateData,
+		module.validateStart,
+		module.validateExports,
+		module.validateCode,
+	}
+
+	for _, validate := range validations {
+		if err := validate(bp.Module); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *moduleContext) validateLimits(*Module) error {
+	for idx, table := range m.tables {
+		if table.Limits.HasMax && table.Limits.Min > table.Limits.Max {
+			return moduleError("table %d: %w", idx, ErrInvalidLimits)
+		}
+	}
+
+	if len(m.memories) > 1 {
+		return moduleError("%w: %d memories defined", ErrMultipleMemories, len(m.memories))
+	}
+
+	for idx, memory := range m.memories {
+		limits := memory.Limits
+		if limits.Min > maxMemoryPages || (limits.HasMax && limits.Max > maxMemoryPages) {
+			return moduleError("memory %d: %w", idx, ErrMemoryTooLarge)
+		}
+
+		if limits.HasMax && limits.Min > limits.Max {
+			return moduleError("memory %d: %w", idx, ErrInvalidLimits)
+		}
+	}
+
+	return nil
+}
+
+func (m *moduleContext) validateGlobals(module *Module) error {
+	for idx, global := range module.Globals {
+		if err := m.validateConstantExpression(global.Init, global.Type.ValType); err != nil {
+			return moduleError("global %d initializer: %w", m.importedGlobals+idx, err)
+		}
+	}
+
+	return nil
+}
+
+func (m *moduleContext) validateElements(module *Module) error {
+	for idx, element := range module.Elements {
+		for _, funcIdx := range element.FuncIndices {
+			if funcIdx >= len(m.functions) {
+				return moduleError("element %d: %w: %d", idx, ErrUnknownFunction, funcIdx)
+			}
+		}
+
+		for _, init := range element.Init {
+			if err := m.validateConstantExpression(init, element.Type); err != nil {
+				return moduleError("element %d: %w", idx, err)
+			}
+		}
+
+		if element.Mode != ElementActive {
+			continue
+		}
+
+		if element.Table >= len(m.tables) {
+			return moduleError("element %d: %w: %d", idx, ErrUnknownTable, element.Table)
+		}
+
+		if elemType := m.tables[element.Table].ElemType; elemType.SpecByte != element.Type.SpecByte {
+			return moduleError("element %d: %w: table %d holds %s, got %s",
+				idx, ErrTypeMismatch, element.Table, elemType, element.Type)
+		}
+
+		if err := m.validateConstantExpression(element.Offset, I32); err != nil {
+			return moduleError("element %d offset: %w", idx, err)
+		}
+	}
+
+	return nil
+}
+
+func (m *moduleContext) validateData(module *Module) error {
+	if m.dataCount != nil && int(*m.dataCount) != len(module.Data) {
+		return moduleError("%w: data count %d, %d data segments",
+			ErrDataCountMismatch, *m.dataCount, len(module.Data))
+	}
+
+	for idx, segment := range module.Data {
+		if segment.Mode != DataActive {
+			continue
+		}
+
+		if segment.Memory >= len(m.memories) {
+			return moduleError("data %d: %w: %d", idx, ErrUnknownMemory, segment.Memory)
+		}
+
+		if err := m.validateConstantExpression(segment.Offset, I32); err != nil {
+			return moduleError("data %d offset: %w", idx, err)
+		}
+	}
+
+	return nil
+}
+
+func (m *moduleContext) validateStart(module *Module) error {
+	if module.Start == nil {
+		return nil
+	}
+
+	funcIdx := *module.Start
+	if funcIdx >= len(m.functions) {
+		return moduleError("start: %w: %d", ErrUnknownFunction, funcIdx)
+	}
+
+	signature := m.functions[funcIdx].Signature
+	if len(signature.ParamsTypes) > 0 || len(signature.ResultsTypes) > 0 {
+		return moduleError("%w, got %s", ErrInvalidStartFunction, signature)
+	}
+
+	return nil
+}
+
+func (m *moduleContext) validateExports(module *Module) error {
+	names := make(map[string]struct{}, len(module.Exports))
+
+	for _, export := range module.Exports {
+		if _, ok := names[export.Name]; ok {
+			return moduleError("%w: %q", ErrDuplicateExport, export.Name)
+		}
+		names[export.Name] = struct{}{}
+
+		var kind error
+		var defined int
+		switch export.Type {
+		case ExportedFunc:
+			kind, defined = ErrUnknownFunction, len(m.functions)
+		case ExportedTable:
+			kind, defined = ErrUnknownTable, len(m.tables)
+		case ExportedMem:
+			kind, defined = ErrUnknownMemory, len(m.memories)
+		case ExportedGlobal:
+			kind, defined = ErrUnknownGlobal, len(m.globals)
+		default:
+			return moduleError("export %q: %w: 0x%x", export.Name, ErrUnknownExportKind, byte(export.Type))
+		}
+
+		if export.Index >= defined {
+			return moduleError("export %q: %w: %d", export.Name, kind, export.Index)
+		}
+	}
+
+	return nil
+}
+
+func (m *moduleContext) validateCode(module *Module) error {
+	defined := module.DefinedFuncs()
+	imported := len(module.Funcs) - len(defined)
+
+	for idx, function := range defined {
+		funcIdx := imported + idx
+		if function.Code == nil {
+			return moduleError("%w: function %d without body", ErrFunctionCodeMismatch, funcIdx)
+		}
+
+		if err := m.validateFunction(funcIdx, function.Code); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateConstantExpression checks the expression only uses constant instructions
+// and produces a single value of the expected type, global.get can only refer to
+// immutable imported globals
+func (m *moduleContext) validateConstantExpression(expr []byte, expected Type) error {
+	reader := bytes.NewReader(expr)
+	stack := make([]Type, 0, 1)
+
+	for reader.Len() > 0 {
+		inst, err := decodeInstruction(reader, len(expr)-reader.Len())
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidConstantExpression, err)
+		}
+
+		switch inst.Opcode {
+		case opcodes.End:
+			if len(stack) != 1 || stack[0].SpecByte != expected.SpecByte {
+				return fmt.Errorf("%w: expected [%s], got %s",
+					ErrTypeMismatch, expected, typesString(stack))
+			}
+
+			return nil
+		case opcodes.I32Const:
+			stack = append(stack, I32)
+		case opcodes.I64Const:
+			stack = append(stack, I64)
+		case opcodes.F32Const:
+			stack = append(stack, F32)
+		case opcodes.F64Const:
+			stack = append(stack, F64)
+		case opcodes.GlobalGet:
+			if int(inst.Index) >= m.importedGlobals {
+				return fmt.Errorf("%w: %d", ErrUnknownGlobal, inst.Index)
+			}
+
+			global := m.globals[inst.Index]
+			if global.Mutable {
+				return fmt.Errorf("%w: global %d is mutable", ErrConstantExpressionRequired, inst.Index)
+			}
+
+			stack = append(stack, global.ValType)
+		case opcodes.RefNull:
+			stack = append(stack, inst.Types[0])
+		case opcodes.RefFunc:
+			if int(inst.Index) >= len(m.functions) {
+				return fmt.Errorf("%w: %d", ErrUnknownFunction, inst.Index)
+			}
+
+			stack = append(stack, Type{SpecType: RefType, SpecByte: FUNC_REF_TYPE})
+		default:
+			return fmt.Errorf("%w: %s", ErrConstantExpressionRequired, inst.Opcode)
+		}
+	}
+
+	return fmt.Errorf("%w: missing end", ErrInvalidConstantExpression)
+}
+
+type controlFrame struct {
+	opcode     opcodes.OpCode
+	startTypes []Type
+	endTypes   []Type
+	// height is the operand stack size when the frame was pushed
+	height      int
+	unreachable bool
+}
+
+// functionValidator implements the validation algorithm described
+// in the spec appendix, keeping track of the operand types and of
+// the structured instructions that are still open
+type functionValidator struct {
+	module   *moduleContext
+	locals   []Type
+	results  []Type
+	operands []Type
+	controls []controlFrame
+}
+
+func (m *moduleContext) validateFunction(funcIdx int, code *CodeParser) error {
+	signature := m.functions[funcIdx].Signature
+
+	v := &functionValidator{
+		module:  m,
+		locals:  append(append([]Type(nil), signature.ParamsTypes...), code.Locals...),
+		results: signature.ResultsTypes,
+	}
+
+	// the function body is validated as an implicit block
+	v.pushControl(opcodes.Block, nil, signature.ResultsTypes)
+
+	for idx := range code.Instructions {
+		inst := &code.Instructions[idx]
+		if len(v.controls) == 0 {
+			return &ValidationError{FuncIndex: funcIdx, Offset: inst.Offset,
+				Err: fmt.Errorf("%w: instructions after the function end", ErrMalformedBody)}
+		}
+
+		if err := v.validateInstruction(inst); err != nil {
+			return &ValidationError{FuncIndex: funcIdx, Offset: inst.Offset,
+				Err: fmt.Errorf("%s: %w", inst.Opcode, err)}
+		}
+	}
+
+	if len(v.controls) > 0 {
+		return &ValidationError{FuncIndex: funcIdx, Offset: len(code.Body),
+			Err: fmt.Errorf("%w: missing end", ErrMalformedBody)}
+	}
+
+	return nil
+}
+
+func (v *functionValidator) pushOperand(t Type) {
+	v.operands = append(v.operands, t)
+}
+
+func (v *functionValidator) pushOperands(types []Type) {
+	v.operands = append(v.operands, types...)
+}
+
+func (v *functionValidator) popOperand() (Type, error) {
+	frame := v.controls[len(v.controls)-1]
+	if len(v.operands) == frame.height {
+		if frame.unreachable {
+			return unknownType, nil
+		}
+
+		return Type{}, fmt.Errorf("%w: expected a value but the stack is empty", ErrTypeMismatch)
+	}
+
+	operand := v.operands[len(v.operands)-1]
+	v.operands = v.operands[:len(v.operands)-1]
+	return operand, nil
+}
+
+func (v *functionValidator) popExpected(expected Type) (Type, error) {
+	actual, err := v.popOperand()
+	if err != nil {
+		return Type{}, fmt.Errorf("%w: expected %s but the stack is empty", ErrTypeMismatch, expected)
+	}
+
+	if actual == unknownType {
+		return expected, nil
+	}
+
+	if expected != unknownType && actual.SpecByte != expected.SpecByte {
+		return Type{}, fmt.Errorf("%w: expected %s, got %s", ErrTypeMismatch, expected, actual)
+	}
+
+	return actual, nil
+}
+
+func (v *functionValidator) popOperands(types []Type) error {
+	for idx := len(types) - 1; idx >= 0; idx-- {
+		if _, err := v.popExpected(types[idx]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (v *functionValidator) pushControl(op opcodes.OpCode, in, out []Type) {
+	v.controls = append(v.controls, controlFrame{
+		opcode:     op,
+		startTypes: in,
+		endTypes:   out,
+		height:     len(v.operands),
+	})
+
+	v.pushOperands(in)
+}
+
+func (v *functionValidator) popControl() (controlFrame, error) {
+	if len(v.controls) == 0 {
+		return controlFrame{}, fmt.Errorf("%w: unbalanced end", ErrMalformedBody)
+	}
+
+	frame := v.controls[len(v.controls)-1]
+	if err := v.popOperands(frame.endTypes); err != nil {
+		return controlFrame{}, err
+	}
+
+	if len(v.operands) != frame.height {
+		return controlFrame{}, fmt.Errorf("%w: %d values remaining on the stack at the end of the block",
+			ErrTypeMismatch, len(v.operands)-frame.height)
+	}
+
+	v.controls = v.controls[:len(v.controls)-1]
+	return frame, nil
+}
+
+// markUnreachable drops the operands of the current frame, the following
+// instructions are validated against a polymorphic stack
+func (v *functionValidator) markUnreachable() {
+	frame := &v.controls[len(v.controls)-1]
+	v.operands = v.operands[:frame.height]
+	frame.unreachable = true
+}
+
+// labelTypes returns the types a branch to the label carries, the
+// params for a loop as it jumps to its start, otherwise the results
+func (v *functionValidator) labelTypes(label uint32) ([]Type, error) {
+	if int(label) >= len(v.controls) {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownLabel, label)
+	}
+
+	frame := v.controls[len(v.controls)-1-int(label)]
+	if frame.opcode == opcodes.Loop {
+		return frame.startTypes, nil
+	}
+
+	return frame.endTypes, nil
+}
+
+func (v *functionValidator) signature(typeIdx uint32) (*FunctionSignatureParser, error) {
+	if int(typeIdx) >= len(v.module.types) {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownType, typeIdx)
+	}
+
+	return v.module.types[typeIdx], nil
+}
+
+// blockSignature returns the params and results of the block type
+func (v *functionValidator) blockSignature(inst *Instruction) ([]Type, []Type, error) {
+	if !inst.TypeIndexed {
+		return nil, inst.Results, nil
+	}
+
+	signature, err := v.signature(inst.Index)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return signature.ParamsTypes, signature.ResultsTypes, nil
+}
+
+func (v *functionValidator) global(globalIdx uint32) (*GlobalType, error) {
+	if int(globalIdx) >= len(v.module.globals) {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownGlobal, globalIdx)
+	}
+
+	return v.module.globals[globalIdx], nil
+}
+
+func (v *functionValidator) requireMemory() error {
+	if len(v.module.memories) == 0 {
+		return fmt.Errorf("%w: 0", ErrUnknownMemory)
+	}
+
+	return nil
+}
+
+func (v *functionValidator) requireDataSegment(dataIdx uint32) error {
+	if v.module.dataCount == nil {
+		return ErrDataCountRequired
+	}
+
+	if dataIdx >= *v.module.dataCount {
+		return fmt.Errorf("%w: %d", ErrUnknownDataSegment, dataIdx)
+	}
+
+	return nil
+}
+
+func (v *functionValidator) validateInstruction(inst *Instruction) error {
+	switch inst.Opcode {
+	case opcodes.Unreachable:
+		v.markUnreachable()
+	case opcodes.Nop:
+	case opcodes.Block, opcodes.Loop, opcodes.If:
+		params, results, err := v.blockSignature(inst)
+		if err != nil {
+			return err
+		}
+
+		if inst.Opcode == opcodes.If {
+			if _, err := v.popExpected(I32); err != nil {
+				return err
+			}
+		}
+
+		if err := v.popOperands(params); err != nil {
+			return err
+		}
+
+		v.pushControl(inst.Opcode, params, results)
+	case opcodes.Else:
+		frame, err := v.popControl()
+		if err != nil {
+			return err
+		}
+
+		if frame.opcode != opcodes.If {
+			return fmt.Errorf("%w: else without if", ErrMalformedBody)
+		}
+
+		v.pushControl(opcodes.Else, frame.startTypes, frame.endTypes)
+	case opcodes.End:
+		frame, err := v.popControl()
+		if err != nil {
+			return err
+		}
+
+		// without an else branch the if must leave the stack as it found it
+		if frame.opcode == opcodes.If && !typesEqual(frame.startTypes, frame.endTypes) {
+			return fmt.Errorf("%w: if without else must not produce %s",
+				ErrTypeMismatch, typesString(frame.endTypes))
+		}
+
+		v.pushOperands(frame.endTypes)
+	case opcodes.Br:
+		types, err := v.labelTypes(inst.Index)
+		if err != nil {
+			return err
+		}
+
+		if err := v.popOperands(types); err != nil {
+			return err
+		}
+
+		v.markUnreachable()
+	case opcodes.BrIf:
+		types, err := v.labelTypes(inst.Index)
+		if err != nil {
+			return err
+		}
+
+		if _, err := v.popExpected(I32); err != nil {
+			return err
+		}
+
+		if err := v.popOperands(types); err != nil {
+			return err
+		}
+
+		v.pushOperands(types)
+	case opcodes.BrTable:
+		defaultTypes, err := v.labelTypes(inst.Index)
+		if err != nil {
+			return err
+		}
+
+		if _, err := v.popExpected(I32); err != nil {
+			return err
+		}
+
+		// every label must take the operands the default label takes
+		for _, label := range inst.Labels {
+			types, err := v.labelTypes(label)
+			if err != nil {
+				return err
+			}
+
+			if len(types) != len(defaultTypes) {
+				return fmt.Errorf("%w: label %d expects %s but the default label expects %s",
+					ErrTypeMismatch, label, typesString(types), typesString(defaultTypes))
+			}
+
+			popped := make([]Type, len(types))
+			for idx := len(types) - 1; idx >= 0; idx-- {
+				if popped[idx], err = v.popExpected(types[idx]); err != nil {
+					return err
+				}
+			}
+
+			v.pushOperands(popped)
+		}
+
+		if err := v.popOperands(defaultTypes); err != nil {
+			return err
+		}
+
+		v.markUnreachable()
+	case opcodes.Return:
+		if err := v.popOperands(v.results); err != nil {
+			return err
+		}
+
+		v.markUnreachable()
+	case opcodes.Call:
+		if int(inst.Index) >= len(v.module.functions) {
+			return fmt.Errorf("%w: %d", ErrUnknownFunction, inst.Index)
+		}
+
+		signature := v.module.functions[inst.Index].Signature
+		if err := v.popOperands(signature.ParamsTypes); err != nil {
+			return err
+		}
+
+		v.pushOperands(signature.ResultsTypes)
+	case opcodes.CallIndirect:
+		if int(inst.Table) >= len(v.module.tables) {
+			return fmt.Errorf("%w: %d", ErrUnknownTable, inst.Table)
+		}
+
+		if elemType := v.module.tables[inst.Table].ElemType; elemType.SpecByte != FUNC_REF_TYPE {
+			return fmt.Errorf("%w: table %d holds %s, expected funcref",
+				ErrTypeMismatch, inst.Table, elemType)
+		}
+
+		signature, err := v.signature(inst.Index)
+		if err != nil {
+			return err
+		}
+
+		if _, err := v.popExpected(I32); err != nil {
+			return err
+		}
+
+		if err := v.popOperands(signature.ParamsTypes); err != nil {
+			return err
+		}
+
+		v.pushOperands(signature.ResultsTypes)
+	case opcodes.Drop:
+		if _, err := v.popOperand(); err != nil {
+			return err
+		}
+	case opcodes.Select:
+		if _, err := v.popExpected(I32); err != nil {
+			return err
+		}
+
+		first, err := v.popOperand()
+		if err != nil {
+			return err
+		}
+
+		second, err := v.popOperand()
+		if err != nil {
+			return err
+		}
+
+		if (first != unknownType && first.SpecType != NumType) ||
+			(second != unknownType && second.SpecType != NumType) {
+			return fmt.Errorf("%w: untyped select expects numeric operands", ErrTypeMismatch)
+		}
+
+		if first != unknownType && second != unknownType && first.SpecByte != second.SpecByte {
+			return fmt.Errorf("%w: select operands %s and %s", ErrTypeMismatch, second, first)
+		}
+
+		if first == unknownType {
+			first = second
+		}
+
+		v.pushOperand(first)
+	case opcodes.SelectTyped:
+		if len(inst.Types) != 1 {
+			return fmt.Errorf("%w: select expects a single result type, got %d",
+				ErrTypeMismatch, len(inst.Types))
+		}
+
+		if err := v.popOperands([]Type{inst.Types[0], inst.Types[0], I32}); err != nil {
+			return err
+		}
+
+		v.pushOperand(inst.Types[0])
+	case opcodes.LocalGet, opcodes.LocalSet, opcodes.LocalTee:
+		if int(inst.Index) >= len(v.locals) {
+			return fmt.Errorf("%w: %d", ErrUnknownLocal, inst.Index)
+		}
+
+		local := v.locals[inst.Index]
+		if inst.Opcode != opcodes.LocalGet {
+			if _, err := v.popExpected(local); err != nil {
+				return err
+			}
+		}
+
+		if inst.Opcode != opcodes.LocalSet {
+			v.pushOperand(local)
+		}
+	case opcodes.GlobalGet:
+		global, err := v.global(inst.Index)
+		if err != nil {
+			return err
+		}
+
+		v.pushOperand(global.ValType)
+	case opcodes.GlobalSet:
+		global, err := v.global(inst.Index)
+		if err != nil {
+			return err
+		}
+
+		if !global.Mutable {
+			return fmt.Errorf("%w: %d", ErrImmutableGlobal, inst.Index)
+		}
+
+		if _, err := v.popExpected(global.ValType); err != nil {
+			return err
+		}
+	case opcodes.I32Load, opcodes.I64Load, opcodes.F32Load, opcodes.F64Load,
+		opcodes.I32Load8S, opcodes.I32Load8U, opcodes.I32Load16S, opcodes.I32Load16U,
+		opcodes.I64Load8S, opcodes.I64Load8U, opcodes.I64Load16S, opcodes.I64Load16U,
+		opcodes.I64Load32S, opcodes.I64Load32U:
+		valueType, err := v.validateMemArg(inst)
+		if err != nil {
+			return err
+		}
+
+		if _, err := v.popExpected(I32); err != nil {
+			return err
+		}
+
+		v.pushOperand(valueType)
+	case opcodes.I32Store, opcodes.I64Store, opcodes.F32Store, opcodes.F64Store,
+		opcodes.I32Store8, opcodes.I32Store16,
+		opcodes.I64Store8, opcodes.I64Store16, opcodes.I64Store32:
+		valueType, err := v.validateMemArg(inst)
+		if err != nil {
+			return err
+		}
+
+		if err := v.popOperands([]Type{I32, valueType}); err != nil {
+			return err
+		}
+	case opcodes.MemorySize:
+		if err := v.requireMemory(); err != nil {
+			return err
+		}
+
+		v.pushOperand(I32)
+	case opcodes.MemoryGrow:
+		if err := v.requireMemory(); err != nil {
+			return err
+		}
+
+		if _, err := v.popExpected(I32); err != nil {
+			return err
+		}
+
+		v.pushOperand(I32)
+	case opcodes.MemoryInit:
+		if err := v.requireMemory(); err != nil {
+			return err
+		}
+
+		if err := v.requireDataSegment(inst.Index); err != nil {
+			return err
+		}
+
+		if err := v.popOperands([]Type{I32, I32, I32}); err != nil {
+			return err
+		}
+	case opcodes.DataDrop:
+		if err := v.requireDataSegment(inst.Index); err != nil {
+			return err
+		}
+	case opcodes.I32Const:
+		v.pushOperand(I32)
+	case opcodes.I64Const:
+		v.pushOperand(I64)
+	case opcodes.F32Const:
+		v.pushOperand(F32)
+	case opcodes.F64Const:
+		v.pushOperand(F64)
+	default:
+		numeric, ok := numericInstructions[inst.Opcode]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownInstruction, inst.Opcode)
+		}
+
+		if err := v.popOperands(numeric.operands); err != nil {
+			return err
+		}
+
+		v.pushOperand(numeric.result)
+	}
+
+	return nil
+}
+
+// validateMemArg checks the module has a memory and the alignment of the
+// access, returning the type of the value loaded or stored by the instruction
+func (v *functionValidator) validateMemArg(inst *Instruction) (Type, error) {
+	if err := v.requireMemory(); err != nil {
+		return Type{}, err
+	}
+
+	var width uint64
+	var valueType Type
+
+	switch inst.Opcode {
+	case opcodes.I32Load8S, opcodes.I32Load8U, opcodes.I32Store8:
+		width, valueType = 1, I32
+	case opcodes.I64Load8S, opcodes.I64Load8U, opcodes.I64Store8:
+		width, valueType = 1, I64
+	case opcodes.I32Load16S, opcodes.I32Load16U, opcodes.I32Store16:
+		width, valueType = 2, I32
+	case opcodes.I64Load16S, opcodes.I64Load16U, opcodes.I64Store16:
+		width, valueType = 2, I64
+	case opcodes.I32Load, opcodes.I32Store:
+		width, valueType = 4, I32
+	case opcodes.F32Load, opcodes.F32Store:
+		width, valueType = 4, F32
+	case opcodes.I64Load32S, opcodes.I64Load32U, opcodes.I64Store32:
+		width, valueType = 4, I64
+	case opcodes.I64Load, opcodes.I64Store:
+		width, valueType = 8, I64
+	case opcodes.F64Load, opcodes.F64Store:
+		width, valueType = 8, F64
+	}
+
+	if inst.MemArg.Align >= 64 || uint64(1)<<inst.MemArg.Align > width {
+		return Type{}, fmt.Errorf("%w: 2^%d", ErrAlignmentTooLarge, inst.MemArg.Align)
+	}
+
+	return valueType, nil
+}
+
+func typesEqual(a, b []Type) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for idx := range a {
+		if a[idx].SpecByte != b[idx].SpecByte {
+			return false
+		}
+	}
+
+	return true
+}
+
+func typesString(types []Type) string {
+	names := make([]string, len(types))
+	for idx, t := range types {
+		names[idx] = t.String()
+	}
+
+	return "[" + strings.Join(names, " ") + "]"
+}
@@ -0,0 +1,269 @@
+package parser_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/EclesioMeloJunior/wasvm/parser"
+
+	"github.com/stretchr/testify/require"
+)
+
+func section(id byte, contents ...byte) []byte {
+	return append([]byte{id, byte(len(contents))}, contents...)
+}
+
+// singleFunctionModule builds a module with a single function of
+// type func(params) -> (results), the extra sections are placed
+// before or after the code section according to their id
+func singleFunctionModule(params, results, body []byte, extra ...[]byte) []byte {
+	functype := append([]byte{0x01, 0x60, byte(len(params))}, params...)
+	functype = append(functype, byte(len(results)))
+	functype = append(functype, results...)
+
+	code := append([]byte{0x00}, body...)
+	code = append([]byte{0x01, byte(len(code))}, code...)
+
+	module := []byte{0x00, 0x61, 0x73, 0x6D, 0x01, 0x00, 0x00, 0x00}
+	module = append(module, section(parser.TypeSection, functype...)...)
+	module = append(module, section(parser.FunctionSection, 0x01, 0x00)...)
+	for _, s := range extra {
+		if s[0] < parser.CodeSection {
+			module = append(module, s...)
+		}
+	}
+
+	module = append(module, section(parser.CodeSection, code...)...)
+	for _, s := range extra {
+		if s[0] > parser.CodeSection {
+			module = append(module, s...)
+		}
+	}
+
+	return module
+}
+
+func TestValidate_Resources(t *testing.T) {
+	resources, err := filepath.Glob("../resources/*.wasm")
+	require.NoError(t, err)
+	require.NotEmpty(t, resources)
+
+	for _, resource := range resources {
+		bp, err := parser.BinaryFormat(resource)
+		require.NoError(t, err)
+		require.NoError(t, parser.Validate(bp), resource)
+	}
+}
+
+func TestValidate_FunctionBodies(t *testing.T) {
+	i32 := []byte{parser.I32_NUM_TYPE}
+
+	tests := map[string]struct {
+		params, results []byte
+		body            []byte
+		expectedErr     error
+		expectedOffset  int
+	}{
+		"valid constant": {
+			results: i32,
+			body:    []byte{0x41, 0x01, 0x0B},
+		},
+		"polymorphic stack after unreachable": {
+			results: i32,
+			body:    []byte{0x00, 0x6A, 0x0B},
+		},
+		"select between locals": {
+			params:  []byte{parser.I32_NUM_TYPE, parser.I32_NUM_TYPE},
+			results: i32,
+			body:    []byte{0x20, 0x00, 0x20, 0x01, 0x41, 0x01, 0x1B, 0x0B},
+		},
+		"missing result": {
+			results:        i32,
+			body:           []byte{0x0B},
+			expectedErr:    parser.ErrTypeMismatch,
+			expectedOffset: 0,
+		},
+		"i64 operand for i32.add": {
+			results:        i32,
+			body:           []byte{0x42, 0x01, 0x41, 0x01, 0x6A, 0x0B},
+			expectedErr:    parser.ErrTypeMismatch,
+			expectedOffset: 4,
+		},
+		"value left on the stack": {
+			body:           []byte{0x41, 0x01, 0x0B},
+			expectedErr:    parser.ErrTypeMismatch,
+			expectedOffset: 2,
+		},
+		"if with result and without else": {
+			results:        i32,
+			body:           []byte{0x41, 0x01, 0x04, 0x7F, 0x41, 0x02, 0x0B, 0x0B},
+			expectedErr:    parser.ErrTypeMismatch,
+			expectedOffset: 6,
+		},
+		"block with the params and results of a type": {
+			params:  i32,
+			results: i32,
+			// local.get 0 block (type 0) end
+			body: []byte{0x20, 0x00, 0x02, 0x00, 0x0B, 0x0B},
+		},
+		"block param of the wrong type": {
+			params:         i32,
+			results:        i32,
+			body:           []byte{0x42, 0x01, 0x02, 0x00, 0x0B, 0x0B},
+			expectedErr:    parser.ErrTypeMismatch,
+			expectedOffset: 2,
+		},
+		"block with an unknown type": {
+			body:           []byte{0x02, 0x05, 0x0B, 0x0B},
+			expectedErr:    parser.ErrUnknownType,
+			expectedOffset: 0,
+		},
+		"br carries the block result": {
+			results: i32,
+			// block (result i32) i32.const 1 br 0 end
+			body: []byte{0x02, 0x7F, 0x41, 0x01, 0x0C, 0x00, 0x0B, 0x0B},
+		},
+		"br without the block result": {
+			results:        i32,
+			body:           []byte{0x02, 0x7F, 0x0C, 0x00, 0x0B, 0x0B},
+			expectedErr:    parser.ErrTypeMismatch,
+			expectedOffset: 2,
+		},
+		"br to a loop carries its params": {
+			params:  i32,
+			results: i32,
+			// local.get 0 loop (type 0) br 0 end
+			body: []byte{0x20, 0x00, 0x03, 0x00, 0x0C, 0x00, 0x0B, 0x0B},
+		},
+		"br_if keeps the label operands": {
+			results: i32,
+			body:    []byte{0x41, 0x01, 0x41, 0x00, 0x0D, 0x00, 0x0B},
+		},
+		"br_table to the function body": {
+			results: i32,
+			body:    []byte{0x41, 0x05, 0x41, 0x00, 0x0E, 0x01, 0x00, 0x00, 0x0B},
+		},
+		"br_table labels with different arities": {
+			results: i32,
+			// block (result i32) block i32.const 0 i32.const 0 br_table 0 1 end end
+			body:           []byte{0x02, 0x7F, 0x02, 0x40, 0x41, 0x00, 0x41, 0x00, 0x0E, 0x01, 0x00, 0x01, 0x0B, 0x0B, 0x0B},
+			expectedErr:    parser.ErrTypeMismatch,
+			expectedOffset: 8,
+		},
+		"unknown label": {
+			body:           []byte{0x0C, 0x01, 0x0B},
+			expectedErr:    parser.ErrUnknownLabel,
+			expectedOffset: 0,
+		},
+		"unknown local": {
+			params:         i32,
+			results:        i32,
+			body:           []byte{0x20, 0x02, 0x0B},
+			expectedErr:    parser.ErrUnknownLocal,
+			expectedOffset: 0,
+		},
+		"unknown function": {
+			body:           []byte{0x10, 0x05, 0x0B},
+			expectedErr:    parser.ErrUnknownFunction,
+			expectedOffset: 0,
+		},
+		"load without memory": {
+			results:        i32,
+			body:           []byte{0x41, 0x00, 0x28, 0x02, 0x00, 0x0B},
+			expectedErr:    parser.ErrUnknownMemory,
+			expectedOffset: 2,
+		},
+		"missing end": {
+			results:        i32,
+			body:           []byte{0x41, 0x01},
+			expectedErr:    parser.ErrMalformedBody,
+			expectedOffset: 2,
+		},
+	}
+
+	for tname, tt := range tests {
+		tt := tt
+		t.Run(tname, func(t *testing.T) {
+			bp, err := parser.Decode(singleFunctionModule(tt.params, tt.results, tt.body))
+			require.NoError(t, err)
+
+			err = parser.Validate(bp)
+			if tt.expectedErr == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, tt.expectedErr)
+
+			var validationErr *parser.ValidationError
+			require.True(t, errors.As(err, &validationErr))
+			require.Equal(t, 0, validationErr.FuncIndex)
+			require.Equal(t, tt.expectedOffset, validationErr.Offset)
+		})
+	}
+}
+
+func TestValidate_Module(t *testing.T) {
+	body := []byte{0x0B}
+
+	tests := map[string]struct {
+		sections    [][]byte
+		expectedErr error
+	}{
+		"duplicate export": {
+			sections: [][]byte{
+				section(parser.ExportSection, 0x02, 0x01, 'f', 0x00, 0x00, 0x01, 'f', 0x00, 0x00),
+			},
+			expectedErr: parser.ErrDuplicateExport,
+		},
+		"export unknown function": {
+			sections: [][]byte{
+				section(parser.ExportSection, 0x01, 0x01, 'f', 0x00, 0x01),
+			},
+			expectedErr: parser.ErrUnknownFunction,
+		},
+		"export unknown kind": {
+			sections: [][]byte{
+				section(parser.ExportSection, 0x01, 0x01, 'f', 0x04, 0x00),
+			},
+			expectedErr: parser.ErrUnknownExportKind,
+		},
+		"memory min greater than max": {
+			sections: [][]byte{
+				section(parser.MemorySection, 0x01, 0x01, 0x02, 0x01),
+			},
+			expectedErr: parser.ErrInvalidLimits,
+		},
+		"initializer reading a defined global": {
+			sections: [][]byte{
+				// (global (mut i32) (i32.const 0)) (global i32 (global.get 0))
+				section(parser.GlobalSection, 0x02,
+					0x7F, 0x01, 0x41, 0x00, 0x0B,
+					0x7F, 0x00, 0x23, 0x00, 0x0B),
+			},
+			expectedErr: parser.ErrUnknownGlobal,
+		},
+		"data segment without memory": {
+			sections: [][]byte{
+				section(parser.DataSection, 0x01, 0x00, 0x41, 0x00, 0x0B, 0x01, 'a'),
+			},
+			expectedErr: parser.ErrUnknownMemory,
+		},
+	}
+
+	for tname, tt := range tests {
+		tt := tt
+		t.Run(tname, func(t *testing.T) {
+			bp, err := parser.Decode(singleFunctionModule(nil, nil, body, tt.sections...))
+			require.NoError(t, err)
+
+			err = parser.Validate(bp)
+			require.ErrorIs(t, err, tt.expectedErr)
+
+			var validationErr *parser.ValidationError
+			require.True(t, errors.As(err, &validationErr))
+			require.Equal(t, -1, validationErr.FuncIndex)
+		})
+	}
+}
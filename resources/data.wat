@@ -0,0 +1,22 @@
+(module
+    (memory 1)
+
+    (data (i32.const 16) "hello")
+    (data $greet "world!")
+
+    (func (export "load8_u") (param i32) (result i32)
+        local.get 0
+        i32.load8_u
+    )
+
+    (func (export "init") (param i32) (param i32) (param i32)
+        local.get 0
+        local.get 1
+        local.get 2
+        memory.init $greet
+    )
+
+    (func (export "drop")
+        data.drop $greet
+    )
+)
@@ -0,0 +1,29 @@
+(module
+  (func (export "hypot") (param f64) (param f64) (result f64)
+    local.get 0
+    local.get 0
+    f64.mul
+    local.get 1
+    local.get 1
+    f64.mul
+    f64.add
+    f64.sqrt
+    )
+
+  (func (export "clamp") (param f32) (param f32) (param f32) (result f32)
+    (local f32)
+    local.get 0
+    local.get 1
+    f32.max
+    local.set 3
+    local.get 3
+    local.get 2
+    f32.min
+    )
+
+  (func (export "is_nan") (param f64) (result i32)
+    local.get 0
+    local.get 0
+    f64.ne
+    )
+)
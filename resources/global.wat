@@ -0,0 +1,20 @@
+(module
+    (import "env" "base" (global $base i32))
+
+    (global $counter (mut i32) (global.get $base))
+    (global $big i64 (i64.const 1234567890123))
+
+    (func (export "increment") (result i32)
+        global.get $counter
+        i32.const 1
+        i32.add
+        global.set $counter
+        global.get $counter
+    )
+
+    (func (export "big") (result i64)
+        global.get $big
+    )
+
+    (export "counter" (global $counter))
+)
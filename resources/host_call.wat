@@ -0,0 +1,13 @@
+(module
+    (import "env" "add" (func $add (param i32) (param i32) (result i32)))
+    (import "env" "log" (func $log (param i32)))
+
+    (func (export "log_sum") (param i32) (param i32)
+        local.get 0
+        local.get 1
+        call $add
+        call $log
+    )
+
+    (export "add" (func $add))
+)
@@ -0,0 +1,31 @@
+(module
+  (func $fac (export "fac") (param i64) (result i64)
+    local.get 0
+    i64.const 1
+    i64.le_s
+    if (result i64)
+      i64.const 1
+    else
+      local.get 0
+      local.get 0
+      i64.const 1
+      i64.sub
+      call $fac
+      i64.mul
+    end
+    )
+
+  (func (export "div")
+    (param i64) (param i64) (result i64)
+    local.get 0
+    local.get 1
+    i64.div_s
+    )
+
+  (func (export "rotl")
+    (param i64) (param i64) (result i64)
+    local.get 0
+    local.get 1
+    i64.rotl
+    )
+)
@@ -0,0 +1,23 @@
+(module
+    (import "env" "unused" (func $unused (param i32)))
+
+    (func $double (param i32) (result i32)
+        local.get 0
+        local.get 0
+        i32.add
+    )
+
+    (func $sub (param i32) (param i32) (result i32)
+        local.get 0
+        local.get 1
+        i32.sub
+    )
+
+    (func (export "quadruple_minus") (param i32) (param i32) (result i32)
+        local.get 0
+        call $double
+        call $double
+        local.get 1
+        call $sub
+    )
+)
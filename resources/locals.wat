@@ -0,0 +1,31 @@
+(module
+  ;; returns the greatest param, the if branch updates $max
+  ;; showing the branches share the locals of the function
+  (func (export "max") (param $a i32) (param $b i32) (result i32)
+    (local $max i32)
+    local.get $b
+    local.set $max
+
+    local.get $b
+    local.get $a
+    i32.lt_s
+    if (result i32)
+      local.get $a
+      local.tee $max
+    else
+      local.get $b
+    end
+    drop
+
+    local.get $max
+  )
+
+  ;; declared locals are zero initialized
+  (func (export "zero_i64") (result i64) (local i32 i64)
+    local.get 1
+  )
+
+  (func (export "zero_f64") (result f64) (local $x f32) (local $y f64)
+    local.get $y
+  )
+)
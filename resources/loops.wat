@@ -0,0 +1,67 @@
+(module
+  (func (export "fac") (param i64) (result i64)
+    (local i64)
+    i64.const 1
+    local.set 1
+    block $done
+      loop $next
+        local.get 0
+        i64.const 2
+        i64.lt_u
+        br_if $done
+        local.get 1
+        local.get 0
+        i64.mul
+        local.set 1
+        local.get 0
+        i64.const 1
+        i64.sub
+        local.set 0
+        br $next
+      end
+    end
+    local.get 1
+  )
+
+  (func (export "fib") (param i32) (result i32)
+    (local i32 i32)
+    i32.const 1
+    i32.const 0
+    block $done (param i32 i32) (result i32 i32)
+      loop $step (param i32 i32) (result i32 i32)
+        local.get 0
+        i32.eqz
+        br_if $done
+        local.set 1
+        local.tee 2
+        local.get 1
+        i32.add
+        local.get 2
+        local.get 0
+        i32.const 1
+        i32.sub
+        local.set 0
+        br $step
+      end
+    end
+    local.set 1
+    drop
+    local.get 1
+  )
+
+  (func (export "classify") (param i32) (result i32)
+    block $default
+      block $one
+        block $zero
+          local.get 0
+          br_table $zero $one $default
+        end
+        i32.const 100
+        return
+      end
+      i32.const 101
+      return
+    end
+    i32.const -1
+  )
+)
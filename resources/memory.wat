@@ -0,0 +1,54 @@
+(module
+    (memory 1 2)
+
+    (func (export "store_i32") (param i32) (param i32)
+        local.get 0
+        local.get 1
+        i32.store
+    )
+
+    (func (export "load_i32") (param i32) (result i32)
+        local.get 0
+        i32.load
+    )
+
+    (func (export "load8_s") (param i32) (result i32)
+        local.get 0
+        i32.load8_s
+    )
+
+    (func (export "load8_u") (param i32) (result i32)
+        local.get 0
+        i32.load8_u
+    )
+
+    (func (export "load16_s_offset") (param i32) (result i32)
+        local.get 0
+        i32.load16_s offset=2
+    )
+
+    (func (export "store_i64") (param i32) (param i64)
+        local.get 0
+        local.get 1
+        i64.store
+    )
+
+    (func (export "load_i64") (param i32) (result i64)
+        local.get 0
+        i64.load
+    )
+
+    (func (export "load32_s") (param i32) (result i64)
+        local.get 0
+        i64.load32_s
+    )
+
+    (func (export "size") (result i32)
+        memory.size
+    )
+
+    (func (export "grow") (param i32) (result i32)
+        local.get 0
+        memory.grow
+    )
+)
@@ -0,0 +1,38 @@
+(module
+  (type $pair (func (param i32 i32) (result i32 i32)))
+
+  (func $swap (export "swap") (type $pair)
+    local.get 1
+    local.get 0
+  )
+
+  (func (export "sum_and_diff") (param i32 i32) (result i32 i32)
+    local.get 0
+    local.get 1
+    block (type $pair)
+      i32.add
+      local.get 0
+      local.get 1
+      i32.sub
+    end
+  )
+
+  (func (export "swap_if") (param i32 i32 i32) (result i32 i32)
+    local.get 0
+    local.get 1
+    i32.const 0
+    local.get 2
+    i32.lt_s
+    if (type $pair)
+      call $swap
+    end
+  )
+
+  (func (export "double") (param i32) (result i32)
+    local.get 0
+    loop (param i32) (result i32)
+      i32.const 2
+      i32.mul
+    end
+  )
+)
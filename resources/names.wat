@@ -0,0 +1,30 @@
+(module $math
+    (memory 1)
+
+    (func $fac (export "fac") (param $n i32) (result i32)
+        local.get $n
+        i32.const 1
+        i32.lt_s
+        if (result i32)
+            i32.const 1
+        else
+            local.get $n
+            local.get $n
+            i32.const 1
+            i32.sub
+            call $fac
+            i32.mul
+        end
+    )
+
+    (func $oob (result i32)
+        i32.const -1
+        i32.load
+    )
+
+    (func $outer (export "outer") (result i32)
+        call $oob
+    )
+
+    (@custom "wasvm" "custom section contents")
+)
@@ -19,4 +19,18 @@
         local.get 1
         i32.mul
     )
+
+    (func (export "div")
+        (param i32) (param i32) (result i32)
+        local.get 0
+        local.get 1
+        i32.div_s
+    )
+
+    (func (export "rem")
+        (param i32) (param i32) (result i32)
+        local.get 0
+        local.get 1
+        i32.rem_u
+    )
 )
\ No newline at end of file
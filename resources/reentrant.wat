@@ -0,0 +1,19 @@
+(module
+    (import "env" "fac" (func $host_fac (param i32) (result i32)))
+
+    ;; the recursion goes through the host, which calls the export back
+    (func (export "fac") (param i32) (result i32)
+        local.get 0
+        i32.eqz
+        if (result i32)
+            i32.const 1
+        else
+            local.get 0
+            local.get 0
+            i32.const 1
+            i32.sub
+            call $host_fac
+            i32.mul
+        end
+    )
+)
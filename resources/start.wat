@@ -0,0 +1,10 @@
+(module
+    (import "env" "log" (func $log (param i32)))
+
+    (func $main
+        i32.const 7
+        call $log
+    )
+
+    (start $main)
+)
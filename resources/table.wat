@@ -0,0 +1,42 @@
+(module
+    (type $binop (func (param i32) (param i32) (result i32)))
+    (type $unop (func (param i32) (result i32)))
+
+    (table 4 funcref)
+
+    (elem (i32.const 0) $add $sub)
+    (elem $passive func $mul)
+    (elem declare func $neg)
+    (elem (table 0) (i32.const 2) funcref (ref.func $neg) (ref.null func))
+
+    (func $add (type $binop)
+        local.get 0
+        local.get 1
+        i32.add
+    )
+
+    (func $sub (type $binop)
+        local.get 0
+        local.get 1
+        i32.sub
+    )
+
+    (func $mul (type $binop)
+        local.get 0
+        local.get 1
+        i32.mul
+    )
+
+    (func $neg (type $unop)
+        i32.const 0
+        local.get 0
+        i32.sub
+    )
+
+    (func (export "dispatch") (param i32) (param i32) (param i32) (result i32)
+        local.get 1
+        local.get 2
+        local.get 0
+        call_indirect (type $binop)
+    )
+)
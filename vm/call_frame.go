@@ -1,387 +1,667 @@
 package vm
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
+	"math"
 
-	"github.com/EclesioMeloJunior/wasvm/leb128"
 	"github.com/EclesioMeloJunior/wasvm/opcodes"
 	"github.com/EclesioMeloJunior/wasvm/parser"
 )
 
 var (
-	ErrEmptyFuncIndex   = errors.New("expected a func index got empty")
-	ErrParamOutOfBounds = errors.New("param out of bounds")
-	ErrWrongType        = errors.New("wrong type")
+	ErrEmptyFuncIndex     = errors.New("expected a func index got empty")
+	ErrParamOutOfBounds   = errors.New("param out of bounds")
+	ErrLocalOutOfBounds   = errors.New("local out of bounds")
+	ErrWrongType          = errors.New("wrong type")
+	ErrUnreachable        = errors.New("unreachable executed")
+	ErrUnsupportedType    = errors.New("unsupported value type")
+	ErrCallStackExhausted = errors.New("call stack exhausted")
 )
 
+// maxCallDepth is the maximum amount of nested calls,
+// it stops infinite recursions before they exhaust the host stack
+const maxCallDepth = 1000
+
+// maxStackHeight is the maximum amount of values the stack of a
+// call can hold, pushing past it traps with ErrStackOverflow
+const maxStackHeight = 1024
+
 type callFrame struct {
 	rt    *Runtime
 	pc    uint
 	stack Stack
 
-	params       []any
+	// host is only defined when the frame calls a host function
+	host *hostFunction
+
+	paramTypes []parser.Type
+	// localTypes are the declared locals, they come right after the params
+	localTypes []parser.Type
+	// locals holds the params followed by the declared locals of the current call
+	locals []any
+
 	results      []any
-	instructions []byte
+	instructions []parser.Instruction
+
+	// labels are the blocks, loops and ifs being executed, the innermost
+	// is the last one, the function body itself is not part of them
+	labels []label
+	// delimiters are the else and end positions of each structured
+	// instruction, they are found when the function is first called as
+	// the runtime reuses the frame of a function for all of its calls
+	delimiters []delimiter
+}
+
+// label is a structured instruction being executed, a branch to it
+// unwinds the stack to its height keeping the values it carries
+type label struct {
+	// start is the position of the block, loop or if instruction and end
+	// is the position of its end, branches to loops continue after the start
+	start, end uint
+	loop       bool
+	// arity is the amount of values carried by a branch to the label, the
+	// loop params as it jumps back to its start, otherwise the results
+	arity int
+	// height is the stack size before the params of the block were pushed
+	height int
 }
 
-func newCallFrame(rt *Runtime, instructions []byte, paramTypes, resultTypes []parser.Type) *callFrame {
+type delimiter struct {
+	elseAt, endAt uint
+}
+
+func newCallFrame(rt *Runtime, instructions []parser.Instruction,
+	paramTypes, localTypes, resultTypes []parser.Type) (*callFrame, error) {
 	cf := &callFrame{
 		rt:           rt,
 		pc:           0,
-		stack:        make([]StackValue, 0, 1024),
 		instructions: instructions,
-		params:       make([]any, len(paramTypes)),
+		paramTypes:   paramTypes,
+		localTypes:   localTypes,
 		results:      make([]any, len(resultTypes)),
 	}
 
 	for idx, pt := range paramTypes {
-		switch pt.SpecByte {
-		case parser.I32_NUM_TYPE:
-			cf.params[idx] = int32(0)
-		case parser.I64_NUM_TYPE:
-			cf.params[idx] = int64(0)
-		default:
-			// TODO: implement other types at
-			panic(fmt.Sprintf("param type not supported yet: %x", pt.SpecByte))
+		if _, err := zeroValue(pt); err != nil {
+			return nil, fmt.Errorf("param %d: %w", idx, err)
+		}
+	}
+
+	for idx, lt := range localTypes {
+		if _, err := zeroValue(lt); err != nil {
+			return nil, fmt.Errorf("local %d: %w", idx, err)
 		}
 	}
 
 	for idx, rt := range resultTypes {
-		switch rt.SpecByte {
-		case parser.I32_NUM_TYPE:
-			cf.results[idx] = int32(0)
-		case parser.I64_NUM_TYPE:
-			cf.results[idx] = int64(0)
-		default:
-			// TODO: implement other types at
-			panic(fmt.Sprintf("result type not supported yet: %x", rt.SpecByte))
+		value, err := zeroValue(rt)
+		if err != nil {
+			return nil, fmt.Errorf("result %d: %w", idx, err)
 		}
+
+		cf.results[idx] = value
 	}
 
-	return cf
+	return cf, nil
 }
 
-// searchAtContext will try to find a specific opcode
-// inside a context (IF, BLOCK, WHILE) ignoring inner contexts
-// currently it is not possible to search for context starter or ender
-func (c *callFrame) searchAtContext(ctxStarter, ctxEnder, toSearch opcodes.OpCode) (position uint) {
-	startAt := c.pc + 1
-	contextsAcc := 0
-
-	for idx, inst := range c.instructions[startAt:] {
-		switch inst {
-		case byte(ctxStarter):
-			contextsAcc++
-		case byte(ctxEnder):
-			if contextsAcc == 0 {
-				return 0
-			}
-			contextsAcc--
-		case byte(toSearch):
-			if contextsAcc == 0 {
-				return startAt + uint(idx)
-			}
-		}
+// zeroValue returns the default value of a value type,
+// references default to null which is represented by nil
+func zeroValue(t parser.Type) (any, error) {
+	switch t.SpecByte {
+	case parser.I32_NUM_TYPE:
+		return int32(0), nil
+	case parser.I64_NUM_TYPE:
+		return int64(0), nil
+	case parser.F32_NUM_TYPE:
+		return float32(0), nil
+	case parser.F64_NUM_TYPE:
+		return float64(0), nil
+	case parser.FUNC_REF_TYPE, parser.EXTERN_REF_TYPE:
+		return nil, nil
 	}
 
-	return 0
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, t)
 }
 
-func (c *callFrame) searchContextDelimiter(ctxStarter, ctxEnder opcodes.OpCode) (position uint) {
-	startAt := c.pc + 1
-	contextsAcc := 0
-
-	for idx, inst := range c.instructions[startAt:] {
-		switch inst {
-		case byte(ctxStarter):
-			contextsAcc++
-		case byte(ctxEnder):
-			if contextsAcc == 0 {
-				return startAt + uint(idx)
+// blockDelimiters returns, for each structured instruction, the positions
+// of its else, zero when there is none, and of its end, the end is zero
+// when the instruction is never closed
+func blockDelimiters(instructions []parser.Instruction) []delimiter {
+	delimiters := make([]delimiter, len(instructions))
+	open := make([]uint, 0)
+
+	for idx := range instructions {
+		switch instructions[idx].Opcode {
+		case opcodes.Block, opcodes.Loop, opcodes.If:
+			open = append(open, uint(idx))
+		case opcodes.Else:
+			if len(open) > 0 {
+				delimiters[open[len(open)-1]].elseAt = uint(idx)
+			}
+		case opcodes.End:
+			if len(open) > 0 {
+				delimiters[open[len(open)-1]].endAt = uint(idx)
+				open = open[:len(open)-1]
 			}
-			contextsAcc--
 		}
 	}
 
-	return 0
+	return delimiters
 }
 
 func (c *callFrame) Call(params ...any) ([]any, error) {
-	for {
-		if uint(len(c.instructions)) <= c.pc {
-			return nil, nil
+	if c.host != nil {
+		return c.host.call(c.rt, params...)
+	}
+
+	if len(params) != len(c.paramTypes) {
+		return nil, fmt.Errorf("%w: expected %d params, got %d",
+			ErrParamOutOfBounds, len(c.paramTypes), len(params))
+	}
+
+	locals := make([]any, len(params)+len(c.localTypes))
+	for idx, param := range params {
+		if !valueMatchesType(param, c.paramTypes[idx]) {
+			return nil, fmt.Errorf("%w: expected %s param, got %T",
+				ErrWrongType, c.paramTypes[idx], param)
 		}
 
-		currentInstruction := opcodes.OpCode(c.instructions[c.pc])
+		locals[idx] = param
+	}
 
-		switch currentInstruction {
-		case opcodes.LocalGet:
-			// push the parameter onto the stack.
-			// advance the pointer counter to get the variable index
-			c.pc += 1
-			bytesRead, paramAt, err := leb128.DecodeUint(bytes.NewReader(c.instructions[c.pc:]))
-			if err != nil {
-				return nil, fmt.Errorf("failed to decode u32 local index: %w", err)
-			}
+	// the declared locals start with the zero value of their types
+	for idx, localType := range c.localTypes {
+		locals[len(params)+idx], _ = zeroValue(localType)
+	}
 
-			if len(params) < int(paramAt) {
-				return nil, ErrParamOutOfBounds
-			}
+	if c.delimiters == nil {
+		c.delimiters = blockDelimiters(c.instructions)
+	}
 
-			c.stack.push(StackValue{
-				value:   params[paramAt],
-				startAt: c.pc,
-				endAt:   c.pc + uint(bytesRead),
-			})
+	// each call executes on its own copy of the frame, so a call reentering
+	// the function through a host function does not clobber the outer one
+	frame := *c
+	frame.stack = make([]StackValue, 0, maxStackHeight)
+	frame.labels = nil
+	return frame.execute(locals)
+}
 
-			c.pc += uint(bytesRead)
+// execute runs the instructions using the given locals, the blocks, loops
+// and ifs are executed within the frame, keeping track of them in labels
+func (c *callFrame) execute(locals []any) ([]any, error) {
+	c.locals = locals
+	c.pc = 0
 
-		case opcodes.I32Const:
-			// push the i32 leb128 encoded value onto the stack.
-			// lets start read the encoded number
-			c.pc += 1
-			bytesRead, value, err := leb128.DecodeInt[int32](
-				bytes.NewReader(c.instructions[c.pc:]))
+	for {
+		if uint(len(c.instructions)) <= c.pc {
+			return nil, nil
+		}
 
-			if err != nil {
-				return nil, fmt.Errorf("failed to decode int32: %w", err)
-			}
+		inst := &c.instructions[c.pc]
 
-			stackBasedValue := StackValue{
-				value:   value,
-				startAt: c.pc,
-				endAt:   c.pc + uint(bytesRead),
-			}
+		switch inst.Opcode {
+		case opcodes.Unreachable:
+			return nil, ErrUnreachable
 
-			c.stack.push(stackBasedValue)
-			c.pc += uint(bytesRead)
+		case opcodes.Nop:
 
-		case opcodes.I32Add:
-			rhs, err := popEnsureType[int32](&c.stack)
-			if err != nil {
+		case opcodes.Drop:
+			if _, err := c.stack.pop(); err != nil {
 				return nil, fmt.Errorf("cannot pop: %w", err)
 			}
 
-			lhs, err := popEnsureType[int32](&c.stack)
+		case opcodes.Select, opcodes.SelectTyped:
+			condition, err := popEnsureType[int32](&c.stack)
 			if err != nil {
 				return nil, fmt.Errorf("cannot pop: %w", err)
 			}
 
-			c.stack.push(StackValue{
-				value: lhs + rhs,
-			})
-
-			c.pc++
-
-		case opcodes.I32Sub:
-			rhs, err := popEnsureType[int32](&c.stack)
+			second, err := c.stack.pop()
 			if err != nil {
 				return nil, fmt.Errorf("cannot pop: %w", err)
 			}
 
-			lhs, err := popEnsureType[int32](&c.stack)
+			first, err := c.stack.pop()
 			if err != nil {
 				return nil, fmt.Errorf("cannot pop: %w", err)
 			}
 
-			c.stack.push(StackValue{
-				value: lhs - rhs,
-			})
-
-			c.pc++
+			selected := second
+			if condition != 0 {
+				selected = first
+			}
 
-		case opcodes.I32Mul:
-			rhs, err := popEnsureType[int32](&c.stack)
-			if err != nil {
-				return nil, fmt.Errorf("cannot pop: %w", err)
+			if err := c.stack.push(selected); err != nil {
+				return nil, fmt.Errorf("%s: cannot push: %w", inst.Opcode, err)
 			}
 
-			lhs, err := popEnsureType[int32](&c.stack)
-			if err != nil {
-				return nil, fmt.Errorf("cannot pop: %w", err)
+		case opcodes.LocalGet, opcodes.LocalSet, opcodes.LocalTee:
+			localIdx := inst.Index
+			if localIdx >= uint32(len(c.locals)) {
+				return nil, fmt.Errorf("%w: %d", ErrLocalOutOfBounds, localIdx)
 			}
 
-			c.stack.push(StackValue{
-				value: lhs * rhs,
-			})
+			switch inst.Opcode {
+			case opcodes.LocalGet:
+				if err := c.stack.push(StackValue{
+					value: c.locals[localIdx],
+				}); err != nil {
+					return nil, fmt.Errorf("%s: cannot push: %w", inst.Opcode, err)
+				}
+			case opcodes.LocalSet:
+				value, err := c.stack.pop()
+				if err != nil {
+					return nil, fmt.Errorf("cannot pop: %w", err)
+				}
 
-			c.pc++
+				c.locals[localIdx] = value.value
+			case opcodes.LocalTee:
+				if len(c.stack) == 0 {
+					return nil, fmt.Errorf("cannot peek: %w", ErrEmptyStack)
+				}
 
-		case opcodes.I32LowerThanSigned:
-			rhs, err := popEnsureType[int32](&c.stack)
-			if err != nil {
-				return nil, fmt.Errorf("cannot pop: %w", err)
+				c.locals[localIdx] = c.stack[len(c.stack)-1].value
 			}
 
-			lhs, err := popEnsureType[int32](&c.stack)
+		case opcodes.GlobalGet, opcodes.GlobalSet:
+			global, err := c.rt.global(uint(inst.Index))
 			if err != nil {
-				return nil, fmt.Errorf("cannot pop: %w", err)
+				return nil, err
 			}
 
-			if lhs < rhs {
-				c.stack.push(TrueStackValue)
+			if inst.Opcode == opcodes.GlobalGet {
+				if err := c.stack.push(StackValue{
+					value: global.Get(),
+				}); err != nil {
+					return nil, fmt.Errorf("%s: cannot push: %w", inst.Opcode, err)
+				}
 			} else {
-				c.stack.push(FalseStackValue)
+				value, err := c.stack.pop()
+				if err != nil {
+					return nil, fmt.Errorf("cannot pop: %w", err)
+				}
+
+				if err := global.Set(value.value); err != nil {
+					return nil, fmt.Errorf("global.set %d: %w", inst.Index, err)
+				}
 			}
 
-			c.pc++
+		case opcodes.I32Const:
+			if err := c.stack.push(StackValue{
+				value: inst.I32,
+			}); err != nil {
+				return nil, fmt.Errorf("%s: cannot push: %w", inst.Opcode, err)
+			}
 
-		case opcodes.If:
-			jumpToElse := c.searchAtContext(opcodes.If, opcodes.End, opcodes.Else)
-			jumpToIfEnd := c.searchContextDelimiter(opcodes.If, opcodes.End)
-			if jumpToIfEnd == 0 {
-				return nil, fmt.Errorf("failed to find if end")
+		case opcodes.I64Const:
+			if err := c.stack.push(StackValue{
+				value: inst.I64,
+			}); err != nil {
+				return nil, fmt.Errorf("%s: cannot push: %w", inst.Opcode, err)
 			}
 
-			// check if the IF branch contains a result type
-			resultTypeByteCode := c.instructions[c.pc+1]
-			resultType := make([]parser.Type, 0, 1)
+		case opcodes.F32Const:
+			if err := c.stack.push(StackValue{
+				value: math.Float32frombits(inst.F32),
+			}); err != nil {
+				return nil, fmt.Errorf("%s: cannot push: %w", inst.Opcode, err)
+			}
 
-			switch resultTypeByteCode {
-			case parser.I32_NUM_TYPE, parser.I64_NUM_TYPE, parser.F32_NUM_TYPE, parser.F64_NUM_TYPE:
-				resultType = append(resultType, parser.Type{
-					SpecType: parser.NumType,
-					SpecByte: resultTypeByteCode,
-				})
-			case opcodes.EmptyBlockType:
-				fmt.Println("0x40 found")
+		case opcodes.F64Const:
+			if err := c.stack.push(StackValue{
+				value: math.Float64frombits(inst.F64),
+			}); err != nil {
+				return nil, fmt.Errorf("%s: cannot push: %w", inst.Opcode, err)
 			}
 
-			// compute the if branch call frame
-			condition, err := popEnsureType[bool](&c.stack)
-			if err != nil {
-				return nil, fmt.Errorf("cannot pop: %w", err)
+		case opcodes.Block, opcodes.Loop, opcodes.If:
+			delimiter := c.delimiters[c.pc]
+			if delimiter.endAt == 0 {
+				return nil, fmt.Errorf("failed to find %s end", inst.Opcode)
 			}
 
-			if condition {
-				var amountOfInstructions uint
-				if jumpToElse != 0 {
-					amountOfInstructions = jumpToElse - c.pc
-				} else {
-					amountOfInstructions = jumpToIfEnd - c.pc
+			condition := int32(1)
+			if inst.Opcode == opcodes.If {
+				value, err := popEnsureType[int32](&c.stack)
+				if err != nil {
+					return nil, fmt.Errorf("cannot pop: %w", err)
 				}
 
-				branchInstructions := make([]byte, amountOfInstructions-1)
-				if len(resultType) > 0 {
-					// if the IF branch contains a result type
-					// then include the instructions without the RESULT TYPE or ELSE opcode
-					copy(branchInstructions[:], c.instructions[c.pc+2:c.pc+amountOfInstructions])
+				condition = value
+			}
+
+			if err := c.enterBlock(inst, delimiter.endAt); err != nil {
+				return nil, err
+			}
+
+			// a false condition continues at the else branch, when
+			// there is none the if end is executed next
+			if condition == 0 {
+				if delimiter.elseAt != 0 {
+					c.pc = delimiter.elseAt + 1
 				} else {
-					// only removes the ELSE opcode
-					copy(branchInstructions[:], c.instructions[c.pc+1:c.pc+amountOfInstructions])
+					c.pc = delimiter.endAt
 				}
+				continue
+			}
+
+		case opcodes.Else:
+			// the then branch is done, the if end is executed next
+			if len(c.labels) == 0 {
+				return nil, fmt.Errorf("else outside of if")
+			}
 
-				latestItem := amountOfInstructions - 2
-				branchInstructions[latestItem] = byte(opcodes.End)
-				branchCallFrame := newCallFrame(c.rt, branchInstructions, nil, resultType)
+			c.pc = c.labels[len(c.labels)-1].end
+			continue
 
-				result, err := branchCallFrame.Call(params...)
+		case opcodes.End:
+			if len(c.labels) > 0 {
+				c.labels = c.labels[:len(c.labels)-1]
+				break
+			}
+
+			return c.popResults()
+
+		case opcodes.Return:
+			return c.popResults()
+
+		case opcodes.Br, opcodes.BrIf, opcodes.BrTable:
+			depth := inst.Index
+			switch inst.Opcode {
+			case opcodes.BrIf:
+				condition, err := popEnsureType[int32](&c.stack)
 				if err != nil {
-					return nil, fmt.Errorf("if branching: %w", err)
+					return nil, fmt.Errorf("cannot pop: %w", err)
 				}
 
-				// only push the result to the stack if we spec a result from if branch call frame
-				if len(result) > 0 && len(resultType) > 0 {
-					c.stack.push(StackValue{
-						value: result[0],
-					})
+				if condition == 0 {
+					c.pc++
+					continue
 				}
-			} else if jumpToElse != 0 { // there is a else branch
-				amountOfInstructions := jumpToIfEnd - jumpToElse
-				branchInstructions := make([]byte, amountOfInstructions)
-
-				copy(branchInstructions[:], c.instructions[jumpToElse+1:jumpToElse+1+amountOfInstructions])
-
-				branchCallFrame := newCallFrame(c.rt, branchInstructions, nil, resultType)
-				result, err := branchCallFrame.Call(params...)
+			case opcodes.BrTable:
+				labelIdx, err := popEnsureType[int32](&c.stack)
 				if err != nil {
-					return nil, fmt.Errorf("else branching: %w", err)
+					return nil, fmt.Errorf("cannot pop: %w", err)
 				}
 
-				// only push the result to the stack if we spec a result from if branch call frame
-				if len(result) > 0 && len(resultType) > 0 {
-					c.stack.push(StackValue{
-						value: result[0],
-					})
+				if uint32(labelIdx) < uint32(len(inst.Labels)) {
+					depth = inst.Labels[labelIdx]
 				}
 			}
 
-			c.pc = jumpToIfEnd
-		case opcodes.End, opcodes.Return:
-			if len(c.results) > 0 && len(c.stack) == 0 {
-				return nil, fmt.Errorf("stack empty but expected %d return(s)",
-					len(c.results))
+			returned, err := c.branch(depth)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", inst.Opcode, err)
 			}
 
-			results := make([]any, len(c.results))
-			for idx := 0; idx < len(c.results); idx++ {
-				popped, err := c.stack.pop()
-				if err != nil {
-					return nil, fmt.Errorf("cannot pop result from stack: %w", err)
-				}
+			if returned {
+				return c.popResults()
+			}
+			continue
 
-				results[idx] = popped.value
+		case opcodes.Call:
+			if err := c.callFunction(int(inst.Index)); err != nil {
+				return nil, err
 			}
 
-			return results, nil
+		case opcodes.CallIndirect:
+			elemIdx, err := popEnsureType[int32](&c.stack)
+			if err != nil {
+				return nil, fmt.Errorf("cannot pop: %w", err)
+			}
 
-		case opcodes.Call:
-			// advance the pointer counter to get the func index
-			c.pc += 1
-			reader := bytes.NewReader(c.instructions[c.pc:])
-			bytesRead, funcIdx, err := leb128.DecodeUint(reader)
+			funcIdx, err := c.rt.resolveIndirectCall(uint(inst.Table), uint(inst.Index), uint32(elemIdx))
 			if err != nil {
-				return nil, fmt.Errorf("failed to decode u32 func index: %w", err)
+				return nil, fmt.Errorf("call_indirect: %w", err)
 			}
 
-			if bytesRead == 0 {
-				return nil, ErrEmptyFuncIndex
+			if err := c.callFunction(funcIdx); err != nil {
+				return nil, err
 			}
 
-			functionSection := c.rt.binary.Parsers[parser.FunctionSection].(*parser.FunctionSectionParser)
-			codeSection := c.rt.binary.Parsers[parser.CodeSection].(*parser.CodeSectionParser)
+		case opcodes.I32Load, opcodes.I64Load, opcodes.F32Load, opcodes.F64Load,
+			opcodes.I32Load8S, opcodes.I32Load8U, opcodes.I32Load16S, opcodes.I32Load16U,
+			opcodes.I64Load8S, opcodes.I64Load8U, opcodes.I64Load16S, opcodes.I64Load16U,
+			opcodes.I64Load32S, opcodes.I64Load32U:
+			if err := c.executeLoad(inst); err != nil {
+				return nil, err
+			}
 
-			codeToCall := codeSection.FunctionsCode[funcIdx]
-			codeDefs := functionSection.Funcs[funcIdx]
+		case opcodes.I32Store, opcodes.I64Store, opcodes.F32Store, opcodes.F64Store,
+			opcodes.I32Store8, opcodes.I32Store16,
+			opcodes.I64Store8, opcodes.I64Store16, opcodes.I64Store32:
+			if err := c.executeStore(inst); err != nil {
+				return nil, err
+			}
 
-			funcCallFrame := newCallFrame(c.rt,
-				codeToCall.Body,
-				codeDefs.Signature.ParamsTypes,
-				codeDefs.Signature.ResultsTypes)
+		case opcodes.MemorySize:
+			memory, err := c.memory()
+			if err != nil {
+				return nil, err
+			}
 
-			argumentsLen := len(codeDefs.Signature.ParamsTypes)
-			funcArgs := make([]any, argumentsLen)
-			for i := 0; i < argumentsLen; i++ {
-				stackValue, err := c.stack.pop()
-				if err != nil {
-					return nil, fmt.Errorf("cannot pop value from the stack: %w", err)
-				}
+			if err := c.stack.push(StackValue{
+				value: int32(memory.Size()),
+			}); err != nil {
+				return nil, fmt.Errorf("%s: cannot push: %w", inst.Opcode, err)
+			}
 
-				// TODO: maybe check the param type before include in the argument list?
-				funcArgs[i] = stackValue.value
+		case opcodes.MemoryGrow:
+			memory, err := c.memory()
+			if err != nil {
+				return nil, err
 			}
 
-			results, err := funcCallFrame.Call(funcArgs...)
+			delta, err := popEnsureType[int32](&c.stack)
 			if err != nil {
-				return nil, fmt.Errorf("calling function at index %d: %w", funcIdx, err)
+				return nil, fmt.Errorf("cannot pop: %w", err)
+			}
+
+			if err := c.stack.push(StackValue{
+				value: memory.Grow(uint32(delta)),
+			}); err != nil {
+				return nil, fmt.Errorf("%s: cannot push: %w", inst.Opcode, err)
 			}
 
-			expectedResultLen := len(codeDefs.Signature.ResultsTypes)
-			if len(results) != expectedResultLen {
-				return nil, fmt.Errorf("expected %d results, got %d", expectedResultLen, len(results))
+		case opcodes.MemoryInit:
+			memory, err := c.memory()
+			if err != nil {
+				return nil, err
 			}
 
-			for _, result := range results {
-				c.stack.push(StackValue{
-					value: result,
-				})
+			segment, err := c.rt.dataSegment(uint(inst.Index))
+			if err != nil {
+				return nil, err
 			}
 
-			c.pc++
+			n, err := popEnsureType[int32](&c.stack)
+			if err != nil {
+				return nil, fmt.Errorf("cannot pop: %w", err)
+			}
+
+			source, err := popEnsureType[int32](&c.stack)
+			if err != nil {
+				return nil, fmt.Errorf("cannot pop: %w", err)
+			}
+
+			destination, err := popEnsureType[int32](&c.stack)
+			if err != nil {
+				return nil, fmt.Errorf("cannot pop: %w", err)
+			}
+
+			err = memory.init(segment, uint32(destination), uint32(source), uint32(n))
+			if err != nil {
+				return nil, fmt.Errorf("memory.init: %w", err)
+			}
+
+		case opcodes.DataDrop:
+			segment, err := c.rt.dataSegment(uint(inst.Index))
+			if err != nil {
+				return nil, err
+			}
+
+			segment.drop()
+
 		default:
-			return nil, fmt.Errorf("unknonw instruction: %s", currentInstruction)
+			numeric, err := c.executeNumeric(inst.Opcode)
+			if err != nil {
+				return nil, err
+			}
+
+			if !numeric {
+				return nil, fmt.Errorf("unknonw instruction: %s", inst.Opcode)
+			}
+		}
+
+		c.pc++
+	}
+}
+
+// enterBlock pushes the label of the block, loop or if at the pc,
+// its params are the values at the top of the stack
+func (c *callFrame) enterBlock(inst *parser.Instruction, endAt uint) error {
+	paramTypes, resultTypes, err := c.blockSignature(inst)
+	if err != nil {
+		return fmt.Errorf("%s: %w", inst.Opcode, err)
+	}
+
+	if len(c.stack) < len(paramTypes) {
+		return fmt.Errorf("%s: expected %d params: %w", inst.Opcode, len(paramTypes), ErrEmptyStack)
+	}
+
+	blockLabel := label{
+		start:  c.pc,
+		end:    endAt,
+		arity:  len(resultTypes),
+		height: len(c.stack) - len(paramTypes),
+	}
+
+	if inst.Opcode == opcodes.Loop {
+		blockLabel.loop = true
+		blockLabel.arity = len(paramTypes)
+	}
+
+	c.labels = append(c.labels, blockLabel)
+	return nil
+}
+
+// branch unwinds the stack to the label at depth keeping the values it
+// carries and moves the pc to where the label continues, it tells true
+// when the branch targets the function body, which means a return
+func (c *callFrame) branch(depth uint32) (bool, error) {
+	if int(depth) == len(c.labels) {
+		return true, nil
+	}
+
+	if int(depth) > len(c.labels) {
+		return false, fmt.Errorf("%w: %d", parser.ErrUnknownLabel, depth)
+	}
+
+	target := c.labels[len(c.labels)-1-int(depth)]
+	if len(c.stack) < target.height+target.arity {
+		return false, fmt.Errorf("expected %d values: %w", target.arity, ErrEmptyStack)
+	}
+
+	carried := c.stack[len(c.stack)-target.arity:]
+	c.stack = append(c.stack[:target.height], carried...)
+
+	// a loop label is kept as the loop executes again
+	if target.loop {
+		c.labels = c.labels[:len(c.labels)-int(depth)]
+		c.pc = target.start + 1
+		return false, nil
+	}
+
+	c.labels = c.labels[:len(c.labels)-1-int(depth)]
+	c.pc = target.end + 1
+	return false, nil
+}
+
+// popResults pops the function results, the
+// last result is the one at the top of the stack
+func (c *callFrame) popResults() ([]any, error) {
+	if len(c.results) > 0 && len(c.stack) == 0 {
+		return nil, fmt.Errorf("stack empty but expected %d return(s)",
+			len(c.results))
+	}
+
+	results := make([]any, len(c.results))
+	for idx := len(c.results) - 1; idx >= 0; idx-- {
+		popped, err := c.stack.pop()
+		if err != nil {
+			return nil, fmt.Errorf("cannot pop result from stack: %w", err)
 		}
+
+		results[idx] = popped.value
+	}
+
+	return results, nil
+}
+
+// blockSignature returns the params and results of the block type,
+// the type indexed ones are looked up in the module types
+func (c *callFrame) blockSignature(inst *parser.Instruction) ([]parser.Type, []parser.Type, error) {
+	if !inst.TypeIndexed {
+		return nil, inst.Results, nil
 	}
+
+	if c.rt == nil {
+		return nil, nil, fmt.Errorf("%w: block type %d", parser.ErrUnknownType, inst.Index)
+	}
+
+	return c.rt.binary.Module.BlockSignature(inst)
+}
+
+// callFunction pops the arguments of the function at funcIdx from the
+// stack, calls it and pushes its results back onto the stack
+func (c *callFrame) callFunction(funcIdx int) error {
+	codeDefs, err := c.rt.binary.Module.Function(funcIdx)
+	if err != nil {
+		return fmt.Errorf("cannot call function: %w", err)
+	}
+
+	if c.rt.callDepth >= maxCallDepth {
+		return fmt.Errorf("%w: more than %d nested calls", ErrCallStackExhausted, maxCallDepth)
+	}
+
+	funcCallFrame, err := c.rt.functionCallFrame(funcIdx)
+	if err != nil {
+		return fmt.Errorf("cannot call function: %w", err)
+	}
+
+	argumentsLen := len(codeDefs.Signature.ParamsTypes)
+	funcArgs := make([]any, argumentsLen)
+	for i := argumentsLen - 1; i >= 0; i-- {
+		stackValue, err := c.stack.pop()
+		if err != nil {
+			return fmt.Errorf("cannot pop value from the stack: %w", err)
+		}
+
+		// TODO: maybe check the param type before include in the argument list?
+		funcArgs[i] = stackValue.value
+	}
+
+	c.rt.callDepth++
+	results, err := funcCallFrame.Call(funcArgs...)
+	c.rt.callDepth--
+	if err != nil {
+		return fmt.Errorf("calling function %s: %w", c.rt.describeFunction(funcIdx), err)
+	}
+
+	expectedResultLen := len(codeDefs.Signature.ResultsTypes)
+	if len(results) != expectedResultLen {
+		return fmt.Errorf("expected %d results, got %d", expectedResultLen, len(results))
+	}
+
+	for _, result := range results {
+		if err := c.stack.push(StackValue{
+			value: result,
+		}); err != nil {
+			return fmt.Errorf("cannot push result to the stack: %w", err)
+		}
+	}
+
+	return nil
 }
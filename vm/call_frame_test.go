@@ -1,11 +1,23 @@
 package vm
 
 import (
+	"math"
 	"testing"
 
+	"github.com/EclesioMeloJunior/wasvm/builder"
+	"github.com/EclesioMeloJunior/wasvm/opcodes"
+	"github.com/EclesioMeloJunior/wasvm/parser"
+
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+func decodeInstructions(t *testing.T, body []byte) []parser.Instruction {
+	instructions, err := parser.DecodeInstructions(body)
+	require.NoError(t, err)
+	return instructions
+}
+
 func TestIFOpCodeIntruction(t *testing.T) {
 	tests := map[string]struct {
 		instructions []byte
@@ -74,6 +86,18 @@ func TestIFOpCodeIntruction(t *testing.T) {
 			expected: []any{int32(7)}, // we spect the number 1 only
 			results:  []any{int32(0)}, //define the result type
 		},
+		"immediates holding the else and end opcodes": {
+			instructions: []byte{
+				0x41, 0x01, // put 01 in the stack
+				0x41, 0x02, // put 02 in the stack
+				0x48,                   // 01 < 02 (true)
+				0x04, 0x7F, 0x41, 0x0B, // if condition, put 11 in the stack
+				0x05, 0x41, 0x05, 0x0B, // else condition, put 05 in the stack + if end
+				0x0B, // function end
+			},
+			expected: []any{int32(11)},
+			results:  []any{int32(0)},
+		},
 	}
 
 	for tname, tt := range tests {
@@ -82,8 +106,7 @@ func TestIFOpCodeIntruction(t *testing.T) {
 			cf := &callFrame{
 				pc:           0,
 				stack:        make([]StackValue, 0, 1024),
-				instructions: tt.instructions,
-				params:       []any{},
+				instructions: decodeInstructions(t, tt.instructions),
 				results:      tt.results,
 			}
 
@@ -98,3 +121,579 @@ func TestIFOpCodeIntruction(t *testing.T) {
 		})
 	}
 }
+
+func TestBranchInstructions(t *testing.T) {
+	// switchBody returns 10, 20 or 30 as the param selects
+	// the first, the second or the default br_table label
+	switchBody := []builder.Instruction{
+		builder.Block(), builder.Block(), builder.Block(),
+		builder.LocalGet(0), builder.BrTable([]uint32{0, 1}, 2),
+		builder.End(), builder.I32Const(10), builder.Return(),
+		builder.End(), builder.I32Const(20), builder.Return(),
+		builder.End(), builder.I32Const(30),
+		builder.End(),
+	}
+
+	tests := map[string]struct {
+		body     []builder.Instruction
+		param    int32
+		wantErr  error
+		expected []any
+	}{
+		"br unwinds the stack out of nested blocks": {
+			body: []builder.Instruction{
+				builder.BlockResult(parser.I32), builder.Block(),
+				builder.I32Const(1), builder.I32Const(7), builder.Br(1),
+				builder.End(), builder.I32Const(9),
+				builder.End(),
+				builder.End(),
+			},
+			expected: []any{int32(7)},
+		},
+		"br_if loops until the counter is zero": {
+			body: []builder.Instruction{
+				builder.Loop(),
+				builder.LocalGet(1), builder.LocalGet(0), builder.Op(opcodes.I32Add), builder.LocalSet(1),
+				builder.LocalGet(0), builder.I32Const(1), builder.Op(opcodes.I32Sub), builder.LocalTee(0),
+				builder.BrIf(0),
+				builder.End(),
+				builder.LocalGet(1),
+				builder.End(),
+			},
+			param:    5,
+			expected: []any{int32(15)},
+		},
+		"br_if not taken keeps the operands": {
+			body: []builder.Instruction{
+				builder.BlockResult(parser.I32),
+				builder.I32Const(3), builder.LocalGet(0), builder.BrIf(0),
+				builder.Op(opcodes.Drop), builder.I32Const(4),
+				builder.End(),
+				builder.End(),
+			},
+			expected: []any{int32(4)},
+		},
+		"br_if taken carries the operands": {
+			body: []builder.Instruction{
+				builder.BlockResult(parser.I32),
+				builder.I32Const(3), builder.LocalGet(0), builder.BrIf(0),
+				builder.Op(opcodes.Drop), builder.I32Const(4),
+				builder.End(),
+				builder.End(),
+			},
+			param:    1,
+			expected: []any{int32(3)},
+		},
+		"br_table first label":   {body: switchBody, param: 0, expected: []any{int32(10)}},
+		"br_table second label":  {body: switchBody, param: 1, expected: []any{int32(20)}},
+		"br_table default label": {body: switchBody, param: -1, expected: []any{int32(30)}},
+		"return from a loop": {
+			body: []builder.Instruction{
+				builder.Loop(), builder.I32Const(8), builder.Return(), builder.End(),
+				builder.Op(opcodes.Unreachable),
+				builder.End(),
+			},
+			expected: []any{int32(8)},
+		},
+		"br to the function body returns": {
+			body: []builder.Instruction{
+				builder.Block(), builder.Block(), builder.I32Const(6), builder.Br(2), builder.End(), builder.End(),
+				builder.Op(opcodes.Unreachable),
+				builder.End(),
+			},
+			expected: []any{int32(6)},
+		},
+		"br to an unknown label": {
+			body:    []builder.Instruction{builder.I32Const(6), builder.Br(1), builder.End()},
+			wantErr: parser.ErrUnknownLabel,
+		},
+	}
+
+	for tname, tt := range tests {
+		tt := tt
+		t.Run(tname, func(t *testing.T) {
+			body := make([]byte, 0)
+			for _, instr := range tt.body {
+				body = append(body, instr...)
+			}
+
+			cf := &callFrame{
+				stack:        make([]StackValue, 0, 1024),
+				instructions: decodeInstructions(t, body),
+				paramTypes:   []parser.Type{parser.I32},
+				localTypes:   []parser.Type{parser.I32},
+				results:      []any{int32(0)},
+			}
+
+			res, err := cf.Call(tt.param)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, res)
+		})
+	}
+}
+
+func TestParametricInstructions(t *testing.T) {
+	tests := map[string]struct {
+		instructions []byte
+		wantErr      error
+		expected     []any
+	}{
+		"drop discards the top value": {
+			instructions: []byte{
+				0x41, 0x01, // put 01 in the stack
+				0x41, 0x02, // put 02 in the stack
+				0x1A, // drop 02
+				0x0B,
+			},
+			expected: []any{int32(1)},
+		},
+		"select picks the first value when the condition is not zero": {
+			instructions: []byte{
+				0x41, 0x01, 0x41, 0x02, // put 01 and 02 in the stack
+				0x41, 0x05, // condition
+				0x01, // nop
+				0x1B, // select
+				0x0B,
+			},
+			expected: []any{int32(1)},
+		},
+		"typed select picks the second value when the condition is zero": {
+			instructions: []byte{
+				0x41, 0x01, 0x41, 0x02, // put 01 and 02 in the stack
+				0x41, 0x00, // condition
+				0x1C, 0x01, 0x7F, // select (result i32)
+				0x0B,
+			},
+			expected: []any{int32(2)},
+		},
+		"unreachable traps": {
+			instructions: []byte{0x00, 0x41, 0x01, 0x0B},
+			wantErr:      ErrUnreachable,
+		},
+	}
+
+	for tname, tt := range tests {
+		tt := tt
+		t.Run(tname, func(t *testing.T) {
+			cf := &callFrame{
+				stack:        make([]StackValue, 0, 1024),
+				instructions: decodeInstructions(t, tt.instructions),
+				results:      []any{int32(0)},
+			}
+
+			res, err := cf.Call()
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, res)
+		})
+	}
+}
+
+// numericBody pushes the operands as constants followed by the instruction
+func numericBody(op opcodes.OpCode, operands ...any) []byte {
+	body := make([]byte, 0)
+	for _, operand := range operands {
+		switch v := operand.(type) {
+		case int32:
+			body = append(body, builder.I32Const(v)...)
+		case int64:
+			body = append(body, builder.I64Const(v)...)
+		case float32:
+			body = append(body, builder.F32Const(v)...)
+		case float64:
+			body = append(body, builder.F64Const(v)...)
+		}
+	}
+
+	return append(append(body, builder.Op(op)...), byte(opcodes.End))
+}
+
+func TestI32Instructions(t *testing.T) {
+	tests := map[string]struct {
+		op       opcodes.OpCode
+		operands []any
+		expected int32
+		wantErr  error
+	}{
+		"eqz of zero":           {op: opcodes.I32Eqz, operands: []any{int32(0)}, expected: 1},
+		"eqz of non zero":       {op: opcodes.I32Eqz, operands: []any{int32(-3)}, expected: 0},
+		"eq":                    {op: opcodes.I32Eq, operands: []any{int32(4), int32(4)}, expected: 1},
+		"ne":                    {op: opcodes.I32Ne, operands: []any{int32(4), int32(4)}, expected: 0},
+		"lt_s":                  {op: opcodes.I32LtS, operands: []any{int32(-1), int32(1)}, expected: 1},
+		"lt_u":                  {op: opcodes.I32LtU, operands: []any{int32(-1), int32(1)}, expected: 0},
+		"gt_s":                  {op: opcodes.I32GtS, operands: []any{int32(-1), int32(1)}, expected: 0},
+		"gt_u":                  {op: opcodes.I32GtU, operands: []any{int32(-1), int32(1)}, expected: 1},
+		"le_s":                  {op: opcodes.I32LeS, operands: []any{int32(2), int32(2)}, expected: 1},
+		"le_u":                  {op: opcodes.I32LeU, operands: []any{int32(-2), int32(2)}, expected: 0},
+		"ge_s":                  {op: opcodes.I32GeS, operands: []any{int32(-2), int32(2)}, expected: 0},
+		"ge_u":                  {op: opcodes.I32GeU, operands: []any{int32(-2), int32(2)}, expected: 1},
+		"clz":                   {op: opcodes.I32Clz, operands: []any{int32(1)}, expected: 31},
+		"clz of zero":           {op: opcodes.I32Clz, operands: []any{int32(0)}, expected: 32},
+		"ctz":                   {op: opcodes.I32Ctz, operands: []any{int32(8)}, expected: 3},
+		"popcnt":                {op: opcodes.I32Popcnt, operands: []any{int32(-1)}, expected: 32},
+		"add wraps":             {op: opcodes.I32Add, operands: []any{int32(math.MaxInt32), int32(1)}, expected: math.MinInt32},
+		"div_s truncates":       {op: opcodes.I32DivS, operands: []any{int32(-7), int32(2)}, expected: -3},
+		"div_u":                 {op: opcodes.I32DivU, operands: []any{int32(-1), int32(2)}, expected: math.MaxInt32},
+		"div_s by zero":         {op: opcodes.I32DivS, operands: []any{int32(1), int32(0)}, wantErr: ErrIntegerDivideByZero},
+		"div_u by zero":         {op: opcodes.I32DivU, operands: []any{int32(1), int32(0)}, wantErr: ErrIntegerDivideByZero},
+		"div_s overflow":        {op: opcodes.I32DivS, operands: []any{int32(math.MinInt32), int32(-1)}, wantErr: ErrIntegerOverflow},
+		"rem_s keeps the sign":  {op: opcodes.I32RemS, operands: []any{int32(-7), int32(2)}, expected: -1},
+		"rem_s of min by -1":    {op: opcodes.I32RemS, operands: []any{int32(math.MinInt32), int32(-1)}, expected: 0},
+		"rem_u":                 {op: opcodes.I32RemU, operands: []any{int32(-1), int32(10)}, expected: 5},
+		"rem_s by zero":         {op: opcodes.I32RemS, operands: []any{int32(1), int32(0)}, wantErr: ErrIntegerDivideByZero},
+		"and":                   {op: opcodes.I32And, operands: []any{int32(0b1100), int32(0b1010)}, expected: 0b1000},
+		"or":                    {op: opcodes.I32Or, operands: []any{int32(0b1100), int32(0b1010)}, expected: 0b1110},
+		"xor":                   {op: opcodes.I32Xor, operands: []any{int32(0b1100), int32(0b1010)}, expected: 0b0110},
+		"shl takes the modulo":  {op: opcodes.I32Shl, operands: []any{int32(1), int32(33)}, expected: 2},
+		"shr_s keeps the sign":  {op: opcodes.I32ShrS, operands: []any{int32(-8), int32(1)}, expected: -4},
+		"shr_u fills with zero": {op: opcodes.I32ShrU, operands: []any{int32(-8), int32(28)}, expected: 0xF},
+		"rotl":                  {op: opcodes.I32Rotl, operands: []any{int32(math.MinInt32), int32(1)}, expected: 1},
+		"rotr":                  {op: opcodes.I32Rotr, operands: []any{int32(1), int32(1)}, expected: math.MinInt32},
+	}
+
+	for tname, tt := range tests {
+		tt := tt
+		t.Run(tname, func(t *testing.T) {
+			cf := &callFrame{
+				stack:        make([]StackValue, 0, 1024),
+				instructions: decodeInstructions(t, numericBody(tt.op, tt.operands...)),
+				results:      []any{int32(0)},
+			}
+
+			res, err := cf.Call()
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, []any{tt.expected}, res)
+		})
+	}
+}
+
+func TestI64Instructions(t *testing.T) {
+	tests := map[string]struct {
+		op       opcodes.OpCode
+		operands []any
+		expected any
+		wantErr  error
+	}{
+		"eqz of zero":           {op: opcodes.I64Eqz, operands: []any{int64(0)}, expected: int32(1)},
+		"eqz of non zero":       {op: opcodes.I64Eqz, operands: []any{int64(math.MinInt64)}, expected: int32(0)},
+		"eq":                    {op: opcodes.I64Eq, operands: []any{int64(1 << 40), int64(1 << 40)}, expected: int32(1)},
+		"ne":                    {op: opcodes.I64Ne, operands: []any{int64(1 << 40), int64(1 << 40)}, expected: int32(0)},
+		"lt_s":                  {op: opcodes.I64LtS, operands: []any{int64(-1), int64(1)}, expected: int32(1)},
+		"lt_u":                  {op: opcodes.I64LtU, operands: []any{int64(-1), int64(1)}, expected: int32(0)},
+		"gt_s":                  {op: opcodes.I64GtS, operands: []any{int64(-1), int64(1)}, expected: int32(0)},
+		"gt_u":                  {op: opcodes.I64GtU, operands: []any{int64(-1), int64(1)}, expected: int32(1)},
+		"le_s":                  {op: opcodes.I64LeS, operands: []any{int64(2), int64(2)}, expected: int32(1)},
+		"le_u":                  {op: opcodes.I64LeU, operands: []any{int64(-2), int64(2)}, expected: int32(0)},
+		"ge_s":                  {op: opcodes.I64GeS, operands: []any{int64(-2), int64(2)}, expected: int32(0)},
+		"ge_u":                  {op: opcodes.I64GeU, operands: []any{int64(-2), int64(2)}, expected: int32(1)},
+		"clz":                   {op: opcodes.I64Clz, operands: []any{int64(1)}, expected: int64(63)},
+		"clz of zero":           {op: opcodes.I64Clz, operands: []any{int64(0)}, expected: int64(64)},
+		"ctz":                   {op: opcodes.I64Ctz, operands: []any{int64(1 << 40)}, expected: int64(40)},
+		"popcnt":                {op: opcodes.I64Popcnt, operands: []any{int64(-1)}, expected: int64(64)},
+		"add wraps":             {op: opcodes.I64Add, operands: []any{int64(math.MaxInt64), int64(1)}, expected: int64(math.MinInt64)},
+		"sub":                   {op: opcodes.I64Sub, operands: []any{int64(1), int64(1 << 33)}, expected: int64(1 - 1<<33)},
+		"mul":                   {op: opcodes.I64Mul, operands: []any{int64(1 << 31), int64(1 << 2)}, expected: int64(1 << 33)},
+		"div_s truncates":       {op: opcodes.I64DivS, operands: []any{int64(-7), int64(2)}, expected: int64(-3)},
+		"div_u":                 {op: opcodes.I64DivU, operands: []any{int64(-1), int64(2)}, expected: int64(math.MaxInt64)},
+		"div_s by zero":         {op: opcodes.I64DivS, operands: []any{int64(1), int64(0)}, wantErr: ErrIntegerDivideByZero},
+		"div_u by zero":         {op: opcodes.I64DivU, operands: []any{int64(1), int64(0)}, wantErr: ErrIntegerDivideByZero},
+		"div_s overflow":        {op: opcodes.I64DivS, operands: []any{int64(math.MinInt64), int64(-1)}, wantErr: ErrIntegerOverflow},
+		"rem_s keeps the sign":  {op: opcodes.I64RemS, operands: []any{int64(-7), int64(2)}, expected: int64(-1)},
+		"rem_s of min by -1":    {op: opcodes.I64RemS, operands: []any{int64(math.MinInt64), int64(-1)}, expected: int64(0)},
+		"rem_u":                 {op: opcodes.I64RemU, operands: []any{int64(-1), int64(10)}, expected: int64(5)},
+		"rem_u by zero":         {op: opcodes.I64RemU, operands: []any{int64(1), int64(0)}, wantErr: ErrIntegerDivideByZero},
+		"and":                   {op: opcodes.I64And, operands: []any{int64(0b1100), int64(0b1010)}, expected: int64(0b1000)},
+		"or":                    {op: opcodes.I64Or, operands: []any{int64(0b1100), int64(0b1010)}, expected: int64(0b1110)},
+		"xor":                   {op: opcodes.I64Xor, operands: []any{int64(0b1100), int64(0b1010)}, expected: int64(0b0110)},
+		"shl takes the modulo":  {op: opcodes.I64Shl, operands: []any{int64(1), int64(65)}, expected: int64(2)},
+		"shr_s keeps the sign":  {op: opcodes.I64ShrS, operands: []any{int64(-8), int64(1)}, expected: int64(-4)},
+		"shr_u fills with zero": {op: opcodes.I64ShrU, operands: []any{int64(-8), int64(60)}, expected: int64(0xF)},
+		"rotl":                  {op: opcodes.I64Rotl, operands: []any{int64(math.MinInt64), int64(1)}, expected: int64(1)},
+		"rotr":                  {op: opcodes.I64Rotr, operands: []any{int64(1), int64(1)}, expected: int64(math.MinInt64)},
+	}
+
+	for tname, tt := range tests {
+		tt := tt
+		t.Run(tname, func(t *testing.T) {
+			cf := &callFrame{
+				stack:        make([]StackValue, 0, 1024),
+				instructions: decodeInstructions(t, numericBody(tt.op, tt.operands...)),
+				results:      []any{tt.expected},
+			}
+
+			res, err := cf.Call()
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, []any{tt.expected}, res)
+		})
+	}
+}
+
+// assertFloatResult compares the bits of the floats, so -0 is told apart
+// from 0, while any NaN is accepted as the spec does not fix its payload
+func assertFloatResult(t *testing.T, expected, result any) {
+	t.Helper()
+
+	switch v := expected.(type) {
+	case float32:
+		require.IsType(t, v, result)
+		if math.IsNaN(float64(v)) {
+			assert.True(t, math.IsNaN(float64(result.(float32))), "expected NaN, got %v", result)
+			return
+		}
+		assert.Equal(t, math.Float32bits(v), math.Float32bits(result.(float32)), "expected %v, got %v", v, result)
+	case float64:
+		require.IsType(t, v, result)
+		if math.IsNaN(v) {
+			assert.True(t, math.IsNaN(result.(float64)), "expected NaN, got %v", result)
+			return
+		}
+		assert.Equal(t, math.Float64bits(v), math.Float64bits(result.(float64)), "expected %v, got %v", v, result)
+	default:
+		assert.Equal(t, expected, result)
+	}
+}
+
+func TestF32Instructions(t *testing.T) {
+	nan := float32(math.NaN())
+	inf := float32(math.Inf(1))
+	negZero := float32(math.Copysign(0, -1))
+
+	tests := map[string]struct {
+		op       opcodes.OpCode
+		operands []any
+		expected any
+	}{
+		"eq":                     {op: opcodes.F32Eq, operands: []any{float32(1.5), float32(1.5)}, expected: int32(1)},
+		"eq of zeros":            {op: opcodes.F32Eq, operands: []any{negZero, float32(0)}, expected: int32(1)},
+		"eq of NaN":              {op: opcodes.F32Eq, operands: []any{nan, nan}, expected: int32(0)},
+		"ne of NaN":              {op: opcodes.F32Ne, operands: []any{nan, nan}, expected: int32(1)},
+		"lt":                     {op: opcodes.F32Lt, operands: []any{float32(-1), float32(1)}, expected: int32(1)},
+		"lt of NaN":              {op: opcodes.F32Lt, operands: []any{nan, float32(1)}, expected: int32(0)},
+		"gt":                     {op: opcodes.F32Gt, operands: []any{float32(-1), float32(1)}, expected: int32(0)},
+		"le":                     {op: opcodes.F32Le, operands: []any{float32(2), float32(2)}, expected: int32(1)},
+		"ge of NaN":              {op: opcodes.F32Ge, operands: []any{float32(2), nan}, expected: int32(0)},
+		"abs":                    {op: opcodes.F32Abs, operands: []any{float32(-2.5)}, expected: float32(2.5)},
+		"abs of -0":              {op: opcodes.F32Abs, operands: []any{negZero}, expected: float32(0)},
+		"neg":                    {op: opcodes.F32Neg, operands: []any{float32(0)}, expected: negZero},
+		"ceil":                   {op: opcodes.F32Ceil, operands: []any{float32(-1.5)}, expected: float32(-1)},
+		"ceil keeps the sign":    {op: opcodes.F32Ceil, operands: []any{float32(-0.5)}, expected: negZero},
+		"floor":                  {op: opcodes.F32Floor, operands: []any{float32(-1.5)}, expected: float32(-2)},
+		"trunc":                  {op: opcodes.F32Trunc, operands: []any{float32(-1.5)}, expected: float32(-1)},
+		"nearest rounds to even": {op: opcodes.F32Nearest, operands: []any{float32(2.5)}, expected: float32(2)},
+		"nearest":                {op: opcodes.F32Nearest, operands: []any{float32(3.5)}, expected: float32(4)},
+		"sqrt":                   {op: opcodes.F32Sqrt, operands: []any{float32(2.25)}, expected: float32(1.5)},
+		"sqrt of negative":       {op: opcodes.F32Sqrt, operands: []any{float32(-1)}, expected: nan},
+		"add":                    {op: opcodes.F32Add, operands: []any{float32(0.1), float32(0.2)}, expected: float32(0.1) + float32(0.2)},
+		"add of NaN":             {op: opcodes.F32Add, operands: []any{nan, float32(1)}, expected: nan},
+		"sub of infinities":      {op: opcodes.F32Sub, operands: []any{inf, inf}, expected: nan},
+		"mul":                    {op: opcodes.F32Mul, operands: []any{float32(-2), float32(0)}, expected: negZero},
+		"div by zero":            {op: opcodes.F32Div, operands: []any{float32(1), float32(0)}, expected: inf},
+		"div of zeros":           {op: opcodes.F32Div, operands: []any{float32(0), float32(0)}, expected: nan},
+		"min":                    {op: opcodes.F32Min, operands: []any{float32(1), float32(-1)}, expected: float32(-1)},
+		"min of zeros":           {op: opcodes.F32Min, operands: []any{float32(0), negZero}, expected: negZero},
+		"min of NaN":             {op: opcodes.F32Min, operands: []any{float32(1), nan}, expected: nan},
+		"max":                    {op: opcodes.F32Max, operands: []any{float32(1), float32(-1)}, expected: float32(1)},
+		"max of zeros":           {op: opcodes.F32Max, operands: []any{negZero, float32(0)}, expected: float32(0)},
+		"max of NaN":             {op: opcodes.F32Max, operands: []any{nan, float32(1)}, expected: nan},
+		"copysign":               {op: opcodes.F32Copysign, operands: []any{float32(2), float32(-1)}, expected: float32(-2)},
+		"copysign from 0":        {op: opcodes.F32Copysign, operands: []any{float32(-2), float32(0)}, expected: float32(2)},
+		"copysign from -0":       {op: opcodes.F32Copysign, operands: []any{float32(2), negZero}, expected: float32(-2)},
+	}
+
+	for tname, tt := range tests {
+		tt := tt
+		t.Run(tname, func(t *testing.T) {
+			cf := &callFrame{
+				stack:        make([]StackValue, 0, 1024),
+				instructions: decodeInstructions(t, numericBody(tt.op, tt.operands...)),
+				results:      []any{tt.expected},
+			}
+
+			res, err := cf.Call()
+			require.NoError(t, err)
+			require.Len(t, res, 1)
+			assertFloatResult(t, tt.expected, res[0])
+		})
+	}
+}
+
+func TestF64Instructions(t *testing.T) {
+	nan := math.NaN()
+	inf := math.Inf(1)
+	negZero := math.Copysign(0, -1)
+
+	tests := map[string]struct {
+		op       opcodes.OpCode
+		operands []any
+		expected any
+	}{
+		"eq":                     {op: opcodes.F64Eq, operands: []any{1.5, 1.5}, expected: int32(1)},
+		"eq of NaN":              {op: opcodes.F64Eq, operands: []any{nan, nan}, expected: int32(0)},
+		"ne of NaN":              {op: opcodes.F64Ne, operands: []any{nan, 1.0}, expected: int32(1)},
+		"lt":                     {op: opcodes.F64Lt, operands: []any{negZero, 0.0}, expected: int32(0)},
+		"gt":                     {op: opcodes.F64Gt, operands: []any{inf, math.MaxFloat64}, expected: int32(1)},
+		"le of NaN":              {op: opcodes.F64Le, operands: []any{nan, nan}, expected: int32(0)},
+		"ge":                     {op: opcodes.F64Ge, operands: []any{2.0, 1.0}, expected: int32(1)},
+		"abs":                    {op: opcodes.F64Abs, operands: []any{-inf}, expected: inf},
+		"neg":                    {op: opcodes.F64Neg, operands: []any{negZero}, expected: 0.0},
+		"ceil":                   {op: opcodes.F64Ceil, operands: []any{1.1}, expected: 2.0},
+		"floor":                  {op: opcodes.F64Floor, operands: []any{-0.5}, expected: -1.0},
+		"trunc keeps the sign":   {op: opcodes.F64Trunc, operands: []any{-0.5}, expected: negZero},
+		"nearest rounds to even": {op: opcodes.F64Nearest, operands: []any{-2.5}, expected: -2.0},
+		"sqrt":                   {op: opcodes.F64Sqrt, operands: []any{2.0}, expected: math.Sqrt2},
+		"sqrt of -0":             {op: opcodes.F64Sqrt, operands: []any{negZero}, expected: negZero},
+		"add":                    {op: opcodes.F64Add, operands: []any{0.1, 0.2}, expected: 0.30000000000000004},
+		"sub":                    {op: opcodes.F64Sub, operands: []any{inf, 1.0}, expected: inf},
+		"mul of NaN":             {op: opcodes.F64Mul, operands: []any{nan, 0.0}, expected: nan},
+		"div by negative zero":   {op: opcodes.F64Div, operands: []any{1.0, negZero}, expected: -inf},
+		"min of zeros":           {op: opcodes.F64Min, operands: []any{negZero, 0.0}, expected: negZero},
+		"min of NaN":             {op: opcodes.F64Min, operands: []any{nan, -inf}, expected: nan},
+		"max":                    {op: opcodes.F64Max, operands: []any{-inf, 1.0}, expected: 1.0},
+		"max of zeros":           {op: opcodes.F64Max, operands: []any{0.0, negZero}, expected: 0.0},
+		"max of NaN":             {op: opcodes.F64Max, operands: []any{inf, nan}, expected: nan},
+		"copysign":               {op: opcodes.F64Copysign, operands: []any{inf, -1.0}, expected: -inf},
+	}
+
+	for tname, tt := range tests {
+		tt := tt
+		t.Run(tname, func(t *testing.T) {
+			cf := &callFrame{
+				stack:        make([]StackValue, 0, 1024),
+				instructions: decodeInstructions(t, numericBody(tt.op, tt.operands...)),
+				results:      []any{tt.expected},
+			}
+
+			res, err := cf.Call()
+			require.NoError(t, err)
+			require.Len(t, res, 1)
+			assertFloatResult(t, tt.expected, res[0])
+		})
+	}
+}
+
+func TestConversionInstructions(t *testing.T) {
+	nan := math.NaN()
+
+	tests := map[string]struct {
+		op       opcodes.OpCode
+		operand  any
+		expected any
+		wantErr  error
+	}{
+		"i32.wrap_i64":                   {op: opcodes.I32WrapI64, operand: int64(1<<32 | 7), expected: int32(7)},
+		"i32.extend8_s":                  {op: opcodes.I32Extend8S, operand: int32(0x80), expected: int32(-128)},
+		"i32.extend16_s":                 {op: opcodes.I32Extend16S, operand: int32(0x7FFF), expected: int32(0x7FFF)},
+		"i64.extend_i32_s":               {op: opcodes.I64ExtendI32S, operand: int32(-1), expected: int64(-1)},
+		"i64.extend_i32_u":               {op: opcodes.I64ExtendI32U, operand: int32(-1), expected: int64(math.MaxUint32)},
+		"i64.extend8_s":                  {op: opcodes.I64Extend8S, operand: int64(0xFF), expected: int64(-1)},
+		"i64.extend16_s":                 {op: opcodes.I64Extend16S, operand: int64(0x8000), expected: int64(-32768)},
+		"i64.extend32_s":                 {op: opcodes.I64Extend32S, operand: int64(math.MaxUint32), expected: int64(-1)},
+		"i32.trunc_f32_s":                {op: opcodes.I32TruncF32S, operand: float32(-1.9), expected: int32(-1)},
+		"i32.trunc_f32_u":                {op: opcodes.I32TruncF32U, operand: float32(3e9), expected: int32(-1294967296)},
+		"i32.trunc_f32_u of -0.9":        {op: opcodes.I32TruncF32U, operand: float32(-0.9), expected: int32(0)},
+		"i32.trunc_f64_s of min":         {op: opcodes.I32TruncF64S, operand: -2147483648.9, expected: int32(math.MinInt32)},
+		"i32.trunc_f64_s of NaN":         {op: opcodes.I32TruncF64S, operand: nan, wantErr: ErrInvalidConversion},
+		"i32.trunc_f64_s overflow":       {op: opcodes.I32TruncF64S, operand: 2147483648.0, wantErr: ErrIntegerOverflow},
+		"i32.trunc_f64_u underflow":      {op: opcodes.I32TruncF64U, operand: -1.0, wantErr: ErrIntegerOverflow},
+		"i32.trunc_f64_u of max":         {op: opcodes.I32TruncF64U, operand: 4294967295.5, expected: int32(-1)},
+		"i64.trunc_f32_s of infinity":    {op: opcodes.I64TruncF32S, operand: float32(math.Inf(-1)), wantErr: ErrIntegerOverflow},
+		"i64.trunc_f64_s":                {op: opcodes.I64TruncF64S, operand: -4294967296.5, expected: int64(-4294967296)},
+		"i64.trunc_f64_s overflow":       {op: opcodes.I64TruncF64S, operand: 9223372036854775808.0, wantErr: ErrIntegerOverflow},
+		"i64.trunc_f64_u":                {op: opcodes.I64TruncF64U, operand: 18446744073709549568.0, expected: int64(-2048)},
+		"i64.trunc_f64_u overflow":       {op: opcodes.I64TruncF64U, operand: 18446744073709551616.0, wantErr: ErrIntegerOverflow},
+		"i64.trunc_f32_u of NaN":         {op: opcodes.I64TruncF32U, operand: float32(nan), wantErr: ErrInvalidConversion},
+		"i32.trunc_sat_f32_s of NaN":     {op: opcodes.I32TruncSatF32S, operand: float32(nan), expected: int32(0)},
+		"i32.trunc_sat_f32_u underflow":  {op: opcodes.I32TruncSatF32U, operand: float32(-5), expected: int32(0)},
+		"i32.trunc_sat_f64_s overflow":   {op: opcodes.I32TruncSatF64S, operand: 1e10, expected: int32(math.MaxInt32)},
+		"i32.trunc_sat_f64_u overflow":   {op: opcodes.I32TruncSatF64U, operand: 1e10, expected: int32(-1)},
+		"i64.trunc_sat_f32_s underflow":  {op: opcodes.I64TruncSatF32S, operand: float32(-1e30), expected: int64(math.MinInt64)},
+		"i64.trunc_sat_f32_u":            {op: opcodes.I64TruncSatF32U, operand: float32(1.5), expected: int64(1)},
+		"i64.trunc_sat_f64_s overflow":   {op: opcodes.I64TruncSatF64S, operand: math.Inf(1), expected: int64(math.MaxInt64)},
+		"i64.trunc_sat_f64_u overflow":   {op: opcodes.I64TruncSatF64U, operand: math.Inf(1), expected: int64(-1)},
+		"f32.convert_i32_s":              {op: opcodes.F32ConvertI32S, operand: int32(-3), expected: float32(-3)},
+		"f32.convert_i32_u":              {op: opcodes.F32ConvertI32U, operand: int32(-1), expected: float32(4294967296)},
+		"f32.convert_i64_s rounds":       {op: opcodes.F32ConvertI64S, operand: int64(16777217), expected: float32(16777216)},
+		"f32.convert_i64_u":              {op: opcodes.F32ConvertI64U, operand: int64(-1), expected: float32(18446744073709551616)},
+		"f32.demote_f64":                 {op: opcodes.F32DemoteF64, operand: 1e40, expected: float32(math.Inf(1))},
+		"f64.convert_i32_u":              {op: opcodes.F64ConvertI32U, operand: int32(-1), expected: 4294967295.0},
+		"f64.convert_i64_s":              {op: opcodes.F64ConvertI64S, operand: int64(math.MinInt64), expected: -9223372036854775808.0},
+		"f64.convert_i64_u":              {op: opcodes.F64ConvertI64U, operand: int64(-1), expected: 18446744073709551616.0},
+		"f64.promote_f32":                {op: opcodes.F64PromoteF32, operand: float32(0.5), expected: 0.5},
+		"i32.reinterpret_f32 of -0":      {op: opcodes.I32ReinterpretF32, operand: float32(math.Copysign(0, -1)), expected: int32(math.MinInt32)},
+		"i32.reinterpret_f32 of -1":      {op: opcodes.I32ReinterpretF32, operand: float32(-1), expected: int32(-0x40800000)},
+		"i64.reinterpret_f64":            {op: opcodes.I64ReinterpretF64, operand: 1.0, expected: int64(0x3FF0000000000000)},
+		"f32.reinterpret_i32":            {op: opcodes.F32ReinterpretI32, operand: int32(0x3FC00000), expected: float32(1.5)},
+		"f64.reinterpret_i64 of the NaN": {op: opcodes.F64ReinterpretI64, operand: int64(0x7FF8000000000001), expected: nan},
+	}
+
+	for tname, tt := range tests {
+		tt := tt
+		t.Run(tname, func(t *testing.T) {
+			cf := &callFrame{
+				stack:        make([]StackValue, 0, 1024),
+				instructions: decodeInstructions(t, numericBody(tt.op, tt.operand)),
+				results:      []any{tt.expected},
+			}
+
+			res, err := cf.Call()
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, res, 1)
+			assertFloatResult(t, tt.expected, res[0])
+		})
+	}
+}
+
+func TestStackOverflow(t *testing.T) {
+	// the constants overflow the stack before the adds could consume them
+	body := make([]byte, 0)
+	for i := 0; i < 1100; i++ {
+		body = append(body, builder.I32Const(1)...)
+	}
+
+	for i := 0; i < 1099; i++ {
+		body = append(body, byte(opcodes.I32Add))
+	}
+
+	cf := &callFrame{
+		instructions: decodeInstructions(t, append(body, byte(opcodes.End))),
+		results:      []any{int32(0)},
+	}
+
+	_, err := cf.Call()
+	require.ErrorIs(t, err, ErrStackOverflow)
+	require.Contains(t, err.Error(), "i32.const")
+}
+
+func TestStack_PushStopsAtMaxHeight(t *testing.T) {
+	// the limit does not depend on the capacity of the stack
+	stack := make(Stack, 0, 2*maxStackHeight)
+	for i := 0; i < maxStackHeight; i++ {
+		require.NoError(t, stack.push(StackValue{value: int32(i)}))
+	}
+
+	require.ErrorIs(t, stack.push(StackValue{value: int32(0)}), ErrStackOverflow)
+}
@@ -0,0 +1,144 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/EclesioMeloJunior/wasvm/opcodes"
+)
+
+// the bounds, as floats, of the integers a float can be truncated to
+const (
+	twoTo31 = float64(1 << 31)
+	twoTo32 = float64(1 << 32)
+	twoTo63 = float64(1 << 63)
+	twoTo64 = float64(1 << 64)
+)
+
+// conversions are the instructions that pop a value of a type and push it
+// converted to another one, only the non saturating truncations can trap
+var conversions = map[opcodes.OpCode]func(*Stack, opcodes.OpCode) error{
+	opcodes.I32WrapI64:   conversion(func(v int64) (int32, error) { return int32(v), nil }),
+	opcodes.I32Extend8S:  conversion(func(v int32) (int32, error) { return int32(int8(v)), nil }),
+	opcodes.I32Extend16S: conversion(func(v int32) (int32, error) { return int32(int16(v)), nil }),
+
+	opcodes.I64ExtendI32S: conversion(func(v int32) (int64, error) { return int64(v), nil }),
+	opcodes.I64ExtendI32U: conversion(func(v int32) (int64, error) { return int64(uint32(v)), nil }),
+	opcodes.I64Extend8S:   conversion(func(v int64) (int64, error) { return int64(int8(v)), nil }),
+	opcodes.I64Extend16S:  conversion(func(v int64) (int64, error) { return int64(int16(v)), nil }),
+	opcodes.I64Extend32S:  conversion(func(v int64) (int64, error) { return int64(int32(v)), nil }),
+
+	opcodes.I32TruncF32S: conversion(func(v float32) (int32, error) { return truncate[int32](float64(v), -twoTo31, twoTo31) }),
+	opcodes.I32TruncF64S: conversion(func(v float64) (int32, error) { return truncate[int32](v, -twoTo31, twoTo31) }),
+	opcodes.I32TruncF32U: conversion(func(v float32) (int32, error) {
+		u, err := truncate[uint32](float64(v), 0, twoTo32)
+		return int32(u), err
+	}),
+	opcodes.I32TruncF64U: conversion(func(v float64) (int32, error) {
+		u, err := truncate[uint32](v, 0, twoTo32)
+		return int32(u), err
+	}),
+	opcodes.I64TruncF32S: conversion(func(v float32) (int64, error) { return truncate[int64](float64(v), -twoTo63, twoTo63) }),
+	opcodes.I64TruncF64S: conversion(func(v float64) (int64, error) { return truncate[int64](v, -twoTo63, twoTo63) }),
+	opcodes.I64TruncF32U: conversion(func(v float32) (int64, error) {
+		u, err := truncate[uint64](float64(v), 0, twoTo64)
+		return int64(u), err
+	}),
+	opcodes.I64TruncF64U: conversion(func(v float64) (int64, error) {
+		u, err := truncate[uint64](v, 0, twoTo64)
+		return int64(u), err
+	}),
+
+	opcodes.I32TruncSatF32S: conversion(func(v float32) (int32, error) {
+		return truncateSat[int32](float64(v), math.MinInt32, math.MaxInt32), nil
+	}),
+	opcodes.I32TruncSatF32U: conversion(func(v float32) (int32, error) {
+		return int32(truncateSat[uint32](float64(v), 0, math.MaxUint32)), nil
+	}),
+	opcodes.I32TruncSatF64S: conversion(func(v float64) (int32, error) {
+		return truncateSat[int32](v, math.MinInt32, math.MaxInt32), nil
+	}),
+	opcodes.I32TruncSatF64U: conversion(func(v float64) (int32, error) {
+		return int32(truncateSat[uint32](v, 0, math.MaxUint32)), nil
+	}),
+	opcodes.I64TruncSatF32S: conversion(func(v float32) (int64, error) {
+		return truncateSat[int64](float64(v), math.MinInt64, math.MaxInt64), nil
+	}),
+	opcodes.I64TruncSatF32U: conversion(func(v float32) (int64, error) {
+		return int64(truncateSat[uint64](float64(v), 0, math.MaxUint64)), nil
+	}),
+	opcodes.I64TruncSatF64S: conversion(func(v float64) (int64, error) {
+		return truncateSat[int64](v, math.MinInt64, math.MaxInt64), nil
+	}),
+	opcodes.I64TruncSatF64U: conversion(func(v float64) (int64, error) {
+		return int64(truncateSat[uint64](v, 0, math.MaxUint64)), nil
+	}),
+
+	opcodes.F32ConvertI32S: conversion(func(v int32) (float32, error) { return float32(v), nil }),
+	opcodes.F32ConvertI32U: conversion(func(v int32) (float32, error) { return float32(uint32(v)), nil }),
+	opcodes.F32ConvertI64S: conversion(func(v int64) (float32, error) { return float32(v), nil }),
+	opcodes.F32ConvertI64U: conversion(func(v int64) (float32, error) { return float32(uint64(v)), nil }),
+	opcodes.F32DemoteF64:   conversion(func(v float64) (float32, error) { return float32(v), nil }),
+
+	opcodes.F64ConvertI32S: conversion(func(v int32) (float64, error) { return float64(v), nil }),
+	opcodes.F64ConvertI32U: conversion(func(v int32) (float64, error) { return float64(uint32(v)), nil }),
+	opcodes.F64ConvertI64S: conversion(func(v int64) (float64, error) { return float64(v), nil }),
+	opcodes.F64ConvertI64U: conversion(func(v int64) (float64, error) { return float64(uint64(v)), nil }),
+	opcodes.F64PromoteF32:  conversion(func(v float32) (float64, error) { return float64(v), nil }),
+
+	opcodes.I32ReinterpretF32: conversion(func(v float32) (int32, error) { return int32(math.Float32bits(v)), nil }),
+	opcodes.I64ReinterpretF64: conversion(func(v float64) (int64, error) { return int64(math.Float64bits(v)), nil }),
+	opcodes.F32ReinterpretI32: conversion(func(v int32) (float32, error) { return math.Float32frombits(uint32(v)), nil }),
+	opcodes.F64ReinterpretI64: conversion(func(v int64) (float64, error) { return math.Float64frombits(uint64(v)), nil }),
+}
+
+// conversion returns the function that replaces the
+// value on top of the stack by its converted value
+func conversion[T, R any](fn func(T) (R, error)) func(*Stack, opcodes.OpCode) error {
+	return func(s *Stack, op opcodes.OpCode) error {
+		operand, err := popEnsureType[T](s)
+		if err != nil {
+			return fmt.Errorf("%s: cannot pop: %w", op, err)
+		}
+
+		result, err := fn(operand)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		return s.push(StackValue{
+			value: result,
+		})
+	}
+}
+
+// truncate discards the fractional part of v, it traps when v is a NaN
+// or when the truncated value is not within [lo, hi), the range of R
+func truncate[R int32 | int64 | uint32 | uint64](v, lo, hi float64) (R, error) {
+	if math.IsNaN(v) {
+		return 0, ErrInvalidConversion
+	}
+
+	truncated := math.Trunc(v)
+	if truncated < lo || truncated >= hi {
+		return 0, ErrIntegerOverflow
+	}
+
+	return R(truncated), nil
+}
+
+// truncateSat discards the fractional part of v, a NaN results in 0
+// and the values out of the range of R are clamped to its bounds
+func truncateSat[R int32 | int64 | uint32 | uint64](v float64, min, max R) R {
+	truncated := math.Trunc(v)
+	switch {
+	case math.IsNaN(v):
+		return 0
+	case truncated <= float64(min):
+		return min
+	case truncated >= float64(max):
+		return max
+	}
+
+	return R(truncated)
+}
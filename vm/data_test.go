@@ -0,0 +1,72 @@
+package vm_test
+
+import (
+	"testing"
+
+	"github.com/EclesioMeloJunior/wasvm/parser"
+	"github.com/EclesioMeloJunior/wasvm/vm"
+	"github.com/stretchr/testify/require"
+)
+
+const dataWasm = "../resources/data.wasm"
+
+func TestDataWasm_ActiveSegments(t *testing.T) {
+	binaryWASM, err := parser.BinaryFormat(dataWasm)
+	require.NoError(t, err)
+
+	rt, err := vm.NewRuntime(binaryWASM)
+	require.NoError(t, err)
+
+	contents, err := rt.Memory().Read(16, 5)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(contents))
+
+	results, err := rt.Exported["load8_u"].Call(int32(16))
+	require.NoError(t, err)
+	require.Equal(t, []any{int32('h')}, results)
+}
+
+func TestDataWasm_MemoryInitAndDataDrop(t *testing.T) {
+	binaryWASM, err := parser.BinaryFormat(dataWasm)
+	require.NoError(t, err)
+
+	rt, err := vm.NewRuntime(binaryWASM)
+	require.NoError(t, err)
+
+	// copies "orld" from the passive segment to the address 100
+	_, err = rt.Exported["init"].Call(int32(100), int32(1), int32(4))
+	require.NoError(t, err)
+
+	contents, err := rt.Memory().Read(100, 4)
+	require.NoError(t, err)
+	require.Equal(t, "orld", string(contents))
+
+	_, err = rt.Exported["init"].Call(int32(100), int32(4), int32(3))
+	require.ErrorIs(t, err, vm.ErrOutOfBoundsMemoryAccess)
+
+	_, err = rt.Exported["init"].Call(int32(vm.PageSize-2), int32(0), int32(3))
+	require.ErrorIs(t, err, vm.ErrOutOfBoundsMemoryAccess)
+
+	_, err = rt.Exported["drop"].Call()
+	require.NoError(t, err)
+
+	// a dropped segment behaves as an empty one
+	_, err = rt.Exported["init"].Call(int32(0), int32(0), int32(0))
+	require.NoError(t, err)
+
+	_, err = rt.Exported["init"].Call(int32(0), int32(0), int32(1))
+	require.ErrorIs(t, err, vm.ErrOutOfBoundsMemoryAccess)
+}
+
+func TestDataSegment_OutOfBoundsFailsInstantiation(t *testing.T) {
+	// (module (memory 1) (data (i32.const 65534) "abc"))
+	binaryWASM, err := parser.Decode([]byte{
+		0x00, 0x61, 0x73, 0x6D, 0x01, 0x00, 0x00, 0x00,
+		0x05, 0x03, 0x01, 0x00, 0x01,
+		0x0B, 0x0B, 0x01, 0x00, 0x41, 0xFE, 0xFF, 0x03, 0x0B, 0x03, 0x61, 0x62, 0x63,
+	})
+	require.NoError(t, err)
+
+	_, err = vm.NewRuntime(binaryWASM)
+	require.ErrorIs(t, err, vm.ErrOutOfBoundsMemoryAccess)
+}
@@ -0,0 +1,122 @@
+package vm
+
+import (
+	"math"
+
+	"github.com/EclesioMeloJunior/wasvm/opcodes"
+)
+
+const (
+	f32SignBit = uint32(1) << 31
+	f64SignBit = uint64(1) << 63
+)
+
+type float interface {
+	float32 | float64
+}
+
+// f32UnaryOps are the f32 instructions that take a single operand, abs, neg
+// and copysign only touch the sign bit so the NaN payloads are kept, the
+// rounding ones are exact when computed over the f64 value of the operand
+var f32UnaryOps = map[opcodes.OpCode]func(float32) float32{
+	opcodes.F32Abs:     func(v float32) float32 { return math.Float32frombits(math.Float32bits(v) &^ f32SignBit) },
+	opcodes.F32Neg:     func(v float32) float32 { return math.Float32frombits(math.Float32bits(v) ^ f32SignBit) },
+	opcodes.F32Ceil:    func(v float32) float32 { return float32(math.Ceil(float64(v))) },
+	opcodes.F32Floor:   func(v float32) float32 { return float32(math.Floor(float64(v))) },
+	opcodes.F32Trunc:   func(v float32) float32 { return float32(math.Trunc(float64(v))) },
+	opcodes.F32Nearest: func(v float32) float32 { return float32(math.RoundToEven(float64(v))) },
+	opcodes.F32Sqrt:    func(v float32) float32 { return float32(math.Sqrt(float64(v))) },
+}
+
+// f32Comparisons are the f32 instructions that push an i32 boolean,
+// any comparison against a NaN is false except for ne
+var f32Comparisons = map[opcodes.OpCode]func(lhs, rhs float32) (int32, error){
+	opcodes.F32Eq: func(lhs, rhs float32) (int32, error) { return boolToI32(lhs == rhs), nil },
+	opcodes.F32Ne: func(lhs, rhs float32) (int32, error) { return boolToI32(lhs != rhs), nil },
+	opcodes.F32Lt: func(lhs, rhs float32) (int32, error) { return boolToI32(lhs < rhs), nil },
+	opcodes.F32Gt: func(lhs, rhs float32) (int32, error) { return boolToI32(lhs > rhs), nil },
+	opcodes.F32Le: func(lhs, rhs float32) (int32, error) { return boolToI32(lhs <= rhs), nil },
+	opcodes.F32Ge: func(lhs, rhs float32) (int32, error) { return boolToI32(lhs >= rhs), nil },
+}
+
+// f32BinaryOps are the f32 instructions that take two operands, none of
+// them trap, a division by zero results in an infinity or in a NaN
+var f32BinaryOps = map[opcodes.OpCode]func(lhs, rhs float32) (float32, error){
+	opcodes.F32Add: func(lhs, rhs float32) (float32, error) { return lhs + rhs, nil },
+	opcodes.F32Sub: func(lhs, rhs float32) (float32, error) { return lhs - rhs, nil },
+	opcodes.F32Mul: func(lhs, rhs float32) (float32, error) { return lhs * rhs, nil },
+	opcodes.F32Div: func(lhs, rhs float32) (float32, error) { return lhs / rhs, nil },
+	opcodes.F32Min: func(lhs, rhs float32) (float32, error) { return floatMin(lhs, rhs), nil },
+	opcodes.F32Max: func(lhs, rhs float32) (float32, error) { return floatMax(lhs, rhs), nil },
+	opcodes.F32Copysign: func(lhs, rhs float32) (float32, error) {
+		return math.Float32frombits(math.Float32bits(lhs)&^f32SignBit | math.Float32bits(rhs)&f32SignBit), nil
+	},
+}
+
+// f64UnaryOps are the f64 instructions that take a single operand
+var f64UnaryOps = map[opcodes.OpCode]func(float64) float64{
+	opcodes.F64Abs:     math.Abs,
+	opcodes.F64Neg:     func(v float64) float64 { return math.Float64frombits(math.Float64bits(v) ^ f64SignBit) },
+	opcodes.F64Ceil:    math.Ceil,
+	opcodes.F64Floor:   math.Floor,
+	opcodes.F64Trunc:   math.Trunc,
+	opcodes.F64Nearest: math.RoundToEven,
+	opcodes.F64Sqrt:    math.Sqrt,
+}
+
+// f64Comparisons are the f64 instructions that push an i32 boolean
+var f64Comparisons = map[opcodes.OpCode]func(lhs, rhs float64) (int32, error){
+	opcodes.F64Eq: func(lhs, rhs float64) (int32, error) { return boolToI32(lhs == rhs), nil },
+	opcodes.F64Ne: func(lhs, rhs float64) (int32, error) { return boolToI32(lhs != rhs), nil },
+	opcodes.F64Lt: func(lhs, rhs float64) (int32, error) { return boolToI32(lhs < rhs), nil },
+	opcodes.F64Gt: func(lhs, rhs float64) (int32, error) { return boolToI32(lhs > rhs), nil },
+	opcodes.F64Le: func(lhs, rhs float64) (int32, error) { return boolToI32(lhs <= rhs), nil },
+	opcodes.F64Ge: func(lhs, rhs float64) (int32, error) { return boolToI32(lhs >= rhs), nil },
+}
+
+// f64BinaryOps are the f64 instructions that take two operands
+var f64BinaryOps = map[opcodes.OpCode]func(lhs, rhs float64) (float64, error){
+	opcodes.F64Add:      func(lhs, rhs float64) (float64, error) { return lhs + rhs, nil },
+	opcodes.F64Sub:      func(lhs, rhs float64) (float64, error) { return lhs - rhs, nil },
+	opcodes.F64Mul:      func(lhs, rhs float64) (float64, error) { return lhs * rhs, nil },
+	opcodes.F64Div:      func(lhs, rhs float64) (float64, error) { return lhs / rhs, nil },
+	opcodes.F64Min:      func(lhs, rhs float64) (float64, error) { return floatMin(lhs, rhs), nil },
+	opcodes.F64Max:      func(lhs, rhs float64) (float64, error) { return floatMax(lhs, rhs), nil },
+	opcodes.F64Copysign: func(lhs, rhs float64) (float64, error) { return math.Copysign(lhs, rhs), nil },
+}
+
+// floatMin returns a NaN when any of the operands is a NaN,
+// and -0 is taken as lower than 0 unlike in the comparisons
+func floatMin[F float](lhs, rhs F) F {
+	switch {
+	case lhs != lhs || rhs != rhs:
+		return lhs + rhs
+	case lhs == 0 && rhs == 0:
+		if math.Signbit(float64(lhs)) {
+			return lhs
+		}
+		return rhs
+	case lhs < rhs:
+		return lhs
+	}
+
+	return rhs
+}
+
+// floatMax returns a NaN when any of the operands is a NaN,
+// and 0 is taken as greater than -0 unlike in the comparisons
+func floatMax[F float](lhs, rhs F) F {
+	switch {
+	case lhs != lhs || rhs != rhs:
+		return lhs + rhs
+	case lhs == 0 && rhs == 0:
+		if math.Signbit(float64(lhs)) {
+			return rhs
+		}
+		return lhs
+	case lhs > rhs:
+		return lhs
+	}
+
+	return rhs
+}
@@ -0,0 +1,116 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/EclesioMeloJunior/wasvm/opcodes"
+	"github.com/EclesioMeloJunior/wasvm/parser"
+)
+
+var ErrGlobalIndexOutOfBounds = errors.New("global index out of bounds")
+
+// Global holds the value of a global variable, it can be
+// shared between the host and the instances that import it
+type Global struct {
+	Type  parser.GlobalType
+	value any
+}
+
+func NewGlobal(globalType parser.GlobalType, value any) (*Global, error) {
+	if !valueMatchesType(value, globalType.ValType) {
+		return nil, fmt.Errorf("%w: expected %s global value, got %T",
+			ErrWrongType, globalType.ValType, value)
+	}
+
+	return &Global{Type: globalType, value: value}, nil
+}
+
+func (g *Global) Get() any {
+	return g.value
+}
+
+// Set changes the global value, the global must be mutable
+// and the value must match the global value type
+func (g *Global) Set(value any) error {
+	if !g.Type.Mutable {
+		return parser.ErrImmutableGlobal
+	}
+
+	if !valueMatchesType(value, g.Type.ValType) {
+		return fmt.Errorf("%w: expected %s global value, got %T",
+			ErrWrongType, g.Type.ValType, value)
+	}
+
+	g.value = value
+	return nil
+}
+
+func (rt *Runtime) global(idx uint) (*Global, error) {
+	return globalAt(rt.globals, idx)
+}
+
+func globalAt(globals []*Global, idx uint) (*Global, error) {
+	if idx >= uint(len(globals)) {
+		return nil, fmt.Errorf("%w: %d", ErrGlobalIndexOutOfBounds, idx)
+	}
+
+	return globals[idx], nil
+}
+
+// evaluateConstantExpression computes the value produced by expr, global.get
+// can only refer to the given globals while ref.func can refer to any of
+// the functionsLen functions of the function index space
+func evaluateConstantExpression(globals []*Global, functionsLen int, expr []byte) (any, error) {
+	instructions, err := parser.DecodeInstructions(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", parser.ErrInvalidConstantExpression, err)
+	}
+
+	stack := make(Stack, 0, 1)
+	for _, inst := range instructions {
+		var value any
+		switch inst.Opcode {
+		case opcodes.End:
+			if len(stack) != 1 {
+				return nil, fmt.Errorf("%w: expected 1 value, got %d",
+					parser.ErrInvalidConstantExpression, len(stack))
+			}
+
+			return stack[0].value, nil
+		case opcodes.I32Const:
+			value = inst.I32
+		case opcodes.I64Const:
+			value = inst.I64
+		case opcodes.F32Const:
+			value = math.Float32frombits(inst.F32)
+		case opcodes.F64Const:
+			value = math.Float64frombits(inst.F64)
+		case opcodes.GlobalGet:
+			global, err := globalAt(globals, uint(inst.Index))
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %s", parser.ErrInvalidConstantExpression, inst.Opcode, err)
+			}
+
+			value = global.Get()
+		case opcodes.RefNull:
+		case opcodes.RefFunc:
+			if int(inst.Index) >= functionsLen {
+				return nil, fmt.Errorf("%w: %s: %s: %d", parser.ErrInvalidConstantExpression,
+					inst.Opcode, parser.ErrFunctionIndexOutOfBounds, inst.Index)
+			}
+
+			value = funcRef(inst.Index)
+		default:
+			return nil, fmt.Errorf("%w: unexpected instruction %s",
+				parser.ErrInvalidConstantExpression, inst.Opcode)
+		}
+
+		if err := stack.push(StackValue{value: value}); err != nil {
+			return nil, fmt.Errorf("%w: %s", parser.ErrInvalidConstantExpression, err)
+		}
+	}
+
+	return nil, fmt.Errorf("%w: missing end", parser.ErrInvalidConstantExpression)
+}
@@ -0,0 +1,77 @@
+package vm_test
+
+import (
+	"testing"
+
+	"github.com/EclesioMeloJunior/wasvm/parser"
+	"github.com/EclesioMeloJunior/wasvm/vm"
+	"github.com/stretchr/testify/require"
+)
+
+const globalWasm = "../resources/global.wasm"
+
+func TestGlobalWasm_GetAndSet(t *testing.T) {
+	binaryWASM, err := parser.BinaryFormat(globalWasm)
+	require.NoError(t, err)
+
+	base, err := vm.NewGlobal(parser.GlobalType{ValType: parser.I32}, int32(41))
+	require.NoError(t, err)
+
+	linker := vm.NewLinker()
+	linker.DefineGlobal("env", "base", base)
+
+	rt, err := vm.NewRuntimeWithLinker(binaryWASM, linker)
+	require.NoError(t, err)
+
+	// $counter is initialized with the imported global value
+	counter, ok := rt.ExportedGlobal("counter")
+	require.True(t, ok)
+	require.Equal(t, int32(41), counter.Get())
+
+	results, err := rt.Exported["increment"].Call()
+	require.NoError(t, err)
+	require.Equal(t, []any{int32(42)}, results)
+	require.Equal(t, int32(42), counter.Get())
+
+	// the host writes are seen by the module
+	require.NoError(t, counter.Set(int32(100)))
+	results, err = rt.Exported["increment"].Call()
+	require.NoError(t, err)
+	require.Equal(t, []any{int32(101)}, results)
+
+	results, err = rt.Exported["big"].Call()
+	require.NoError(t, err)
+	require.Equal(t, []any{int64(1234567890123)}, results)
+}
+
+func TestGlobalWasm_ImportErrors(t *testing.T) {
+	binaryWASM, err := parser.BinaryFormat(globalWasm)
+	require.NoError(t, err)
+
+	_, err = vm.NewRuntime(binaryWASM)
+	require.ErrorIs(t, err, vm.ErrUnresolvedImport)
+
+	mutableBase, err := vm.NewGlobal(parser.GlobalType{ValType: parser.I32, Mutable: true}, int32(1))
+	require.NoError(t, err)
+
+	linker := vm.NewLinker()
+	linker.DefineGlobal("env", "base", mutableBase)
+
+	_, err = vm.NewRuntimeWithLinker(binaryWASM, linker)
+	require.ErrorIs(t, err, vm.ErrIncompatibleImport)
+}
+
+func TestGlobal_TypeAndMutability(t *testing.T) {
+	_, err := vm.NewGlobal(parser.GlobalType{ValType: parser.I64}, int32(1))
+	require.ErrorIs(t, err, vm.ErrWrongType)
+
+	immutable, err := vm.NewGlobal(parser.GlobalType{ValType: parser.I64}, int64(1))
+	require.NoError(t, err)
+	require.ErrorIs(t, immutable.Set(int64(2)), parser.ErrImmutableGlobal)
+
+	mutable, err := vm.NewGlobal(parser.GlobalType{ValType: parser.F64, Mutable: true}, float64(1))
+	require.NoError(t, err)
+	require.ErrorIs(t, mutable.Set(float32(2)), vm.ErrWrongType)
+	require.NoError(t, mutable.Set(float64(2)))
+	require.Equal(t, float64(2), mutable.Get())
+}
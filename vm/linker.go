@@ -0,0 +1,158 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/EclesioMeloJunior/wasvm/parser"
+)
+
+var (
+	ErrUnresolvedImport   = errors.New("unresolved import")
+	ErrIncompatibleImport = errors.New("incompatible import")
+)
+
+// HostFunction is a Go function that can be imported by a wasm module,
+// rt is the instance that is calling the function
+type HostFunction func(rt *Runtime, params ...any) ([]any, error)
+
+type hostFunction struct {
+	module, name string
+	signature    *parser.FunctionSignatureParser
+	fn           HostFunction
+}
+
+func (h *hostFunction) call(rt *Runtime, params ...any) ([]any, error) {
+	if len(params) != len(h.signature.ParamsTypes) {
+		return nil, fmt.Errorf("host function %s.%s: %w: expected %d params, got %d",
+			h.module, h.name, ErrParamOutOfBounds, len(h.signature.ParamsTypes), len(params))
+	}
+
+	for idx, param := range params {
+		if !valueMatchesType(param, h.signature.ParamsTypes[idx]) {
+			return nil, fmt.Errorf("host function %s.%s: %w: expected %s param, got %T",
+				h.module, h.name, ErrWrongType, h.signature.ParamsTypes[idx], param)
+		}
+	}
+
+	results, err := h.fn(rt, params...)
+	if err != nil {
+		return nil, fmt.Errorf("host function %s.%s: %w", h.module, h.name, err)
+	}
+
+	if len(results) != len(h.signature.ResultsTypes) {
+		return nil, fmt.Errorf("host function %s.%s: expected %d results, got %d",
+			h.module, h.name, len(h.signature.ResultsTypes), len(results))
+	}
+
+	for idx, result := range results {
+		if !valueMatchesType(result, h.signature.ResultsTypes[idx]) {
+			return nil, fmt.Errorf("host function %s.%s: %w: expected %s result, got %T",
+				h.module, h.name, ErrWrongType, h.signature.ResultsTypes[idx], result)
+		}
+	}
+
+	return results, nil
+}
+
+// Linker holds the values provided by the host that are
+// used to satisfy the module imports while instantiating it
+type Linker struct {
+	functions map[string]map[string]*hostFunction
+	globals   map[string]map[string]*Global
+}
+
+func NewLinker() *Linker {
+	return &Linker{
+		functions: make(map[string]map[string]*hostFunction),
+		globals:   make(map[string]map[string]*Global),
+	}
+}
+
+// DefineFunction registers fn under module.name, the signature is checked
+// against the one the module expects when the import is resolved
+func (l *Linker) DefineFunction(module, name string,
+	signature *parser.FunctionSignatureParser, fn HostFunction) {
+	if _, ok := l.functions[module]; !ok {
+		l.functions[module] = make(map[string]*hostFunction)
+	}
+
+	l.functions[module][name] = &hostFunction{
+		module:    module,
+		name:      name,
+		signature: signature,
+		fn:        fn,
+	}
+}
+
+func (l *Linker) resolveFunction(imported *parser.Function) (*hostFunction, error) {
+	module, name := imported.Import.Module, imported.Import.Name
+
+	host, ok := l.functions[module][name]
+	if !ok {
+		return nil, fmt.Errorf("%w: function %s.%s", ErrUnresolvedImport, module, name)
+	}
+
+	if !host.signature.Equal(imported.Signature) {
+		return nil, fmt.Errorf("%w: function %s.%s expected %s, got %s",
+			ErrIncompatibleImport, module, name, imported.Signature, host.signature)
+	}
+
+	return host, nil
+}
+
+// DefineGlobal registers the global under module.name, the instances
+// that import it share the same value with the host
+func (l *Linker) DefineGlobal(module, name string, global *Global) {
+	if _, ok := l.globals[module]; !ok {
+		l.globals[module] = make(map[string]*Global)
+	}
+
+	l.globals[module][name] = global
+}
+
+func (l *Linker) resolveGlobal(imported *parser.Import) (*Global, error) {
+	module, name := imported.Module, imported.Name
+
+	global, ok := l.globals[module][name]
+	if !ok {
+		return nil, fmt.Errorf("%w: global %s.%s", ErrUnresolvedImport, module, name)
+	}
+
+	if global.Type.ValType.SpecByte != imported.Global.ValType.SpecByte ||
+		global.Type.Mutable != imported.Global.Mutable {
+		return nil, fmt.Errorf("%w: global %s.%s expected %s, got %s",
+			ErrIncompatibleImport, module, name,
+			globalTypeString(*imported.Global), globalTypeString(global.Type))
+	}
+
+	return global, nil
+}
+
+func globalTypeString(globalType parser.GlobalType) string {
+	if globalType.Mutable {
+		return fmt.Sprintf("(mut %s)", globalType.ValType)
+	}
+
+	return globalType.ValType.String()
+}
+
+func valueMatchesType(value any, t parser.Type) bool {
+	switch value.(type) {
+	case int32:
+		return t.SpecByte == parser.I32_NUM_TYPE
+	case int64:
+		return t.SpecByte == parser.I64_NUM_TYPE
+	case float32:
+		return t.SpecByte == parser.F32_NUM_TYPE
+	case float64:
+		return t.SpecByte == parser.F64_NUM_TYPE
+	case nil:
+		return t.SpecType == parser.RefType
+	case funcRef:
+		return t.SpecByte == parser.FUNC_REF_TYPE
+	}
+
+	// any other host value is an external reference
+	return t.SpecByte == parser.EXTERN_REF_TYPE
+}
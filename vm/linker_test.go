@@ -0,0 +1,128 @@
+package vm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/EclesioMeloJunior/wasvm/parser"
+	"github.com/EclesioMeloJunior/wasvm/vm"
+	"github.com/stretchr/testify/require"
+)
+
+const hostCallWasm = "../resources/host_call.wasm"
+
+func newHostCallLinker(logged *[]int32) *vm.Linker {
+	linker := vm.NewLinker()
+	linker.DefineFunction("env", "add",
+		parser.NewFunctionSignature([]parser.Type{parser.I32, parser.I32}, []parser.Type{parser.I32}),
+		func(rt *vm.Runtime, params ...any) ([]any, error) {
+			return []any{params[0].(int32) + params[1].(int32)}, nil
+		})
+
+	linker.DefineFunction("env", "log",
+		parser.NewFunctionSignature([]parser.Type{parser.I32}, nil),
+		func(rt *vm.Runtime, params ...any) ([]any, error) {
+			*logged = append(*logged, params[0].(int32))
+			return nil, nil
+		})
+
+	return linker
+}
+
+func TestLinker_HostFunctionCall(t *testing.T) {
+	binaryWASM, err := parser.BinaryFormat(hostCallWasm)
+	require.NoError(t, err)
+
+	var logged []int32
+	rt, err := vm.NewRuntimeWithLinker(binaryWASM, newHostCallLinker(&logged))
+	require.NoError(t, err)
+
+	results, err := rt.Exported["log_sum"].Call(int32(40), int32(2))
+	require.NoError(t, err)
+	require.Empty(t, results)
+	require.Equal(t, []int32{42}, logged)
+
+	// host functions can be exported back by the module
+	results, err = rt.Exported["add"].Call(int32(1), int32(2))
+	require.NoError(t, err)
+	require.Equal(t, []any{int32(3)}, results)
+}
+
+func TestLinker_ResolveErrors(t *testing.T) {
+	binaryWASM, err := parser.BinaryFormat(hostCallWasm)
+	require.NoError(t, err)
+
+	_, err = vm.NewRuntime(binaryWASM)
+	require.ErrorIs(t, err, vm.ErrUnresolvedImport)
+	require.Contains(t, err.Error(), "env.add")
+
+	linker := vm.NewLinker()
+	linker.DefineFunction("env", "add",
+		parser.NewFunctionSignature([]parser.Type{parser.I64, parser.I64}, []parser.Type{parser.I64}),
+		func(rt *vm.Runtime, params ...any) ([]any, error) { return nil, nil })
+
+	_, err = vm.NewRuntimeWithLinker(binaryWASM, linker)
+	require.ErrorIs(t, err, vm.ErrIncompatibleImport)
+}
+
+func TestLinker_HostFunctionErrors(t *testing.T) {
+	binaryWASM, err := parser.BinaryFormat(hostCallWasm)
+	require.NoError(t, err)
+
+	errHost := errors.New("host failure")
+
+	var logged []int32
+	linker := newHostCallLinker(&logged)
+	linker.DefineFunction("env", "log",
+		parser.NewFunctionSignature([]parser.Type{parser.I32}, nil),
+		func(rt *vm.Runtime, params ...any) ([]any, error) {
+			require.NotNil(t, rt)
+			return nil, errHost
+		})
+
+	rt, err := vm.NewRuntimeWithLinker(binaryWASM, linker)
+	require.NoError(t, err)
+
+	_, err = rt.Exported["log_sum"].Call(int32(1), int32(2))
+	require.ErrorIs(t, err, errHost)
+
+	linker.DefineFunction("env", "add",
+		parser.NewFunctionSignature([]parser.Type{parser.I32, parser.I32}, []parser.Type{parser.I32}),
+		func(rt *vm.Runtime, params ...any) ([]any, error) {
+			return []any{int64(1)}, nil
+		})
+
+	rt, err = vm.NewRuntimeWithLinker(binaryWASM, linker)
+	require.NoError(t, err)
+
+	_, err = rt.Exported["add"].Call(int32(1), int32(2))
+	require.ErrorIs(t, err, vm.ErrWrongType)
+
+	// the params are checked before the host function is called
+	_, err = rt.Exported["add"].Call(int32(1))
+	require.ErrorIs(t, err, vm.ErrParamOutOfBounds)
+
+	_, err = rt.Exported["add"].Call(int32(1), int64(2))
+	require.ErrorIs(t, err, vm.ErrWrongType)
+	require.Contains(t, err.Error(), "param")
+}
+
+func TestLinker_ReentrantCall(t *testing.T) {
+	binaryWASM, err := parser.BinaryFormat("../resources/reentrant.wasm")
+	require.NoError(t, err)
+
+	linker := vm.NewLinker()
+	linker.DefineFunction("env", "fac",
+		parser.NewFunctionSignature([]parser.Type{parser.I32}, []parser.Type{parser.I32}),
+		func(rt *vm.Runtime, params ...any) ([]any, error) {
+			// calls the export back while the outer call is still running
+			return rt.Exported["fac"].Call(params...)
+		})
+
+	rt, err := vm.NewRuntimeWithLinker(binaryWASM, linker)
+	require.NoError(t, err)
+
+	results, err := rt.Exported["fac"].Call(int32(3))
+	require.NoError(t, err)
+	require.Equal(t, []any{int32(6)}, results)
+}
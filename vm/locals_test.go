@@ -0,0 +1,50 @@
+package vm_test
+
+import (
+	"testing"
+
+	"github.com/EclesioMeloJunior/wasvm/parser"
+	"github.com/EclesioMeloJunior/wasvm/vm"
+	"github.com/stretchr/testify/require"
+)
+
+const localsWasm = "../resources/locals.wasm"
+
+func TestLocalsWasm(t *testing.T) {
+	binaryWASM, err := parser.BinaryFormat(localsWasm)
+	require.NoError(t, err)
+
+	rt, err := vm.NewRuntime(binaryWASM)
+	require.NoError(t, err)
+
+	results, err := rt.Exported["max"].Call(int32(3), int32(9))
+	require.NoError(t, err)
+	require.Equal(t, []any{int32(9)}, results)
+
+	// the if branch sets $max with local.tee
+	results, err = rt.Exported["max"].Call(int32(12), int32(9))
+	require.NoError(t, err)
+	require.Equal(t, []any{int32(12)}, results)
+
+	results, err = rt.Exported["zero_i64"].Call()
+	require.NoError(t, err)
+	require.Equal(t, []any{int64(0)}, results)
+
+	results, err = rt.Exported["zero_f64"].Call()
+	require.NoError(t, err)
+	require.Equal(t, []any{float64(0)}, results)
+}
+
+func TestCall_ChecksParams(t *testing.T) {
+	binaryWASM, err := parser.BinaryFormat(localsWasm)
+	require.NoError(t, err)
+
+	rt, err := vm.NewRuntime(binaryWASM)
+	require.NoError(t, err)
+
+	_, err = rt.Exported["max"].Call(int32(3))
+	require.ErrorIs(t, err, vm.ErrParamOutOfBounds)
+
+	_, err = rt.Exported["max"].Call(int32(3), int64(9))
+	require.ErrorIs(t, err, vm.ErrWrongType)
+}
@@ -0,0 +1,307 @@
+package vm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/EclesioMeloJunior/wasvm/opcodes"
+	"github.com/EclesioMeloJunior/wasvm/parser"
+)
+
+const (
+	// PageSize is the size of a single page of the linear memory (64KiB)
+	PageSize = 65536
+	// MaxPages is the maximum number of pages a 32-bit memory can address
+	MaxPages = 65536
+)
+
+var (
+	ErrOutOfBoundsMemoryAccess = errors.New("out of bounds memory access")
+	ErrMemoryAlignment         = errors.New("alignment must not be larger than natural")
+	ErrInvalidMemoryLimits     = errors.New("invalid memory limits")
+	ErrDataIndexOutOfBounds    = errors.New("data index out of bounds")
+)
+
+// Memory is the byte addressable linear memory of an instance
+// MaxMemoryPages is the maximum amount of pages a memory can start with or
+// grow to, it protects the host from allocating huge memories declared by
+// a module and can be raised, up to MaxPages, by hosts that can afford it
+var MaxMemoryPages uint32 = 16384
+
+type Memory struct {
+	data []byte
+	max  uint32
+}
+
+func newMemory(memoryType *parser.MemoryType) (*Memory, error) {
+	max := uint32(MaxPages)
+	if memoryType.Limits.HasMax && memoryType.Limits.Max < max {
+		max = memoryType.Limits.Max
+	}
+
+	if memoryType.Limits.Min > max {
+		return nil, fmt.Errorf("%w: memory min %d pages greater than max %d pages",
+			ErrInvalidMemoryLimits, memoryType.Limits.Min, max)
+	}
+
+	if memoryType.Limits.Min > MaxMemoryPages {
+		return nil, fmt.Errorf("%w: memory min %d pages greater than the supported %d pages",
+			ErrInvalidMemoryLimits, memoryType.Limits.Min, MaxMemoryPages)
+	}
+
+	// the memory can still be declared with a greater max, growing past
+	// the supported pages fails as if the memory reached its max
+	if max > MaxMemoryPages {
+		max = MaxMemoryPages
+	}
+
+	return &Memory{
+		data: make([]byte, uint64(memoryType.Limits.Min)*PageSize),
+		max:  max,
+	}, nil
+}
+
+// Size returns the amount of pages of the memory
+func (m *Memory) Size() uint32 {
+	return uint32(len(m.data) / PageSize)
+}
+
+// Grow increases the memory by delta pages, returns the previous
+// amount of pages or -1 if the memory cannot grow that much
+func (m *Memory) Grow(delta uint32) int32 {
+	previous := m.Size()
+	if uint64(previous)+uint64(delta) > uint64(m.max) {
+		return -1
+	}
+
+	m.data = append(m.data, make([]byte, uint64(delta)*PageSize)...)
+	return int32(previous)
+}
+
+// Bytes exposes the whole memory contents, the returned slice
+// is no longer valid after the memory grows
+func (m *Memory) Bytes() []byte {
+	return m.data
+}
+
+// Read returns a copy of n bytes starting at offset
+func (m *Memory) Read(offset, n uint32) ([]byte, error) {
+	contents, err := m.slice(uint64(offset), uint64(n))
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(nil), contents...), nil
+}
+
+// Write copies the contents into the memory starting at offset
+func (m *Memory) Write(offset uint32, contents []byte) error {
+	dst, err := m.slice(uint64(offset), uint64(len(contents)))
+	if err != nil {
+		return err
+	}
+
+	copy(dst, contents)
+	return nil
+}
+
+func (m *Memory) slice(effectiveAddress, n uint64) ([]byte, error) {
+	if effectiveAddress+n > uint64(len(m.data)) {
+		return nil, fmt.Errorf("%w: address %d, size %d, memory size %d",
+			ErrOutOfBoundsMemoryAccess, effectiveAddress, n, len(m.data))
+	}
+
+	return m.data[effectiveAddress : effectiveAddress+n], nil
+}
+
+// dataInstance is the runtime version of a data segment, active
+// segments are dropped once they are copied into the memory
+type dataInstance struct {
+	bytes   []byte
+	dropped bool
+}
+
+func (d *dataInstance) drop() {
+	d.bytes = nil
+	d.dropped = true
+}
+
+func (rt *Runtime) dataSegment(idx uint) (*dataInstance, error) {
+	if idx >= uint(len(rt.data)) {
+		return nil, fmt.Errorf("%w: %d", ErrDataIndexOutOfBounds, idx)
+	}
+
+	return rt.data[idx], nil
+}
+
+// init copies n bytes of the data segment starting at source
+// into the memory at destination, a dropped segment has no bytes
+func (m *Memory) init(segment *dataInstance, destination, source, n uint32) error {
+	if uint64(source)+uint64(n) > uint64(len(segment.bytes)) {
+		return fmt.Errorf("%w: data offset %d, size %d, data size %d",
+			ErrOutOfBoundsMemoryAccess, source, n, len(segment.bytes))
+	}
+
+	dst, err := m.slice(uint64(destination), uint64(n))
+	if err != nil {
+		return err
+	}
+
+	copy(dst, segment.bytes[source:source+n])
+	return nil
+}
+
+// checkAlignment makes sure the memarg alignment of the
+// instruction is not greater than its natural alignment
+func checkAlignment(arg parser.MemArg, naturalAlignment uint32) error {
+	if arg.Align > 3 || 1<<arg.Align > naturalAlignment {
+		return fmt.Errorf("%w: 2^%d", ErrMemoryAlignment, arg.Align)
+	}
+
+	return nil
+}
+
+// accessWidth returns the amount of bytes read or written by the instruction
+func accessWidth(op opcodes.OpCode) uint32 {
+	switch op {
+	case opcodes.I32Load8S, opcodes.I32Load8U, opcodes.I64Load8S, opcodes.I64Load8U,
+		opcodes.I32Store8, opcodes.I64Store8:
+		return 1
+	case opcodes.I32Load16S, opcodes.I32Load16U, opcodes.I64Load16S, opcodes.I64Load16U,
+		opcodes.I32Store16, opcodes.I64Store16:
+		return 2
+	case opcodes.I32Load, opcodes.F32Load, opcodes.I64Load32S, opcodes.I64Load32U,
+		opcodes.I32Store, opcodes.F32Store, opcodes.I64Store32:
+		return 4
+	}
+
+	return 8
+}
+
+func (c *callFrame) memory() (*Memory, error) {
+	if c.rt == nil || c.rt.memory == nil {
+		return nil, fmt.Errorf("%w: 0", parser.ErrUnknownMemory)
+	}
+
+	return c.rt.memory, nil
+}
+
+func (c *callFrame) executeLoad(inst *parser.Instruction) error {
+	memory, err := c.memory()
+	if err != nil {
+		return err
+	}
+
+	op, arg := inst.Opcode, inst.MemArg
+	width := accessWidth(op)
+	if err := checkAlignment(arg, width); err != nil {
+		return err
+	}
+
+	address, err := popEnsureType[int32](&c.stack)
+	if err != nil {
+		return fmt.Errorf("cannot pop: %w", err)
+	}
+
+	raw, err := memory.slice(uint64(uint32(address))+uint64(arg.Offset), uint64(width))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var value any
+	switch op {
+	case opcodes.I32Load:
+		value = int32(binary.LittleEndian.Uint32(raw))
+	case opcodes.I64Load:
+		value = int64(binary.LittleEndian.Uint64(raw))
+	case opcodes.F32Load:
+		value = math.Float32frombits(binary.LittleEndian.Uint32(raw))
+	case opcodes.F64Load:
+		value = math.Float64frombits(binary.LittleEndian.Uint64(raw))
+	case opcodes.I32Load8S:
+		value = int32(int8(raw[0]))
+	case opcodes.I32Load8U:
+		value = int32(raw[0])
+	case opcodes.I32Load16S:
+		value = int32(int16(binary.LittleEndian.Uint16(raw)))
+	case opcodes.I32Load16U:
+		value = int32(binary.LittleEndian.Uint16(raw))
+	case opcodes.I64Load8S:
+		value = int64(int8(raw[0]))
+	case opcodes.I64Load8U:
+		value = int64(raw[0])
+	case opcodes.I64Load16S:
+		value = int64(int16(binary.LittleEndian.Uint16(raw)))
+	case opcodes.I64Load16U:
+		value = int64(binary.LittleEndian.Uint16(raw))
+	case opcodes.I64Load32S:
+		value = int64(int32(binary.LittleEndian.Uint32(raw)))
+	case opcodes.I64Load32U:
+		value = int64(binary.LittleEndian.Uint32(raw))
+	}
+
+	if err := c.stack.push(StackValue{value: value}); err != nil {
+		return fmt.Errorf("%s: cannot push: %w", inst.Opcode, err)
+	}
+
+	return nil
+}
+
+func (c *callFrame) executeStore(inst *parser.Instruction) error {
+	memory, err := c.memory()
+	if err != nil {
+		return err
+	}
+
+	op, arg := inst.Opcode, inst.MemArg
+	width := accessWidth(op)
+	if err := checkAlignment(arg, width); err != nil {
+		return err
+	}
+
+	var bits uint64
+	switch op {
+	case opcodes.I32Store, opcodes.I32Store8, opcodes.I32Store16:
+		value, err := popEnsureType[int32](&c.stack)
+		if err != nil {
+			return fmt.Errorf("cannot pop: %w", err)
+		}
+		bits = uint64(uint32(value))
+	case opcodes.I64Store, opcodes.I64Store8, opcodes.I64Store16, opcodes.I64Store32:
+		value, err := popEnsureType[int64](&c.stack)
+		if err != nil {
+			return fmt.Errorf("cannot pop: %w", err)
+		}
+		bits = uint64(value)
+	case opcodes.F32Store:
+		value, err := popEnsureType[float32](&c.stack)
+		if err != nil {
+			return fmt.Errorf("cannot pop: %w", err)
+		}
+		bits = uint64(math.Float32bits(value))
+	case opcodes.F64Store:
+		value, err := popEnsureType[float64](&c.stack)
+		if err != nil {
+			return fmt.Errorf("cannot pop: %w", err)
+		}
+		bits = math.Float64bits(value)
+	}
+
+	address, err := popEnsureType[int32](&c.stack)
+	if err != nil {
+		return fmt.Errorf("cannot pop: %w", err)
+	}
+
+	raw, err := memory.slice(uint64(uint32(address))+uint64(arg.Offset), uint64(width))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	encoded := make([]byte, 8)
+	binary.LittleEndian.PutUint64(encoded, bits)
+	copy(raw, encoded[:width])
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package vm_test
+
+import (
+	"testing"
+
+	"github.com/EclesioMeloJunior/wasvm/builder"
+	"github.com/EclesioMeloJunior/wasvm/parser"
+	"github.com/EclesioMeloJunior/wasvm/vm"
+	"github.com/stretchr/testify/require"
+)
+
+const memoryWasm = "../resources/memory.wasm"
+
+func TestMemoryWasm_LoadsAndStores(t *testing.T) {
+	binaryWASM, err := parser.BinaryFormat(memoryWasm)
+	require.NoError(t, err)
+
+	rt, err := vm.NewRuntime(binaryWASM)
+	require.NoError(t, err)
+	require.NotNil(t, rt.Memory())
+
+	call := func(name string, params ...any) []any {
+		results, err := rt.Exported[name].Call(params...)
+		require.NoError(t, err)
+		return results
+	}
+
+	call("store_i32", int32(8), int32(-2))
+	require.Equal(t, []any{int32(-2)}, call("load_i32", int32(8)))
+	require.Equal(t, []any{int32(-2)}, call("load8_s", int32(8)))
+	require.Equal(t, []any{int32(0xFE)}, call("load8_u", int32(8)))
+	require.Equal(t, []any{int32(-1)}, call("load16_s_offset", int32(8)))
+
+	raw, err := rt.Memory().Read(8, 4)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xFE, 0xFF, 0xFF, 0xFF}, raw)
+
+	call("store_i64", int32(16), int64(-0x7FFFFFFF00000001))
+	require.Equal(t, []any{int64(-0x7FFFFFFF00000001)}, call("load_i64", int32(16)))
+	require.Equal(t, []any{int64(-1)}, call("load32_s", int32(16)))
+
+	// the last 4 bytes of the page are still addressable
+	call("store_i32", int32(vm.PageSize-4), int32(1))
+	require.Equal(t, []any{int32(1)}, call("load_i32", int32(vm.PageSize-4)))
+}
+
+func TestMemoryWasm_OutOfBoundsTrap(t *testing.T) {
+	binaryWASM, err := parser.BinaryFormat(memoryWasm)
+	require.NoError(t, err)
+
+	rt, err := vm.NewRuntime(binaryWASM)
+	require.NoError(t, err)
+
+	_, err = rt.Exported["load_i32"].Call(int32(vm.PageSize - 3))
+	require.ErrorIs(t, err, vm.ErrOutOfBoundsMemoryAccess)
+
+	// the effective address must not wrap around 32 bits
+	_, err = rt.Exported["load16_s_offset"].Call(int32(-1))
+	require.ErrorIs(t, err, vm.ErrOutOfBoundsMemoryAccess)
+
+	_, err = rt.Exported["store_i64"].Call(int32(vm.PageSize-7), int64(1))
+	require.ErrorIs(t, err, vm.ErrOutOfBoundsMemoryAccess)
+}
+
+func TestMemoryWasm_SizeAndGrow(t *testing.T) {
+	binaryWASM, err := parser.BinaryFormat(memoryWasm)
+	require.NoError(t, err)
+
+	rt, err := vm.NewRuntime(binaryWASM)
+	require.NoError(t, err)
+
+	results, err := rt.Exported["size"].Call()
+	require.NoError(t, err)
+	require.Equal(t, []any{int32(1)}, results)
+
+	// memory is declared with max 2 pages
+	results, err = rt.Exported["grow"].Call(int32(2))
+	require.NoError(t, err)
+	require.Equal(t, []any{int32(-1)}, results)
+
+	results, err = rt.Exported["grow"].Call(int32(1))
+	require.NoError(t, err)
+	require.Equal(t, []any{int32(1)}, results)
+
+	results, err = rt.Exported["size"].Call()
+	require.NoError(t, err)
+	require.Equal(t, []any{int32(2)}, results)
+
+	// after growing the second page is addressable
+	_, err = rt.Exported["store_i32"].Call(int32(vm.PageSize), int32(7))
+	require.NoError(t, err)
+
+	results, err = rt.Exported["load_i32"].Call(int32(vm.PageSize))
+	require.NoError(t, err)
+	require.Equal(t, []any{int32(7)}, results)
+}
+
+func TestMemory_MinAboveSupportedPages(t *testing.T) {
+	b := builder.New()
+	b.AddMemory(parser.Limits{Min: vm.MaxMemoryPages + 1})
+
+	binaryWASM, err := b.Module()
+	require.NoError(t, err)
+
+	_, err = vm.NewRuntime(binaryWASM)
+	require.ErrorIs(t, err, vm.ErrInvalidMemoryLimits)
+}
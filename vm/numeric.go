@@ -0,0 +1,262 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/EclesioMeloJunior/wasvm/opcodes"
+)
+
+var (
+	ErrIntegerDivideByZero = errors.New("integer divide by zero")
+	ErrIntegerOverflow     = errors.New("integer overflow")
+	ErrInvalidConversion   = errors.New("invalid conversion to integer")
+)
+
+// i32UnaryOps are the i32 instructions that take a single operand
+var i32UnaryOps = map[opcodes.OpCode]func(int32) int32{
+	opcodes.I32Eqz:    func(v int32) int32 { return boolToI32(v == 0) },
+	opcodes.I32Clz:    func(v int32) int32 { return int32(bits.LeadingZeros32(uint32(v))) },
+	opcodes.I32Ctz:    func(v int32) int32 { return int32(bits.TrailingZeros32(uint32(v))) },
+	opcodes.I32Popcnt: func(v int32) int32 { return int32(bits.OnesCount32(uint32(v))) },
+}
+
+// i32BinaryOps are the i32 instructions that take two operands, lhs
+// is the one pushed first, the divisions and remainders can trap
+var i32BinaryOps = map[opcodes.OpCode]func(lhs, rhs int32) (int32, error){
+	opcodes.I32Eq:  func(lhs, rhs int32) (int32, error) { return boolToI32(lhs == rhs), nil },
+	opcodes.I32Ne:  func(lhs, rhs int32) (int32, error) { return boolToI32(lhs != rhs), nil },
+	opcodes.I32LtS: func(lhs, rhs int32) (int32, error) { return boolToI32(lhs < rhs), nil },
+	opcodes.I32LtU: func(lhs, rhs int32) (int32, error) { return boolToI32(uint32(lhs) < uint32(rhs)), nil },
+	opcodes.I32GtS: func(lhs, rhs int32) (int32, error) { return boolToI32(lhs > rhs), nil },
+	opcodes.I32GtU: func(lhs, rhs int32) (int32, error) { return boolToI32(uint32(lhs) > uint32(rhs)), nil },
+	opcodes.I32LeS: func(lhs, rhs int32) (int32, error) { return boolToI32(lhs <= rhs), nil },
+	opcodes.I32LeU: func(lhs, rhs int32) (int32, error) { return boolToI32(uint32(lhs) <= uint32(rhs)), nil },
+	opcodes.I32GeS: func(lhs, rhs int32) (int32, error) { return boolToI32(lhs >= rhs), nil },
+	opcodes.I32GeU: func(lhs, rhs int32) (int32, error) { return boolToI32(uint32(lhs) >= uint32(rhs)), nil },
+
+	opcodes.I32Add: func(lhs, rhs int32) (int32, error) { return lhs + rhs, nil },
+	opcodes.I32Sub: func(lhs, rhs int32) (int32, error) { return lhs - rhs, nil },
+	opcodes.I32Mul: func(lhs, rhs int32) (int32, error) { return lhs * rhs, nil },
+	opcodes.I32DivS: func(lhs, rhs int32) (int32, error) {
+		if rhs == 0 {
+			return 0, ErrIntegerDivideByZero
+		}
+		if lhs == math.MinInt32 && rhs == -1 {
+			return 0, ErrIntegerOverflow
+		}
+		return lhs / rhs, nil
+	},
+	opcodes.I32DivU: func(lhs, rhs int32) (int32, error) {
+		if rhs == 0 {
+			return 0, ErrIntegerDivideByZero
+		}
+		return int32(uint32(lhs) / uint32(rhs)), nil
+	},
+	opcodes.I32RemS: func(lhs, rhs int32) (int32, error) {
+		if rhs == 0 {
+			return 0, ErrIntegerDivideByZero
+		}
+		// the remainder of the overflowing division is 0 instead of a trap
+		if rhs == -1 {
+			return 0, nil
+		}
+		return lhs % rhs, nil
+	},
+	opcodes.I32RemU: func(lhs, rhs int32) (int32, error) {
+		if rhs == 0 {
+			return 0, ErrIntegerDivideByZero
+		}
+		return int32(uint32(lhs) % uint32(rhs)), nil
+	},
+
+	opcodes.I32And: func(lhs, rhs int32) (int32, error) { return lhs & rhs, nil },
+	opcodes.I32Or:  func(lhs, rhs int32) (int32, error) { return lhs | rhs, nil },
+	opcodes.I32Xor: func(lhs, rhs int32) (int32, error) { return lhs ^ rhs, nil },
+
+	// the shift counts are taken modulo the operands bit width
+	opcodes.I32Shl:  func(lhs, rhs int32) (int32, error) { return lhs << (uint32(rhs) % 32), nil },
+	opcodes.I32ShrS: func(lhs, rhs int32) (int32, error) { return lhs >> (uint32(rhs) % 32), nil },
+	opcodes.I32ShrU: func(lhs, rhs int32) (int32, error) { return int32(uint32(lhs) >> (uint32(rhs) % 32)), nil },
+	opcodes.I32Rotl: func(lhs, rhs int32) (int32, error) {
+		return int32(bits.RotateLeft32(uint32(lhs), int(uint32(rhs)%32))), nil
+	},
+	opcodes.I32Rotr: func(lhs, rhs int32) (int32, error) {
+		return int32(bits.RotateLeft32(uint32(lhs), -int(uint32(rhs)%32))), nil
+	},
+}
+
+// i64UnaryOps are the i64 instructions that take a single operand
+var i64UnaryOps = map[opcodes.OpCode]func(int64) int64{
+	opcodes.I64Clz:    func(v int64) int64 { return int64(bits.LeadingZeros64(uint64(v))) },
+	opcodes.I64Ctz:    func(v int64) int64 { return int64(bits.TrailingZeros64(uint64(v))) },
+	opcodes.I64Popcnt: func(v int64) int64 { return int64(bits.OnesCount64(uint64(v))) },
+}
+
+// i64Comparisons are the i64 instructions that push an i32 boolean
+var i64Comparisons = map[opcodes.OpCode]func(lhs, rhs int64) (int32, error){
+	opcodes.I64Eq:  func(lhs, rhs int64) (int32, error) { return boolToI32(lhs == rhs), nil },
+	opcodes.I64Ne:  func(lhs, rhs int64) (int32, error) { return boolToI32(lhs != rhs), nil },
+	opcodes.I64LtS: func(lhs, rhs int64) (int32, error) { return boolToI32(lhs < rhs), nil },
+	opcodes.I64LtU: func(lhs, rhs int64) (int32, error) { return boolToI32(uint64(lhs) < uint64(rhs)), nil },
+	opcodes.I64GtS: func(lhs, rhs int64) (int32, error) { return boolToI32(lhs > rhs), nil },
+	opcodes.I64GtU: func(lhs, rhs int64) (int32, error) { return boolToI32(uint64(lhs) > uint64(rhs)), nil },
+	opcodes.I64LeS: func(lhs, rhs int64) (int32, error) { return boolToI32(lhs <= rhs), nil },
+	opcodes.I64LeU: func(lhs, rhs int64) (int32, error) { return boolToI32(uint64(lhs) <= uint64(rhs)), nil },
+	opcodes.I64GeS: func(lhs, rhs int64) (int32, error) { return boolToI32(lhs >= rhs), nil },
+	opcodes.I64GeU: func(lhs, rhs int64) (int32, error) { return boolToI32(uint64(lhs) >= uint64(rhs)), nil },
+}
+
+// i64BinaryOps are the i64 instructions that take two operands
+// and push an i64, they follow the same rules of the i32 ones
+var i64BinaryOps = map[opcodes.OpCode]func(lhs, rhs int64) (int64, error){
+	opcodes.I64Add: func(lhs, rhs int64) (int64, error) { return lhs + rhs, nil },
+	opcodes.I64Sub: func(lhs, rhs int64) (int64, error) { return lhs - rhs, nil },
+	opcodes.I64Mul: func(lhs, rhs int64) (int64, error) { return lhs * rhs, nil },
+	opcodes.I64DivS: func(lhs, rhs int64) (int64, error) {
+		if rhs == 0 {
+			return 0, ErrIntegerDivideByZero
+		}
+		if lhs == math.MinInt64 && rhs == -1 {
+			return 0, ErrIntegerOverflow
+		}
+		return lhs / rhs, nil
+	},
+	opcodes.I64DivU: func(lhs, rhs int64) (int64, error) {
+		if rhs == 0 {
+			return 0, ErrIntegerDivideByZero
+		}
+		return int64(uint64(lhs) / uint64(rhs)), nil
+	},
+	opcodes.I64RemS: func(lhs, rhs int64) (int64, error) {
+		if rhs == 0 {
+			return 0, ErrIntegerDivideByZero
+		}
+		if rhs == -1 {
+			return 0, nil
+		}
+		return lhs % rhs, nil
+	},
+	opcodes.I64RemU: func(lhs, rhs int64) (int64, error) {
+		if rhs == 0 {
+			return 0, ErrIntegerDivideByZero
+		}
+		return int64(uint64(lhs) % uint64(rhs)), nil
+	},
+
+	opcodes.I64And: func(lhs, rhs int64) (int64, error) { return lhs & rhs, nil },
+	opcodes.I64Or:  func(lhs, rhs int64) (int64, error) { return lhs | rhs, nil },
+	opcodes.I64Xor: func(lhs, rhs int64) (int64, error) { return lhs ^ rhs, nil },
+
+	opcodes.I64Shl:  func(lhs, rhs int64) (int64, error) { return lhs << (uint64(rhs) % 64), nil },
+	opcodes.I64ShrS: func(lhs, rhs int64) (int64, error) { return lhs >> (uint64(rhs) % 64), nil },
+	opcodes.I64ShrU: func(lhs, rhs int64) (int64, error) { return int64(uint64(lhs) >> (uint64(rhs) % 64)), nil },
+	opcodes.I64Rotl: func(lhs, rhs int64) (int64, error) {
+		return int64(bits.RotateLeft64(uint64(lhs), int(uint64(rhs)%64))), nil
+	},
+	opcodes.I64Rotr: func(lhs, rhs int64) (int64, error) {
+		return int64(bits.RotateLeft64(uint64(lhs), -int(uint64(rhs)%64))), nil
+	},
+}
+
+// boolToI32 returns the i32 used by the tests and
+// comparisons, 1 when the condition holds otherwise 0
+func boolToI32(condition bool) int32 {
+	if condition {
+		return 1
+	}
+
+	return 0
+}
+
+// executeNumeric runs the numeric instruction, it tells
+// false when the instruction is not a numeric one
+func (c *callFrame) executeNumeric(op opcodes.OpCode) (bool, error) {
+	if unary, ok := i32UnaryOps[op]; ok {
+		return true, unaryOp(&c.stack, op, unary)
+	}
+
+	if binary, ok := i32BinaryOps[op]; ok {
+		return true, binaryOp(&c.stack, op, binary)
+	}
+
+	if op == opcodes.I64Eqz {
+		return true, unaryOp(&c.stack, op, func(v int64) int32 { return boolToI32(v == 0) })
+	}
+
+	if unary, ok := i64UnaryOps[op]; ok {
+		return true, unaryOp(&c.stack, op, unary)
+	}
+
+	if comparison, ok := i64Comparisons[op]; ok {
+		return true, binaryOp(&c.stack, op, comparison)
+	}
+
+	if binary, ok := i64BinaryOps[op]; ok {
+		return true, binaryOp(&c.stack, op, binary)
+	}
+
+	if unary, ok := f32UnaryOps[op]; ok {
+		return true, unaryOp(&c.stack, op, unary)
+	}
+
+	if comparison, ok := f32Comparisons[op]; ok {
+		return true, binaryOp(&c.stack, op, comparison)
+	}
+
+	if binary, ok := f32BinaryOps[op]; ok {
+		return true, binaryOp(&c.stack, op, binary)
+	}
+
+	if unary, ok := f64UnaryOps[op]; ok {
+		return true, unaryOp(&c.stack, op, unary)
+	}
+
+	if comparison, ok := f64Comparisons[op]; ok {
+		return true, binaryOp(&c.stack, op, comparison)
+	}
+
+	if binary, ok := f64BinaryOps[op]; ok {
+		return true, binaryOp(&c.stack, op, binary)
+	}
+
+	if convert, ok := conversions[op]; ok {
+		return true, convert(&c.stack, op)
+	}
+
+	return false, nil
+}
+
+func unaryOp[T, R any](s *Stack, op opcodes.OpCode, fn func(T) R) error {
+	operand, err := popEnsureType[T](s)
+	if err != nil {
+		return fmt.Errorf("%s: cannot pop: %w", op, err)
+	}
+
+	return s.push(StackValue{
+		value: fn(operand),
+	})
+}
+
+func binaryOp[T, R any](s *Stack, op opcodes.OpCode, fn func(lhs, rhs T) (R, error)) error {
+	rhs, err := popEnsureType[T](s)
+	if err != nil {
+		return fmt.Errorf("%s: cannot pop: %w", op, err)
+	}
+
+	lhs, err := popEnsureType[T](s)
+	if err != nil {
+		return fmt.Errorf("%s: cannot pop: %w", op, err)
+	}
+
+	result, err := fn(lhs, rhs)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s.push(StackValue{
+		value: result,
+	})
+}
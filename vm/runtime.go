@@ -9,47 +9,391 @@ import (
 
 var ErrCannotExportFunction = errors.New("cannot export function")
 
-type exportedFunction func(...any) any
-
 type Runtime struct {
 	binary   *parser.BinaryParser
 	Exported map[string]*callFrame
+
+	// hostFunctions are indexed by the imported function index
+	hostFunctions []*hostFunction
+
+	memory *Memory
+
+	// globals contains the imported globals followed by the defined ones
+	globals         []*Global
+	exportedGlobals map[string]*Global
+
+	tables   []*Table
+	elements []*elementInstance
+	data     []*dataInstance
+
+	// frames are indexed by the function index, they are created
+	// when the function is first called and reused by the next calls
+	frames []*callFrame
+
+	// callDepth is the amount of nested calls being executed
+	callDepth int
 }
 
+// NewRuntime instantiates a module that does not depend on host imports
 func NewRuntime(bp *parser.BinaryParser) (*Runtime, error) {
+	return NewRuntimeWithLinker(bp, NewLinker())
+}
+
+// NewRuntimeWithLinker validates and instantiates a module resolving
+// its imports against the values defined in the linker
+func NewRuntimeWithLinker(bp *parser.BinaryParser, linker *Linker) (*Runtime, error) {
+	if linker == nil {
+		linker = NewLinker()
+	}
+
+	if err := parser.Validate(bp); err != nil {
+		return nil, err
+	}
+
 	runtime := &Runtime{
 		binary: bp,
 	}
 
+	if err := resolveImports(runtime, linker); err != nil {
+		return nil, err
+	}
+
+	if err := instantiateMemory(runtime); err != nil {
+		return nil, err
+	}
+
+	if err := instantiateGlobals(runtime); err != nil {
+		return nil, err
+	}
+
+	if err := instantiateTables(runtime); err != nil {
+		return nil, err
+	}
+
+	if err := instantiateElements(runtime); err != nil {
+		return nil, err
+	}
+
+	if err := instantiateData(runtime); err != nil {
+		return nil, err
+	}
+
 	if err := exposeExportedFunctions(runtime); err != nil {
 		return nil, err
 	}
 
+	if err := runStartFunction(runtime); err != nil {
+		return nil, err
+	}
+
 	return runtime, nil
 }
 
+// runStartFunction calls the start function, if the module defines one,
+// as the last step of the instantiation
+func runStartFunction(runtime *Runtime) error {
+	module := runtime.binary.Module
+	if module.Start == nil {
+		return nil
+	}
+
+	startFuncIdx := *module.Start
+
+	function, err := module.Function(startFuncIdx)
+	if err != nil {
+		return fmt.Errorf("%w: %s", parser.ErrInvalidStartFunction, err)
+	}
+
+	if len(function.Signature.ParamsTypes) > 0 || len(function.Signature.ResultsTypes) > 0 {
+		return fmt.Errorf("%w, got %s", parser.ErrInvalidStartFunction, function.Signature)
+	}
+
+	startFunction, err := runtime.functionCallFrame(startFuncIdx)
+	if err != nil {
+		return fmt.Errorf("%w: %s", parser.ErrInvalidStartFunction, err)
+	}
+
+	if _, err := startFunction.Call(); err != nil {
+		return fmt.Errorf("start function %s: %w", runtime.describeFunction(startFuncIdx), err)
+	}
+
+	return nil
+}
+
+func resolveImports(runtime *Runtime, linker *Linker) error {
+	module := runtime.binary.Module
+	imported := module.Funcs[:len(module.Funcs)-len(module.DefinedFuncs())]
+
+	runtime.hostFunctions = make([]*hostFunction, len(imported))
+	for idx, function := range imported {
+		host, err := linker.resolveFunction(function)
+		if err != nil {
+			return err
+		}
+
+		runtime.hostFunctions[idx] = host
+	}
+
+	for _, imported := range module.Imports {
+		switch imported.Type {
+		case parser.ImportedFunc:
+		case parser.ImportedGlobal:
+			global, err := linker.resolveGlobal(imported)
+			if err != nil {
+				return err
+			}
+
+			runtime.globals = append(runtime.globals, global)
+		default:
+			return fmt.Errorf("%w: %s.%s: only function and global imports are supported",
+				ErrUnresolvedImport, imported.Module, imported.Name)
+		}
+	}
+
+	return nil
+}
+
+func instantiateMemory(runtime *Runtime) error {
+	memories := runtime.binary.Module.Memories
+
+	switch len(memories) {
+	case 0:
+		return nil
+	case 1:
+		memory, err := newMemory(memories[0])
+		if err != nil {
+			return err
+		}
+
+		runtime.memory = memory
+		return nil
+	}
+
+	return fmt.Errorf("%w: %d", parser.ErrMultipleMemories, len(memories))
+}
+
+// instantiateGlobals evaluates the initializer of each global defined in the
+// module, they are appended after the imported globals which are the only
+// ones the initializers can refer to
+func instantiateGlobals(runtime *Runtime) error {
+	importedGlobals := runtime.globals[:len(runtime.globals):len(runtime.globals)]
+
+	for idx, global := range runtime.binary.Module.Globals {
+		value, err := evaluateConstantExpression(importedGlobals, runtime.functionsLen(), global.Init)
+		if err != nil {
+			return fmt.Errorf("initializing global %d: %w", idx, err)
+		}
+
+		instance, err := NewGlobal(*global.Type, value)
+		if err != nil {
+			return fmt.Errorf("initializing global %d: %w", idx, err)
+		}
+
+		runtime.globals = append(runtime.globals, instance)
+	}
+
+	return nil
+}
+
+func instantiateTables(runtime *Runtime) error {
+	for idx, tableType := range runtime.binary.Module.Tables {
+		table, err := newTable(tableType)
+		if err != nil {
+			return fmt.Errorf("initializing table %d: %w", idx, err)
+		}
+
+		runtime.tables = append(runtime.tables, table)
+	}
+
+	return nil
+}
+
+// instantiateElements evaluates the references of every element segment, the
+// active segments are copied into their tables and, as the declarative ones,
+// dropped right away, only passive segments remain available
+func instantiateElements(runtime *Runtime) error {
+	elements := runtime.binary.Module.Elements
+	runtime.elements = make([]*elementInstance, len(elements))
+
+	for idx, element := range elements {
+		refs := make([]any, element.Len())
+		for refIdx := range refs {
+			if element.Init == nil {
+				if element.FuncIndices[refIdx] >= runtime.functionsLen() {
+					return fmt.Errorf("initializing element segment %d: %w: %d", idx,
+						parser.ErrFunctionIndexOutOfBounds, element.FuncIndices[refIdx])
+				}
+
+				refs[refIdx] = funcRef(element.FuncIndices[refIdx])
+				continue
+			}
+
+			ref, err := evaluateConstantExpression(runtime.globals, runtime.functionsLen(), element.Init[refIdx])
+			if err != nil {
+				return fmt.Errorf("initializing element segment %d: %w", idx, err)
+			}
+
+			refs[refIdx] = ref
+		}
+
+		instance := &elementInstance{refs: refs}
+		runtime.elements[idx] = instance
+
+		switch element.Mode {
+		case parser.ElementActive:
+			table, err := runtime.table(uint(element.Table))
+			if err != nil {
+				return fmt.Errorf("initializing element segment %d: %w", idx, err)
+			}
+
+			offset, err := evaluateConstantExpression(runtime.globals, runtime.functionsLen(), element.Offset)
+			if err != nil {
+				return fmt.Errorf("initializing element segment %d offset: %w", idx, err)
+			}
+
+			offsetValue, ok := offset.(int32)
+			if !ok {
+				return fmt.Errorf("initializing element segment %d offset: %w: expected int32, got %T",
+					idx, ErrWrongType, offset)
+			}
+
+			if err := table.init(uint32(offsetValue), refs); err != nil {
+				return fmt.Errorf("initializing element segment %d: %w", idx, err)
+			}
+
+			instance.drop()
+		case parser.ElementDeclarative:
+			instance.drop()
+		}
+	}
+
+	return nil
+}
+
+// instantiateData copies the active data segments into the memory, each
+// segment is bounds checked before it is written, only the passive
+// segments remain available after the instantiation
+func instantiateData(runtime *Runtime) error {
+	data := runtime.binary.Module.Data
+	runtime.data = make([]*dataInstance, len(data))
+
+	for idx, segment := range data {
+		instance := &dataInstance{bytes: segment.Init}
+		runtime.data[idx] = instance
+
+		if segment.Mode != parser.DataActive {
+			continue
+		}
+
+		if segment.Memory != 0 || runtime.memory == nil {
+			return fmt.Errorf("initializing data segment %d: %w: %d",
+				idx, parser.ErrUnknownMemory, segment.Memory)
+		}
+
+		offset, err := evaluateConstantExpression(runtime.globals, runtime.functionsLen(), segment.Offset)
+		if err != nil {
+			return fmt.Errorf("initializing data segment %d offset: %w", idx, err)
+		}
+
+		offsetValue, ok := offset.(int32)
+		if !ok {
+			return fmt.Errorf("initializing data segment %d offset: %w: expected int32, got %T",
+				idx, ErrWrongType, offset)
+		}
+
+		err = runtime.memory.init(instance, uint32(offsetValue), 0, uint32(len(instance.bytes)))
+		if err != nil {
+			return fmt.Errorf("initializing data segment %d: %w", idx, err)
+		}
+
+		instance.drop()
+	}
+
+	return nil
+}
+
+// describeFunction formats the function for error messages, using
+// its name from the name section when the module has one
+func (rt *Runtime) describeFunction(funcIdx int) string {
+	if name, ok := rt.binary.Module.Names.FunctionName(funcIdx); ok {
+		return fmt.Sprintf("$%s (index %d)", name, funcIdx)
+	}
+
+	return fmt.Sprintf("at index %d", funcIdx)
+}
+
+func (rt *Runtime) functionsLen() int {
+	return len(rt.binary.Module.Funcs)
+}
+
+// ExportedGlobal returns the global exported under name
+func (rt *Runtime) ExportedGlobal(name string) (*Global, bool) {
+	global, ok := rt.exportedGlobals[name]
+	return global, ok
+}
+
+// Memory returns the linear memory of the instance or
+// nil if the module does not define a memory
+func (rt *Runtime) Memory() *Memory {
+	return rt.memory
+}
+
+// functionCallFrame returns the call frame for the function at funcIdx
+// of the function index space, imported functions dispatch to the host
+func (rt *Runtime) functionCallFrame(funcIdx int) (*callFrame, error) {
+	function, err := rt.binary.Module.Function(funcIdx)
+	if err != nil {
+		return nil, err
+	}
+
+	if rt.frames == nil {
+		rt.frames = make([]*callFrame, rt.functionsLen())
+	}
+
+	if frame := rt.frames[funcIdx]; frame != nil {
+		return frame, nil
+	}
+
+	var frame *callFrame
+	if function.Import != nil {
+		frame = &callFrame{rt: rt, host: rt.hostFunctions[funcIdx]}
+	} else {
+		frame, err = newCallFrame(rt,
+			function.Code.Instructions,
+			function.Signature.ParamsTypes,
+			function.Code.Locals,
+			function.Signature.ResultsTypes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rt.frames[funcIdx] = frame
+	return frame, nil
+}
+
 func exposeExportedFunctions(runtime *Runtime) error {
-	functionSection := runtime.binary.Parsers[parser.FunctionSection].(*parser.FunctionSectionParser)
-	codeSection := runtime.binary.Parsers[parser.CodeSection].(*parser.CodeSectionParser)
-	exportedSection := runtime.binary.Parsers[parser.ExportSection].(*parser.ExportSectionParser)
+	exports := runtime.binary.Module.Exports
 
-	runtime.Exported = make(map[string]*callFrame, len(exportedSection.Exports))
+	runtime.Exported = make(map[string]*callFrame, len(exports))
+	runtime.exportedGlobals = make(map[string]*Global)
 
-	for _, exported := range exportedSection.Exports {
+	for _, exported := range exports {
 		switch exported.Type {
 		case parser.ExportedFunc:
-			if len(codeSection.FunctionsCode) < exported.Index {
-				return fmt.Errorf("%w: expected function on index %d",
-					ErrCannotExportFunction, exported.Index)
+			exportedFunction, err := runtime.functionCallFrame(exported.Index)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrCannotExportFunction, err)
 			}
 
-			exportedFunction := functionSection.Funcs[exported.Index]
-			exportedCode := codeSection.FunctionsCode[exported.Index]
+			runtime.Exported[exported.Name] = exportedFunction
+		case parser.ExportedGlobal:
+			global, err := runtime.global(uint(exported.Index))
+			if err != nil {
+				return fmt.Errorf("cannot export global %s: %w", exported.Name, err)
+			}
 
-			runtime.Exported[exported.Name] = newCallFrame(runtime,
-				exportedCode.Body,
-				exportedFunction.Signature.ParamsTypes,
-				exportedFunction.Signature.ResultsTypes)
+			runtime.exportedGlobals[exported.Name] = global
 		}
 	}
 
@@ -1,8 +1,12 @@
 package vm_test
 
 import (
+	"errors"
+	"math"
 	"testing"
 
+	"github.com/EclesioMeloJunior/wasvm/builder"
+	"github.com/EclesioMeloJunior/wasvm/opcodes"
 	"github.com/EclesioMeloJunior/wasvm/parser"
 	"github.com/EclesioMeloJunior/wasvm/vm"
 	"github.com/stretchr/testify/assert"
@@ -10,11 +14,19 @@ import (
 )
 
 const (
-	simpleWasm       = "../resources/simple.wasm"
-	operationsWasm   = "../resources/operations.wasm"
-	factorialWasm    = "../resources/factorial.wasm"
-	nestedIfWasm     = "../resources/nested_if.wasm"
-	simpleImportWasm = "../resources/simple_import.wasm"
+	simpleWasm         = "../resources/simple.wasm"
+	operationsWasm     = "../resources/operations.wasm"
+	factorialWasm      = "../resources/factorial.wasm"
+	nestedIfWasm       = "../resources/nested_if.wasm"
+	nestedIfResultWasm = "../resources/nested_if_result.wasm"
+	simpleImportWasm   = "../resources/simple_import.wasm"
+	importCallWasm     = "../resources/import_call.wasm"
+	startWasm          = "../resources/start.wasm"
+	namesWasm          = "../resources/names.wasm"
+	multiValueWasm     = "../resources/multi_value.wasm"
+	i64Wasm            = "../resources/i64.wasm"
+	floatsWasm         = "../resources/floats.wasm"
+	loopsWasm          = "../resources/loops.wasm"
 )
 
 func TestSimpleWasm_ExportedFunction_Execution(t *testing.T) {
@@ -48,7 +60,7 @@ func TestOperationsWasm(t *testing.T) {
 
 	rt, err := vm.NewRuntime(binaryWASM)
 	assert.NoError(t, err)
-	assert.Len(t, rt.Exported, 3)
+	assert.Len(t, rt.Exported, 5)
 
 	tests := []struct {
 		function string
@@ -74,8 +86,18 @@ func TestOperationsWasm(t *testing.T) {
 			rhs:      8,
 			expected: 72,
 		},
-
-		//TODO: implement div operation
+		{
+			function: "div",
+			lhs:      -7,
+			rhs:      2,
+			expected: -3,
+		},
+		{
+			function: "rem",
+			lhs:      -1,
+			rhs:      10,
+			expected: 5,
+		},
 	}
 
 	for _, tt := range tests {
@@ -92,6 +114,124 @@ func TestOperationsWasm(t *testing.T) {
 	}
 }
 
+func TestOperationsWasm_Traps(t *testing.T) {
+	binaryWASM, err := parser.BinaryFormat(operationsWasm)
+	require.NoError(t, err)
+
+	rt, err := vm.NewRuntime(binaryWASM)
+	require.NoError(t, err)
+
+	_, err = rt.Exported["div"].Call(int32(1), int32(0))
+	require.ErrorIs(t, err, vm.ErrIntegerDivideByZero)
+
+	_, err = rt.Exported["div"].Call(int32(math.MinInt32), int32(-1))
+	require.ErrorIs(t, err, vm.ErrIntegerOverflow)
+
+	_, err = rt.Exported["rem"].Call(int32(1), int32(0))
+	require.ErrorIs(t, err, vm.ErrIntegerDivideByZero)
+}
+
+func TestI64Wasm(t *testing.T) {
+	binaryWASM, err := parser.BinaryFormat(i64Wasm)
+	require.NoError(t, err)
+
+	rt, err := vm.NewRuntime(binaryWASM)
+	require.NoError(t, err)
+	require.Len(t, rt.Exported, 3)
+
+	// 20! is the greatest factorial that fits an i64
+	results, err := rt.Exported["fac"].Call(int64(20))
+	require.NoError(t, err)
+	require.Equal(t, []any{int64(2432902008176640000)}, results)
+
+	results, err = rt.Exported["div"].Call(int64(-1<<40), int64(1<<20))
+	require.NoError(t, err)
+	require.Equal(t, []any{int64(-1 << 20)}, results)
+
+	results, err = rt.Exported["rotl"].Call(int64(math.MinInt64), int64(65))
+	require.NoError(t, err)
+	require.Equal(t, []any{int64(1)}, results)
+
+	_, err = rt.Exported["div"].Call(int64(math.MinInt64), int64(-1))
+	require.ErrorIs(t, err, vm.ErrIntegerOverflow)
+
+	_, err = rt.Exported["fac"].Call(int32(20))
+	require.Error(t, err)
+}
+
+func TestFloatsWasm(t *testing.T) {
+	binaryWASM, err := parser.BinaryFormat(floatsWasm)
+	require.NoError(t, err)
+
+	rt, err := vm.NewRuntime(binaryWASM)
+	require.NoError(t, err)
+	require.Len(t, rt.Exported, 3)
+
+	results, err := rt.Exported["hypot"].Call(3.0, 4.0)
+	require.NoError(t, err)
+	require.Equal(t, []any{5.0}, results)
+
+	results, err = rt.Exported["hypot"].Call(math.Inf(-1), 4.0)
+	require.NoError(t, err)
+	require.Equal(t, []any{math.Inf(1)}, results)
+
+	results, err = rt.Exported["clamp"].Call(float32(12.5), float32(0), float32(10))
+	require.NoError(t, err)
+	require.Equal(t, []any{float32(10)}, results)
+
+	results, err = rt.Exported["clamp"].Call(float32(-0.5), float32(0), float32(10))
+	require.NoError(t, err)
+	require.Equal(t, []any{float32(0)}, results)
+
+	results, err = rt.Exported["clamp"].Call(float32(math.NaN()), float32(0), float32(10))
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.True(t, math.IsNaN(float64(results[0].(float32))))
+
+	results, err = rt.Exported["is_nan"].Call(math.NaN())
+	require.NoError(t, err)
+	require.Equal(t, []any{int32(1)}, results)
+
+	_, err = rt.Exported["hypot"].Call(float32(3), float32(4))
+	require.ErrorIs(t, err, vm.ErrWrongType)
+}
+
+// TestLoopsWasm runs functions that iterate with loops, branching
+// out of them with br_if and br, and a switch built with br_table
+func TestLoopsWasm(t *testing.T) {
+	binaryWASM, err := parser.BinaryFormat(loopsWasm)
+	require.NoError(t, err)
+
+	rt, err := vm.NewRuntime(binaryWASM)
+	require.NoError(t, err)
+	require.Len(t, rt.Exported, 3)
+
+	tests := map[string]struct {
+		function string
+		param    any
+		expected any
+	}{
+		"fac of 0":          {function: "fac", param: int64(0), expected: int64(1)},
+		"fac of 20":         {function: "fac", param: int64(20), expected: int64(2432902008176640000)},
+		"fib of 0":          {function: "fib", param: int32(0), expected: int32(0)},
+		"fib of 1":          {function: "fib", param: int32(1), expected: int32(1)},
+		"fib of 30":         {function: "fib", param: int32(30), expected: int32(832040)},
+		"classify 0":        {function: "classify", param: int32(0), expected: int32(100)},
+		"classify 1":        {function: "classify", param: int32(1), expected: int32(101)},
+		"classify 2":        {function: "classify", param: int32(2), expected: int32(-1)},
+		"classify negative": {function: "classify", param: int32(-1), expected: int32(-1)},
+	}
+
+	for tname, tt := range tests {
+		tt := tt
+		t.Run(tname, func(t *testing.T) {
+			results, err := rt.Exported[tt.function].Call(tt.param)
+			require.NoError(t, err)
+			require.Equal(t, []any{tt.expected}, results)
+		})
+	}
+}
+
 func TestFactorialWasm(t *testing.T) {
 	t.Parallel()
 
@@ -143,6 +283,25 @@ func TestFactorialWasm(t *testing.T) {
 func TestNestedIfWasm(t *testing.T) {
 	t.Parallel()
 
+	binaryWASM, err := parser.BinaryFormat(nestedIfWasm)
+	require.NoError(t, err)
+
+	rt, err := vm.NewRuntime(binaryWASM)
+	require.NoError(t, err)
+
+	nestedIf, ok := rt.Exported["nested_if"]
+	require.True(t, ok)
+
+	// the unreachable after the outer if is executed by every call
+	for _, params := range [][]any{{int32(9), int32(0)}, {int32(4), int32(8)}} {
+		_, err := nestedIf.Call(params...)
+		require.ErrorIs(t, err, vm.ErrUnreachable)
+	}
+}
+
+func TestNestedIfResultWasm(t *testing.T) {
+	t.Parallel()
+
 	tests := map[string]struct {
 		a, b     int32
 		expected int32
@@ -163,7 +322,7 @@ func TestNestedIfWasm(t *testing.T) {
 		tt := tt
 		t.Run(tname, func(t *testing.T) {
 			t.Parallel()
-			binaryWASM, err := parser.BinaryFormat(nestedIfWasm)
+			binaryWASM, err := parser.BinaryFormat(nestedIfResultWasm)
 			assert.NoError(t, err)
 
 			rt, err := vm.NewRuntime(binaryWASM)
@@ -183,10 +342,203 @@ func TestNestedIfWasm(t *testing.T) {
 }
 
 func TestSimpleWasmImportFunction(t *testing.T) {
-	// consoleLogFunc := func(n int32) {
-	// 	fmt.Printf("output: %d\n", n)
-	// }
+	binaryWASM, err := parser.BinaryFormat(simpleImportWasm)
+	require.NoError(t, err)
 
-	_, err := parser.BinaryFormat(simpleImportWasm)
+	var logged []int32
+	linker := vm.NewLinker()
+	linker.DefineFunction("console", "log",
+		parser.NewFunctionSignature([]parser.Type{parser.I32}, nil),
+		func(rt *vm.Runtime, params ...any) ([]any, error) {
+			logged = append(logged, params[0].(int32))
+			return nil, nil
+		})
+
+	// the start function logs the value set with local.tee
+	_, err = vm.NewRuntimeWithLinker(binaryWASM, linker)
+	require.NoError(t, err)
+	require.Equal(t, []int32{10}, logged)
+}
+
+func TestImportCallWasm_FunctionIndexSpace(t *testing.T) {
+	binaryWASM, err := parser.BinaryFormat(importCallWasm)
 	require.NoError(t, err)
+
+	linker := vm.NewLinker()
+	linker.DefineFunction("env", "unused",
+		parser.NewFunctionSignature([]parser.Type{parser.I32}, nil),
+		func(rt *vm.Runtime, params ...any) ([]any, error) {
+			return nil, nil
+		})
+
+	rt, err := vm.NewRuntimeWithLinker(binaryWASM, linker)
+	require.NoError(t, err)
+
+	quadrupleMinus, ok := rt.Exported["quadruple_minus"]
+	require.True(t, ok)
+
+	results, err := quadrupleMinus.Call(int32(5), int32(3))
+	require.NoError(t, err)
+	require.Equal(t, []any{int32(17)}, results)
+}
+
+func TestStartWasm_RunsStartFunctionOnInstantiation(t *testing.T) {
+	binaryWASM, err := parser.BinaryFormat(startWasm)
+	require.NoError(t, err)
+
+	var logged []int32
+	linker := vm.NewLinker()
+	linker.DefineFunction("env", "log",
+		parser.NewFunctionSignature([]parser.Type{parser.I32}, nil),
+		func(rt *vm.Runtime, params ...any) ([]any, error) {
+			logged = append(logged, params[0].(int32))
+			return nil, nil
+		})
+
+	_, err = vm.NewRuntimeWithLinker(binaryWASM, linker)
+	require.NoError(t, err)
+	require.Equal(t, []int32{7}, logged)
+
+	errTrap := errors.New("trap")
+	linker.DefineFunction("env", "log",
+		parser.NewFunctionSignature([]parser.Type{parser.I32}, nil),
+		func(rt *vm.Runtime, params ...any) ([]any, error) {
+			return nil, errTrap
+		})
+
+	rt, err := vm.NewRuntimeWithLinker(binaryWASM, linker)
+	require.ErrorIs(t, err, errTrap)
+	require.Nil(t, rt)
+}
+
+func TestNamesWasm_ErrorsUseFunctionNames(t *testing.T) {
+	binaryWASM, err := parser.BinaryFormat(namesWasm)
+	require.NoError(t, err)
+
+	rt, err := vm.NewRuntime(binaryWASM)
+	require.NoError(t, err)
+
+	results, err := rt.Exported["fac"].Call(int32(5))
+	require.NoError(t, err)
+	require.Equal(t, []any{int32(120)}, results)
+
+	_, err = rt.Exported["outer"].Call()
+	require.ErrorIs(t, err, vm.ErrOutOfBoundsMemoryAccess)
+	require.Contains(t, err.Error(), "calling function $oob (index 1)")
+}
+
+func TestMultiValueWasm_BlocksAndFunctionResults(t *testing.T) {
+	binaryWASM, err := parser.BinaryFormat(multiValueWasm)
+	require.NoError(t, err)
+
+	rt, err := vm.NewRuntime(binaryWASM)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		function string
+		params   []any
+		expected []any
+	}{
+		"function with two results": {
+			function: "swap",
+			params:   []any{int32(1), int32(2)},
+			expected: []any{int32(2), int32(1)},
+		},
+		"block with params and results": {
+			function: "sum_and_diff",
+			params:   []any{int32(7), int32(3)},
+			expected: []any{int32(10), int32(4)},
+		},
+		"if with params taking the branch": {
+			function: "swap_if",
+			params:   []any{int32(1), int32(2), int32(1)},
+			expected: []any{int32(2), int32(1)},
+		},
+		"if with params skipping the branch": {
+			function: "swap_if",
+			params:   []any{int32(1), int32(2), int32(0)},
+			expected: []any{int32(1), int32(2)},
+		},
+		"loop with param": {
+			function: "double",
+			params:   []any{int32(21)},
+			expected: []any{int32(42)},
+		},
+	}
+
+	for tname, tt := range tests {
+		tt := tt
+		t.Run(tname, func(t *testing.T) {
+			results, err := rt.Exported[tt.function].Call(tt.params...)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, results)
+		})
+	}
+}
+
+func TestNewRuntime_RejectsInvalidModule(t *testing.T) {
+	// (module (func (result i32) i64.const 1))
+	binaryWASM, err := parser.Decode([]byte{
+		0x00, 0x61, 0x73, 0x6D, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x05, 0x01, 0x60, 0x00, 0x01, 0x7F,
+		0x03, 0x02, 0x01, 0x00,
+		0x0A, 0x06, 0x01, 0x04, 0x00, 0x42, 0x01, 0x0B,
+	})
+	require.NoError(t, err)
+
+	rt, err := vm.NewRuntime(binaryWASM)
+	require.ErrorIs(t, err, parser.ErrTypeMismatch)
+	require.Nil(t, rt)
+
+	var validationErr *parser.ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	require.Equal(t, 0, validationErr.FuncIndex)
+	require.Equal(t, 2, validationErr.Offset)
+}
+
+func TestInfiniteRecursion_ExhaustsCallStack(t *testing.T) {
+	// (module (func $f (export "f") call $f))
+	binaryWASM, err := parser.Decode([]byte{
+		0x00, 0x61, 0x73, 0x6D, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+		0x03, 0x02, 0x01, 0x00,
+		0x07, 0x05, 0x01, 0x01, 0x66, 0x00, 0x00,
+		0x0A, 0x06, 0x01, 0x04, 0x00, 0x10, 0x00, 0x0B,
+	})
+	require.NoError(t, err)
+
+	rt, err := vm.NewRuntime(binaryWASM)
+	require.NoError(t, err)
+
+	_, err = rt.Exported["f"].Call()
+	require.ErrorIs(t, err, vm.ErrCallStackExhausted)
+}
+
+func TestCallResults_OverflowTheStack(t *testing.T) {
+	b := builder.New()
+	pair := b.AddFunction(nil, []parser.Type{parser.I32, parser.I32}, nil,
+		builder.I32Const(1), builder.I32Const(2))
+
+	// the stack is one value short of full when the two results are pushed
+	body := make([]builder.Instruction, 0)
+	for i := 0; i < 1023; i++ {
+		body = append(body, builder.I32Const(1))
+	}
+
+	body = append(body, builder.Call(pair))
+	for i := 0; i < 1024; i++ {
+		body = append(body, builder.Op(opcodes.Drop))
+	}
+
+	f := b.AddFunction(nil, []parser.Type{parser.I32}, nil, body...)
+	b.AddExport("f", parser.ExportedFunc, f)
+
+	binaryWASM, err := b.Module()
+	require.NoError(t, err)
+
+	rt, err := vm.NewRuntime(binaryWASM)
+	require.NoError(t, err)
+
+	_, err = rt.Exported["f"].Call()
+	require.ErrorIs(t, err, vm.ErrStackOverflow)
 }
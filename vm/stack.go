@@ -16,19 +16,12 @@ type StackValue struct {
 	endAt   uint
 }
 
-var TrueStackValue = StackValue{
-	value: true,
-}
-var FalseStackValue = StackValue{
-	value: false,
-}
-
 type Stack []StackValue
 
 func (s *Stack) push(value StackValue) error {
 	defPointer := *s
-	if len(defPointer) >= cap(defPointer) {
-		return fmt.Errorf("%w: limit %d", ErrStackOverflow, cap(defPointer))
+	if len(defPointer) >= maxStackHeight {
+		return fmt.Errorf("%w: limit %d", ErrStackOverflow, maxStackHeight)
 	}
 
 	*s = append(defPointer, value)
@@ -0,0 +1,146 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/EclesioMeloJunior/wasvm/parser"
+)
+
+var (
+	ErrOutOfBoundsTableAccess   = errors.New("out of bounds table access")
+	ErrUndefinedElement         = errors.New("undefined element")
+	ErrUninitializedElement     = errors.New("uninitialized element")
+	ErrIndirectCallTypeMismatch = errors.New("indirect call type mismatch")
+	ErrTableIndexOutOfBounds    = errors.New("table index out of bounds")
+	ErrTypeIndexOutOfBounds     = errors.New("type index out of bounds")
+	ErrInvalidTableLimits       = errors.New("invalid table limits")
+)
+
+// maxTableSize is the maximum amount of elements a table can start with,
+// it protects the host from allocating huge tables declared by a module
+const maxTableSize = 10_000_000
+
+// funcRef is a reference to a function of the
+// function index space, a null reference is nil
+type funcRef int
+
+// Table holds references to functions (or host values
+// for externref tables) that can be called indirectly
+type Table struct {
+	Type     parser.TableType
+	elements []any
+}
+
+func newTable(tableType *parser.TableType) (*Table, error) {
+	if tableType.Limits.HasMax && tableType.Limits.Min > tableType.Limits.Max {
+		return nil, fmt.Errorf("%w: min %d greater than max %d",
+			ErrInvalidTableLimits, tableType.Limits.Min, tableType.Limits.Max)
+	}
+
+	if tableType.Limits.Min > maxTableSize {
+		return nil, fmt.Errorf("%w: min %d greater than the supported %d elements",
+			ErrInvalidTableLimits, tableType.Limits.Min, maxTableSize)
+	}
+
+	return &Table{
+		Type:     *tableType,
+		elements: make([]any, tableType.Limits.Min),
+	}, nil
+}
+
+// Size returns the amount of elements of the table
+func (t *Table) Size() uint32 {
+	return uint32(len(t.elements))
+}
+
+// Get returns the element at idx, nil means a null reference
+func (t *Table) Get(idx uint32) (any, error) {
+	if idx >= t.Size() {
+		return nil, fmt.Errorf("%w: %d", ErrUndefinedElement, idx)
+	}
+
+	return t.elements[idx], nil
+}
+
+// init copies the references into the table starting at offset
+func (t *Table) init(offset uint32, refs []any) error {
+	if uint64(offset)+uint64(len(refs)) > uint64(t.Size()) {
+		return fmt.Errorf("%w: offset %d, %d elements, table size %d",
+			ErrOutOfBoundsTableAccess, offset, len(refs), t.Size())
+	}
+
+	copy(t.elements[offset:], refs)
+	return nil
+}
+
+// elementInstance is the runtime version of an element segment, active and
+// declarative segments are dropped once the instantiation finishes
+type elementInstance struct {
+	refs    []any
+	dropped bool
+}
+
+func (e *elementInstance) drop() {
+	e.refs = nil
+	e.dropped = true
+}
+
+func (rt *Runtime) table(idx uint) (*Table, error) {
+	if idx >= uint(len(rt.tables)) {
+		return nil, fmt.Errorf("%w: %d", ErrTableIndexOutOfBounds, idx)
+	}
+
+	return rt.tables[idx], nil
+}
+
+// functionType returns the function signature declared at typeIdx of the type section
+func (rt *Runtime) functionType(typeIdx uint) (*parser.FunctionSignatureParser, error) {
+	types := rt.binary.Module.Types
+	if typeIdx >= uint(len(types)) {
+		return nil, fmt.Errorf("%w: %d", ErrTypeIndexOutOfBounds, typeIdx)
+	}
+
+	return types[typeIdx], nil
+}
+
+// resolveIndirectCall returns the function index referenced by the element
+// elemIdx of the table, checking it matches the expected signature
+func (rt *Runtime) resolveIndirectCall(tableIdx, typeIdx uint, elemIdx uint32) (int, error) {
+	table, err := rt.table(tableIdx)
+	if err != nil {
+		return 0, err
+	}
+
+	expected, err := rt.functionType(typeIdx)
+	if err != nil {
+		return 0, err
+	}
+
+	element, err := table.Get(elemIdx)
+	if err != nil {
+		return 0, err
+	}
+
+	if element == nil {
+		return 0, fmt.Errorf("%w: %d", ErrUninitializedElement, elemIdx)
+	}
+
+	ref, ok := element.(funcRef)
+	if !ok {
+		return 0, fmt.Errorf("%w: element %d is not a function reference",
+			ErrIndirectCallTypeMismatch, elemIdx)
+	}
+
+	function, err := rt.binary.Module.Function(int(ref))
+	if err != nil {
+		return 0, err
+	}
+
+	if !function.Signature.Equal(expected) {
+		return 0, fmt.Errorf("%w: expected %s, got %s",
+			ErrIndirectCallTypeMismatch, expected, function.Signature)
+	}
+
+	return int(ref), nil
+}
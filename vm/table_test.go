@@ -0,0 +1,67 @@
+package vm_test
+
+import (
+	"testing"
+
+	"github.com/EclesioMeloJunior/wasvm/parser"
+	"github.com/EclesioMeloJunior/wasvm/vm"
+	"github.com/stretchr/testify/require"
+)
+
+const tableWasm = "../resources/table.wasm"
+
+func TestTableWasm_CallIndirect(t *testing.T) {
+	binaryWASM, err := parser.BinaryFormat(tableWasm)
+	require.NoError(t, err)
+
+	rt, err := vm.NewRuntime(binaryWASM)
+	require.NoError(t, err)
+
+	dispatch, ok := rt.Exported["dispatch"]
+	require.True(t, ok)
+
+	tests := map[string]struct {
+		elem     int32
+		expected []any
+		wantErr  error
+	}{
+		"first segment, add": {
+			elem:     0,
+			expected: []any{int32(12)},
+		},
+		"first segment, sub": {
+			elem:     1,
+			expected: []any{int32(8)},
+		},
+		"signature mismatch": {
+			elem:    2,
+			wantErr: vm.ErrIndirectCallTypeMismatch,
+		},
+		"null reference": {
+			elem:    3,
+			wantErr: vm.ErrUninitializedElement,
+		},
+		"out of table bounds": {
+			elem:    4,
+			wantErr: vm.ErrUndefinedElement,
+		},
+		"negative index is treated as unsigned": {
+			elem:    -1,
+			wantErr: vm.ErrUndefinedElement,
+		},
+	}
+
+	for tname, tt := range tests {
+		tt := tt
+		t.Run(tname, func(t *testing.T) {
+			results, err := dispatch.Call(tt.elem, int32(10), int32(2))
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, results)
+		})
+	}
+}
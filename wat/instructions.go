@@ -0,0 +1,554 @@
+package wat
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/EclesioMeloJunior/wasvm/builder"
+	"github.com/EclesioMeloJunior/wasvm/leb128"
+	"github.com/EclesioMeloJunior/wasvm/opcodes"
+	"github.com/EclesioMeloJunior/wasvm/parser"
+)
+
+// naturalAlignment is the log2 of the access size in bytes of the loads
+// and stores, it is the alignment used when `align=` is not given
+var naturalAlignment = map[opcodes.OpCode]uint32{
+	opcodes.I32Load: 2, opcodes.I64Load: 3, opcodes.F32Load: 2, opcodes.F64Load: 3,
+	opcodes.I32Load8S: 0, opcodes.I32Load8U: 0, opcodes.I32Load16S: 1, opcodes.I32Load16U: 1,
+	opcodes.I64Load8S: 0, opcodes.I64Load8U: 0, opcodes.I64Load16S: 1, opcodes.I64Load16U: 1,
+	opcodes.I64Load32S: 2, opcodes.I64Load32U: 2,
+	opcodes.I32Store: 2, opcodes.I64Store: 3, opcodes.F32Store: 2, opcodes.F64Store: 3,
+	opcodes.I32Store8: 0, opcodes.I32Store16: 1,
+	opcodes.I64Store8: 0, opcodes.I64Store16: 1, opcodes.I64Store32: 2,
+}
+
+// funcContext encodes the instructions of a function body or of a constant expression
+type funcContext struct {
+	module *moduleContext
+	locals *indexSpace
+
+	// labels holds the label of every open block, "" for the unnamed ones
+	labels []string
+	body   []byte
+}
+
+func newFuncContext(module *moduleContext, localIDs []string, pos position) (*funcContext, error) {
+	locals := newIndexSpace("local")
+	for _, id := range localIDs {
+		if _, err := locals.add(id, pos); err != nil {
+			return nil, err
+		}
+	}
+
+	return &funcContext{module: module, locals: locals}, nil
+}
+
+// finish closes the body with the final end, every block must be closed
+func (f *funcContext) finish(end position) ([]byte, error) {
+	if len(f.labels) > 0 {
+		return nil, newSyntaxError(end, fmt.Errorf("%w: %d blocks without end", ErrUnexpectedEnd, len(f.labels)))
+	}
+
+	return append(f.body, byte(opcodes.End)), nil
+}
+
+// parseConstantExpression reads the instructions of a constant expression
+// returning them with the final end, they are checked by the validation
+func (m *moduleContext) parseConstantExpression(c *cursor) ([]byte, error) {
+	f, _ := newFuncContext(m, nil, c.end)
+	if err := f.parseInstructions(c); err != nil {
+		return nil, err
+	}
+
+	return f.finish(c.end)
+}
+
+func constantI32(v int32) []byte {
+	return append(builder.I32Const(v), byte(opcodes.End))
+}
+
+func (f *funcContext) parseInstructions(c *cursor) error {
+	for !c.done() {
+		if err := f.parseInstruction(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseInstruction reads a plain instruction, such as `i32.add`,
+// or a folded one such as `(i32.add (local.get 0) (i32.const 1))`
+func (f *funcContext) parseInstruction(c *cursor) error {
+	n := c.next()
+	if n.list {
+		return f.parseFolded(n)
+	}
+
+	if n.token.kind != tokenAtom {
+		return newSyntaxError(n.token.pos, fmt.Errorf("%w: %s, expected instruction", ErrUnexpectedToken, n))
+	}
+
+	switch n.token.text {
+	case "block", "loop", "if":
+		return f.openBlock(n, c)
+	case "else":
+		if len(f.labels) == 0 {
+			return newSyntaxError(n.token.pos, fmt.Errorf("%w: else outside of if", ErrUnexpectedToken))
+		}
+
+		if err := f.checkLabel(c, f.labels[len(f.labels)-1]); err != nil {
+			return err
+		}
+
+		f.body = append(f.body, byte(opcodes.Else))
+		return nil
+	case "end":
+		if len(f.labels) == 0 {
+			return newSyntaxError(n.token.pos, fmt.Errorf("%w: end outside of block", ErrUnexpectedToken))
+		}
+
+		if err := f.checkLabel(c, f.labels[len(f.labels)-1]); err != nil {
+			return err
+		}
+
+		f.closeBlock()
+		return nil
+	}
+
+	instr, err := f.parsePlain(n, c)
+	if err != nil {
+		return err
+	}
+
+	f.body = append(f.body, instr...)
+	return nil
+}
+
+// checkLabel reads the optional label that repeats the block label after else and end
+func (f *funcContext) checkLabel(c *cursor, label string) error {
+	if !c.peek().isID() {
+		return nil
+	}
+
+	n := c.next()
+	if n.token.text != label {
+		return newSyntaxError(n.token.pos, fmt.Errorf("%w: %s, expected %q", ErrLabelMismatch, n, label))
+	}
+
+	return nil
+}
+
+// openBlock reads the label and the block type of block, loop and if
+func (f *funcContext) openBlock(n *node, c *cursor) error {
+	label := c.optionalID()
+
+	blockType, err := f.parseBlockType(c)
+	if err != nil {
+		return err
+	}
+
+	op, _ := opcodes.ByName(n.token.text)
+	f.labels = append(f.labels, label)
+	f.body = append(append(f.body, byte(op)), blockType...)
+	return nil
+}
+
+// parseBlockType reads the block type as a type use, the blocks without
+// params and with up to one result are encoded inline, the others
+// refer to a function type that is added to the module when missing
+func (f *funcContext) parseBlockType(c *cursor) ([]byte, error) {
+	if c.peek().isList("type") {
+		typeIdx, _, err := f.module.parseTypeUse(c)
+		if err != nil {
+			return nil, err
+		}
+
+		return leb128.EncodeInt(int(typeIdx)), nil
+	}
+
+	pos := c.position()
+	params, paramIDs, err := parseParams(c)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range paramIDs {
+		if id != "" {
+			return nil, newSyntaxError(pos, fmt.Errorf("%w: %s, block params cannot be named", ErrUnexpectedToken, id))
+		}
+	}
+
+	results, err := parseResults(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(params) == 0 {
+		switch len(results) {
+		case 0:
+			return []byte{opcodes.EmptyBlockType}, nil
+		case 1:
+			return []byte{results[0].SpecByte}, nil
+		}
+	}
+
+	typeIdx := f.module.typeIndex(parser.NewFunctionSignature(params, results))
+	return leb128.EncodeInt(int(typeIdx)), nil
+}
+
+func (f *funcContext) parseFolded(list *node) error {
+	c := newCursor(list)
+
+	head, err := c.expectAtom("instruction")
+	if err != nil {
+		return err
+	}
+
+	switch head.token.text {
+	case "block", "loop":
+		if err := f.openBlock(head, c); err != nil {
+			return err
+		}
+
+		if err := f.parseInstructions(c); err != nil {
+			return err
+		}
+
+		f.closeBlock()
+		return nil
+	case "if":
+		return f.parseFoldedIf(c)
+	}
+
+	instr, err := f.parsePlain(head, c)
+	if err != nil {
+		return err
+	}
+
+	// the remaining nodes are the folded operands, evaluated before the instruction
+	for !c.done() {
+		operand := c.next()
+		if !operand.list {
+			return newSyntaxError(operand.token.pos, fmt.Errorf("%w: %s, expected folded instruction", ErrUnexpectedToken, operand))
+		}
+
+		if err := f.parseFolded(operand); err != nil {
+			return err
+		}
+	}
+
+	f.body = append(f.body, instr...)
+	return nil
+}
+
+func (f *funcContext) closeBlock() {
+	f.labels = f.labels[:len(f.labels)-1]
+	f.body = append(f.body, byte(opcodes.End))
+}
+
+// parseFoldedIf reads `(if label? blocktype condition* (then instr*) (else instr*)?)`
+func (f *funcContext) parseFoldedIf(c *cursor) error {
+	label := c.optionalID()
+
+	blockType, err := f.parseBlockType(c)
+	if err != nil {
+		return err
+	}
+
+	for c.peek() != nil && c.peek().list && !c.peek().isList("then") {
+		if err := f.parseFolded(c.next()); err != nil {
+			return err
+		}
+	}
+
+	if !c.peek().isList("then") {
+		return c.unexpected("(then ...)")
+	}
+	then := c.next()
+
+	f.labels = append(f.labels, label)
+	f.body = append(append(f.body, byte(opcodes.If)), blockType...)
+
+	tc := newCursor(then)
+	tc.next()
+	if err := f.parseInstructions(tc); err != nil {
+		return err
+	}
+
+	if c.peek().isList("else") {
+		ec := newCursor(c.next())
+		ec.next()
+
+		f.body = append(f.body, byte(opcodes.Else))
+		if err := f.parseInstructions(ec); err != nil {
+			return err
+		}
+	}
+
+	if err := c.expectEnd(); err != nil {
+		return err
+	}
+
+	f.closeBlock()
+	return nil
+}
+
+// parsePlain reads the immediates of the instruction returning it encoded
+func (f *funcContext) parsePlain(n *node, c *cursor) (builder.Instruction, error) {
+	op, ok := opcodes.ByName(n.token.text)
+	if !ok || op == opcodes.Block || op == opcodes.Loop || op == opcodes.If ||
+		op == opcodes.Else || op == opcodes.End {
+		return nil, newSyntaxError(n.token.pos, fmt.Errorf("%w: %s", ErrUnknownInstruction, n))
+	}
+
+	switch op {
+	case opcodes.LocalGet:
+		return f.indexed(c, f.locals, builder.LocalGet)
+	case opcodes.LocalSet:
+		return f.indexed(c, f.locals, builder.LocalSet)
+	case opcodes.LocalTee:
+		return f.indexed(c, f.locals, builder.LocalTee)
+	case opcodes.GlobalGet:
+		return f.indexed(c, f.module.globalSpace, builder.GlobalGet)
+	case opcodes.GlobalSet:
+		return f.indexed(c, f.module.globalSpace, builder.GlobalSet)
+	case opcodes.Call:
+		return f.indexed(c, f.module.funcSpace, builder.Call)
+	case opcodes.RefFunc:
+		return f.indexed(c, f.module.funcSpace, builder.RefFunc)
+	case opcodes.DataDrop:
+		f.module.usesDataCount = true
+		return f.indexed(c, f.module.dataSpace, builder.DataDrop)
+	case opcodes.MemoryInit:
+		f.module.usesDataCount = true
+
+		// memory.init accepts an optional memory index before the data index
+		if isIndex(c.peek()) && c.pos+1 < len(c.nodes) && isIndex(c.nodes[c.pos+1]) {
+			if err := f.memoryIndex(c); err != nil {
+				return nil, err
+			}
+		}
+		return f.indexed(c, f.module.dataSpace, builder.MemoryInit)
+	case opcodes.CallIndirect:
+		tableIdx := uint32(0)
+		if isIndex(c.peek()) {
+			idx, err := f.module.tableSpace.resolve(c.next())
+			if err != nil {
+				return nil, err
+			}
+			tableIdx = idx
+		}
+
+		typeIdx, _, err := f.module.parseTypeUse(c)
+		if err != nil {
+			return nil, err
+		}
+
+		return builder.CallIndirect(typeIdx, tableIdx), nil
+	case opcodes.MemorySize, opcodes.MemoryGrow:
+		if isIndex(c.peek()) {
+			if err := f.memoryIndex(c); err != nil {
+				return nil, err
+			}
+		}
+
+		if op == opcodes.MemorySize {
+			return builder.MemorySize(), nil
+		}
+		return builder.MemoryGrow(), nil
+	case opcodes.Br, opcodes.BrIf:
+		label, err := f.label(c)
+		if err != nil {
+			return nil, err
+		}
+
+		if op == opcodes.Br {
+			return builder.Br(label), nil
+		}
+		return builder.BrIf(label), nil
+	case opcodes.BrTable:
+		// the last label is the default one
+		labels := make([]uint32, 0)
+		for isIndex(c.peek()) || len(labels) == 0 {
+			label, err := f.label(c)
+			if err != nil {
+				return nil, err
+			}
+			labels = append(labels, label)
+		}
+
+		return builder.BrTable(labels[:len(labels)-1], labels[len(labels)-1]), nil
+	case opcodes.I32Const, opcodes.I64Const, opcodes.F32Const, opcodes.F64Const:
+		return f.constant(op, c)
+	case opcodes.RefNull:
+		heapType, err := c.expectAtom("heap type")
+		if err != nil {
+			return nil, err
+		}
+
+		switch heapType.token.text {
+		case "func", "funcref":
+			return builder.RefNull(parser.Type{SpecType: parser.RefType, SpecByte: parser.FUNC_REF_TYPE}), nil
+		case "extern", "externref":
+			return builder.RefNull(parser.Type{SpecType: parser.RefType, SpecByte: parser.EXTERN_REF_TYPE}), nil
+		}
+
+		return nil, newSyntaxError(heapType.token.pos, fmt.Errorf("%w: %s", ErrUnknownType, heapType))
+	case opcodes.Select:
+		if !c.peek().isList("result") {
+			return builder.Op(op), nil
+		}
+
+		pos := c.position()
+		results, err := parseResults(c)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(results) != 1 {
+			return nil, newSyntaxError(pos, fmt.Errorf("%w: select must have one result", ErrUnexpectedToken))
+		}
+
+		return builder.SelectTyped(results[0]), nil
+	}
+
+	if align, ok := naturalAlignment[op]; ok {
+		return f.memoryAccess(op, align, c)
+	}
+
+	return builder.Op(op), nil
+}
+
+func (f *funcContext) indexed(c *cursor, space *indexSpace, encode func(uint32) builder.Instruction) (builder.Instruction, error) {
+	if c.done() {
+		return nil, c.unexpected(space.kind + " index")
+	}
+
+	idx, err := space.resolve(c.next())
+	if err != nil {
+		return nil, err
+	}
+
+	return encode(idx), nil
+}
+
+// label reads a label reference, either the depth of the block
+// or the identifier of one of the blocks that are still open
+func (f *funcContext) label(c *cursor) (uint32, error) {
+	if c.done() {
+		return 0, c.unexpected("label index")
+	}
+
+	n := c.next()
+	if !n.isID() {
+		if n.list || n.token.kind != tokenAtom {
+			return 0, newSyntaxError(nodePosition(n), fmt.Errorf("%w: expected label index", ErrUnexpectedToken))
+		}
+
+		depth, err := parseUint(n.token.text, 32)
+		if err != nil {
+			return 0, newSyntaxError(n.token.pos, err)
+		}
+		return uint32(depth), nil
+	}
+
+	for depth := 0; depth < len(f.labels); depth++ {
+		if f.labels[len(f.labels)-1-depth] == n.token.text {
+			return uint32(depth), nil
+		}
+	}
+
+	return 0, newSyntaxError(n.token.pos, fmt.Errorf("%w: label %s", ErrUnknownIdentifier, n.token.text))
+}
+
+// memoryIndex reads the memory index, it must be 0 as a module has only one memory
+func (f *funcContext) memoryIndex(c *cursor) error {
+	n := c.peek()
+	memIdx, err := f.module.memorySpace.resolve(c.next())
+	if err != nil {
+		return err
+	}
+
+	if memIdx != 0 {
+		return newSyntaxError(n.token.pos, fmt.Errorf("%w: memory %d, only memory 0 is supported", ErrUnexpectedToken, memIdx))
+	}
+
+	return nil
+}
+
+// memoryAccess reads the optional `offset=N` and `align=N` of loads and stores,
+// align is given in bytes and must be a power of two
+func (f *funcContext) memoryAccess(op opcodes.OpCode, align uint32, c *cursor) (builder.Instruction, error) {
+	offset := uint32(0)
+
+	for c.peek() != nil && !c.peek().list &&
+		(strings.HasPrefix(c.peek().token.text, "offset=") || strings.HasPrefix(c.peek().token.text, "align=")) {
+		n := c.next()
+		key, value, _ := strings.Cut(n.token.text, "=")
+
+		parsed, err := parseUint(value, 32)
+		if err != nil {
+			return nil, newSyntaxError(n.token.pos, err)
+		}
+
+		if key == "offset" {
+			offset = uint32(parsed)
+			continue
+		}
+
+		if parsed == 0 || parsed&(parsed-1) != 0 {
+			return nil, newSyntaxError(n.token.pos, fmt.Errorf("%w: alignment %d is not a power of two", ErrInvalidNumber, parsed))
+		}
+
+		align = 0
+		for parsed > 1 {
+			parsed >>= 1
+			align++
+		}
+	}
+
+	return builder.MemoryAccess(op, align, offset), nil
+}
+
+func (f *funcContext) constant(op opcodes.OpCode, c *cursor) (builder.Instruction, error) {
+	n, err := c.expectAtom(op.String() + " value")
+	if err != nil {
+		return nil, err
+	}
+
+	text := n.token.text
+	switch op {
+	case opcodes.I32Const:
+		value, err := parseInt(text, 32)
+		if err != nil {
+			return nil, newSyntaxError(n.token.pos, err)
+		}
+		return builder.I32Const(int32(value)), nil
+	case opcodes.I64Const:
+		value, err := parseInt(text, 64)
+		if err != nil {
+			return nil, newSyntaxError(n.token.pos, err)
+		}
+		return builder.I64Const(value), nil
+	case opcodes.F32Const:
+		bits, err := parseFloat32(text)
+		if err != nil {
+			return nil, newSyntaxError(n.token.pos, err)
+		}
+
+		raw := make([]byte, 4)
+		binary.LittleEndian.PutUint32(raw, bits)
+		return append(builder.Op(op), raw...), nil
+	}
+
+	bits, err := parseFloat64(text)
+	if err != nil {
+		return nil, newSyntaxError(n.token.pos, err)
+	}
+
+	raw := make([]byte, 8)
+	binary.LittleEndian.PutUint64(raw, bits)
+	return append(builder.Op(op), raw...), nil
+}
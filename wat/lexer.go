@@ -0,0 +1,241 @@
+package wat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type tokenKind byte
+
+const (
+	tokenLParen tokenKind = iota
+	tokenRParen
+	// tokenAtom is any keyword, identifier, number or reserved word
+	tokenAtom
+	tokenString
+)
+
+type token struct {
+	kind tokenKind
+	// text is the atom itself or the decoded string contents
+	text string
+	pos  position
+}
+
+type position struct {
+	line, column int
+}
+
+// lexer splits the source into tokens, skipping the white spaces and comments
+type lexer struct {
+	source []byte
+	offset int
+	pos    position
+}
+
+func newLexer(source []byte) *lexer {
+	return &lexer{source: source, pos: position{line: 1, column: 1}}
+}
+
+func (l *lexer) peek(ahead int) byte {
+	if l.offset+ahead >= len(l.source) {
+		return 0
+	}
+
+	return l.source[l.offset+ahead]
+}
+
+func (l *lexer) advance() byte {
+	c := l.source[l.offset]
+	l.offset++
+
+	if c == '\n' {
+		l.pos.line++
+		l.pos.column = 1
+	} else {
+		l.pos.column++
+	}
+
+	return c
+}
+
+func (l *lexer) tokens() ([]token, error) {
+	tokens := make([]token, 0)
+
+	for {
+		if err := l.skipSpacesAndComments(); err != nil {
+			return nil, err
+		}
+
+		if l.offset >= len(l.source) {
+			return tokens, nil
+		}
+
+		start := l.pos
+		switch c := l.peek(0); {
+		case c == '(':
+			l.advance()
+			tokens = append(tokens, token{kind: tokenLParen, pos: start})
+		case c == ')':
+			l.advance()
+			tokens = append(tokens, token{kind: tokenRParen, pos: start})
+		case c == '"':
+			text, err := l.string()
+			if err != nil {
+				return nil, err
+			}
+
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: start})
+		case isIDChar(c):
+			begin := l.offset
+			for l.offset < len(l.source) && isIDChar(l.peek(0)) {
+				l.advance()
+			}
+
+			tokens = append(tokens, token{kind: tokenAtom, text: string(l.source[begin:l.offset]), pos: start})
+		default:
+			return nil, newSyntaxError(start, fmt.Errorf("%w: %q", ErrUnexpectedCharacter, c))
+		}
+	}
+}
+
+// skipSpacesAndComments skips the white spaces, the line comments
+// started by `;;` and the block comments `(; ... ;)` that can be nested
+func (l *lexer) skipSpacesAndComments() error {
+	for l.offset < len(l.source) {
+		switch c := l.peek(0); {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			l.advance()
+		case c == ';' && l.peek(1) == ';':
+			for l.offset < len(l.source) && l.peek(0) != '\n' {
+				l.advance()
+			}
+		case c == '(' && l.peek(1) == ';':
+			start := l.pos
+			depth := 0
+
+			for {
+				if l.offset >= len(l.source) {
+					return newSyntaxError(start, fmt.Errorf("%w: unterminated block comment", ErrUnexpectedEnd))
+				}
+
+				if l.peek(0) == '(' && l.peek(1) == ';' {
+					l.advance()
+					l.advance()
+					depth++
+					continue
+				}
+
+				if l.peek(0) == ';' && l.peek(1) == ')' {
+					l.advance()
+					l.advance()
+					depth--
+					if depth == 0 {
+						break
+					}
+					continue
+				}
+
+				l.advance()
+			}
+		default:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// string reads a string literal decoding its escape sequences,
+// strings may hold any bytes as they are used by data segments
+func (l *lexer) string() (string, error) {
+	start := l.pos
+	l.advance()
+
+	var sb strings.Builder
+	for {
+		if l.offset >= len(l.source) || l.peek(0) == '\n' {
+			return "", newSyntaxError(start, fmt.Errorf("%w: unterminated string", ErrUnexpectedEnd))
+		}
+
+		c := l.advance()
+		if c == '"' {
+			return sb.String(), nil
+		}
+
+		if c != '\\' {
+			sb.WriteByte(c)
+			continue
+		}
+
+		escapePos := l.pos
+		if l.offset >= len(l.source) {
+			return "", newSyntaxError(start, fmt.Errorf("%w: unterminated string", ErrUnexpectedEnd))
+		}
+
+		switch e := l.advance(); e {
+		case 't':
+			sb.WriteByte('\t')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case '"', '\'', '\\':
+			sb.WriteByte(e)
+		case 'u':
+			r, err := l.unicodeEscape()
+			if err != nil {
+				return "", newSyntaxError(escapePos, err)
+			}
+			sb.WriteRune(r)
+		default:
+			if !isHexDigit(e) || !isHexDigit(l.peek(0)) {
+				return "", newSyntaxError(escapePos, fmt.Errorf("%w: \\%c", ErrInvalidEscape, e))
+			}
+
+			value, _ := strconv.ParseUint(string([]byte{e, l.advance()}), 16, 8)
+			sb.WriteByte(byte(value))
+		}
+	}
+}
+
+// unicodeEscape reads the `{hex}` part of a `\u{hex}` escape
+func (l *lexer) unicodeEscape() (rune, error) {
+	if l.peek(0) != '{' {
+		return 0, fmt.Errorf("%w: expected { after \\u", ErrInvalidEscape)
+	}
+	l.advance()
+
+	begin := l.offset
+	for l.offset < len(l.source) && isHexDigit(l.peek(0)) {
+		l.advance()
+	}
+
+	digits := string(l.source[begin:l.offset])
+	if l.peek(0) != '}' || digits == "" {
+		return 0, fmt.Errorf("%w: malformed \\u{...}", ErrInvalidEscape)
+	}
+	l.advance()
+
+	value, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil || !utf8.ValidRune(rune(value)) {
+		return 0, fmt.Errorf("%w: invalid code point \\u{%s}", ErrInvalidEscape, digits)
+	}
+
+	return rune(value), nil
+}
+
+func isIDChar(c byte) bool {
+	switch {
+	case c >= '0' && c <= '9', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return true
+	}
+
+	return strings.IndexByte("!#$%&'*+-./:<=>?@\\^_`|~", c) >= 0
+}
+
+func isHexDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
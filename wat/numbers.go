@@ -0,0 +1,131 @@
+package wat
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// parseUint parses an unsigned integer, decimal or hexadecimal (0x), with
+// optional `_` separators between the digits that fits in bits
+func parseUint(text string, bits int) (uint64, error) {
+	digits, base, err := integerDigits(text)
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := strconv.ParseUint(digits, base, bits)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidNumber, text)
+	}
+
+	return value, nil
+}
+
+// parseInt parses an integer that can be written either signed or unsigned,
+// e.g. i32.const accepts from -2147483648 up to 4294967295 where the values
+// greater than the max signed value wrap around
+func parseInt(text string, bits int) (int64, error) {
+	negative := strings.HasPrefix(text, "-")
+	magnitude, err := parseUint(strings.TrimLeft(text, "+-"), bits)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidNumber, text)
+	}
+
+	if !negative {
+		// the unsigned value is reinterpreted as signed
+		return int64(magnitude<<(64-bits)) >> (64 - bits), nil
+	}
+
+	if magnitude > 1<<(bits-1) {
+		return 0, fmt.Errorf("%w: %s out of range", ErrInvalidNumber, text)
+	}
+
+	return -int64(magnitude), nil
+}
+
+func integerDigits(text string) (digits string, base int, err error) {
+	base = 10
+	if strings.HasPrefix(text, "0x") {
+		base, text = 16, text[2:]
+	}
+
+	if text == "" || strings.HasPrefix(text, "_") || strings.HasSuffix(text, "_") ||
+		strings.Contains(text, "__") {
+		return "", 0, fmt.Errorf("%w: malformed digits", ErrInvalidNumber)
+	}
+
+	return strings.ReplaceAll(text, "_", ""), base, nil
+}
+
+// parseFloat32 parses a f32 returning its bits, see parseFloat
+func parseFloat32(text string) (uint32, error) {
+	bits, err := parseFloat(text, 32)
+	return uint32(bits), err
+}
+
+// parseFloat64 parses a f64 returning its bits, see parseFloat
+func parseFloat64(text string) (uint64, error) {
+	return parseFloat(text, 64)
+}
+
+// parseFloat parses a float of the given size (32 or 64) returning its bits, the
+// values can be decimal, hexadecimal, inf, nan or nan:0x... with an explicit
+// payload, bits are returned as converting a NaN between sizes may change it
+func parseFloat(text string, size int) (uint64, error) {
+	negative := strings.HasPrefix(text, "-")
+	unsigned := strings.TrimPrefix(strings.TrimPrefix(text, "-"), "+")
+
+	mantissaBits, exponentMask := uint64(52), uint64(0x7FF0000000000000)
+	if size == 32 {
+		mantissaBits, exponentMask = 23, 0x7F800000
+	}
+
+	signBit := uint64(0)
+	if negative {
+		signBit = 1 << (size - 1)
+	}
+
+	switch {
+	case unsigned == "inf":
+		return signBit | exponentMask, nil
+	case unsigned == "nan":
+		// the canonical NaN only has the most significant mantissa bit set
+		return signBit | exponentMask | 1<<(mantissaBits-1), nil
+	case strings.HasPrefix(unsigned, "nan:0x"):
+		payload, err := parseUint(unsigned[len("nan:"):], 64)
+		if err != nil || payload == 0 || payload >= 1<<mantissaBits {
+			return 0, fmt.Errorf("%w: %s", ErrInvalidNumber, text)
+		}
+
+		return signBit | exponentMask | payload, nil
+	}
+
+	if strings.Contains(unsigned, "__") || strings.HasPrefix(unsigned, "_") ||
+		strings.HasSuffix(unsigned, "_") {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidNumber, text)
+	}
+
+	digits := strings.ReplaceAll(unsigned, "_", "")
+
+	// go requires the exponent of hexadecimal floats
+	if strings.HasPrefix(digits, "0x") && !strings.ContainsAny(digits, "pP") {
+		digits += "p0"
+	}
+
+	value, err := strconv.ParseFloat(digits, size)
+	if err != nil || (len(digits) > 0 && (digits[0] < '0' || digits[0] > '9')) {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidNumber, text)
+	}
+
+	if negative {
+		value = -value
+	}
+
+	if size == 32 {
+		return uint64(math.Float32bits(float32(value))), nil
+	}
+
+	return math.Float64bits(value), nil
+}
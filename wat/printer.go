@@ -0,0 +1,593 @@
+package wat
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/EclesioMeloJunior/wasvm/opcodes"
+	"github.com/EclesioMeloJunior/wasvm/parser"
+)
+
+// Print renders the module in the text format, see Fprint
+func Print(bp *parser.BinaryParser) (string, error) {
+	var sb strings.Builder
+	if err := Fprint(&sb, bp); err != nil {
+		return "", err
+	}
+
+	return sb.String(), nil
+}
+
+// Fprint writes the module in the text format, the fields are written in the
+// order of their sections and the functions and locals are named after the
+// name section when available, otherwise they are referenced by index. The
+// printed module can be parsed back by Parse
+func Fprint(w io.Writer, bp *parser.BinaryParser) error {
+	p := &printer{module: bp.Module, funcNames: make(map[int]string), localNames: make(map[int]map[int]string)}
+	p.collectNames()
+
+	p.printf("(module")
+	if p.moduleName != "" {
+		p.printf(" %s", p.moduleName)
+	}
+
+	steps := []func() error{
+		p.printTypes, p.printImports, p.printFuncs, p.printTables, p.printMemories,
+		p.printGlobals, p.printExports, p.printStart, p.printElements, p.printData,
+		p.printCustoms,
+	}
+
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+
+	p.printf(")\n")
+	_, err := io.WriteString(w, p.sb.String())
+	return err
+}
+
+type printer struct {
+	module *parser.Module
+	sb     strings.Builder
+
+	moduleName string
+	funcNames  map[int]string
+	localNames map[int]map[int]string
+}
+
+func (p *printer) printf(format string, args ...any) {
+	fmt.Fprintf(&p.sb, format, args...)
+}
+
+// collectNames keeps the names of the name section that can be written as
+// identifiers, the repeated ones are left out so every identifier is unique
+func (p *printer) collectNames() {
+	names := p.module.Names
+	if names == nil {
+		return
+	}
+
+	if isValidID(names.Module) {
+		p.moduleName = "$" + names.Module
+	}
+
+	p.funcNames = uniqueIDs(names.Functions)
+	for funcIdx, locals := range names.Locals {
+		p.localNames[funcIdx] = uniqueIDs(locals)
+	}
+}
+
+func uniqueIDs(names map[int]string) map[int]string {
+	ids := make(map[int]string, len(names))
+	seen := make(map[string]int, len(names))
+
+	for idx, name := range names {
+		if isValidID(name) {
+			seen[name]++
+			ids[idx] = "$" + name
+		}
+	}
+
+	for idx, id := range ids {
+		if seen[id[1:]] > 1 {
+			delete(ids, idx)
+		}
+	}
+
+	return ids
+}
+
+func isValidID(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i := 0; i < len(name); i++ {
+		if !isIDChar(name[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p *printer) funcRef(idx int) string {
+	if id, ok := p.funcNames[idx]; ok {
+		return id
+	}
+
+	return strconv.Itoa(idx)
+}
+
+func (p *printer) printTypes() error {
+	for idx, signature := range p.module.Types {
+		p.printf("\n  (type (;%d;) (func%s))", idx, signatureText(signature, nil))
+	}
+
+	return nil
+}
+
+// signatureText returns the params and results of the signature, the
+// named params are written one by one while the others are grouped
+func signatureText(signature *parser.FunctionSignatureParser, paramNames map[int]string) string {
+	var sb strings.Builder
+	sb.WriteString(valueTypesText("param", signature.ParamsTypes, paramNames))
+
+	if len(signature.ResultsTypes) > 0 {
+		sb.WriteString(" (result")
+		for _, t := range signature.ResultsTypes {
+			sb.WriteString(" " + t.String())
+		}
+		sb.WriteString(")")
+	}
+
+	return sb.String()
+}
+
+func valueTypesText(keyword string, types []parser.Type, names map[int]string) string {
+	var sb strings.Builder
+	grouped := false
+
+	for idx, t := range types {
+		if name, ok := names[idx]; ok {
+			if grouped {
+				sb.WriteString(")")
+				grouped = false
+			}
+			fmt.Fprintf(&sb, " (%s %s %s)", keyword, name, t)
+			continue
+		}
+
+		if !grouped {
+			fmt.Fprintf(&sb, " (%s", keyword)
+			grouped = true
+		}
+		sb.WriteString(" " + t.String())
+	}
+
+	if grouped {
+		sb.WriteString(")")
+	}
+
+	return sb.String()
+}
+
+func (p *printer) printImports() error {
+	funcIdx, tableIdx, memIdx, globalIdx := 0, 0, 0, 0
+	for _, imported := range p.module.Imports {
+		p.printf("\n  (import %s %s ", quote([]byte(imported.Module)), quote([]byte(imported.Name)))
+
+		switch imported.Type {
+		case parser.ImportedFunc:
+			signature, err := p.module.Signature(imported.TypeIndex)
+			if err != nil {
+				return fmt.Errorf("%w: imported function %s.%s: %s",
+					parser.ErrFunctionWithouSignature, imported.Module, imported.Name, err)
+			}
+
+			p.printf("(func %s(type %d)%s))", p.idComment(p.funcNames, funcIdx),
+				imported.TypeIndex, signatureText(signature, p.localNames[funcIdx]))
+			funcIdx++
+		case parser.ImportedTable:
+			p.printf("(table (;%d;) %s))", tableIdx, tableTypeText(imported.Table))
+			tableIdx++
+		case parser.ImportedMem:
+			p.printf("(memory (;%d;) %s))", memIdx, limitsText(imported.Memory.Limits))
+			memIdx++
+		case parser.ImportedGlobal:
+			p.printf("(global (;%d;) %s))", globalIdx, globalTypeText(imported.Global))
+			globalIdx++
+		}
+	}
+
+	return nil
+}
+
+// idComment returns the identifier of the entity or its index as a comment
+func (p *printer) idComment(ids map[int]string, idx int) string {
+	if id, ok := ids[idx]; ok {
+		return id + " "
+	}
+
+	return fmt.Sprintf("(;%d;) ", idx)
+}
+
+func (p *printer) printFuncs() error {
+	defined := p.module.DefinedFuncs()
+	imported := len(p.module.Funcs) - len(defined)
+
+	for idx, function := range defined {
+		funcIdx := imported + idx
+		if function.Signature == nil || function.Code == nil {
+			return fmt.Errorf("%w: function %d", parser.ErrFunctionWithouCode, funcIdx)
+		}
+
+		localNames := p.localNames[funcIdx]
+		p.printf("\n  (func %s(type %d)%s", p.idComment(p.funcNames, funcIdx),
+			function.TypeIndex, signatureText(function.Signature, localNames))
+
+		// the locals names continue the params indexes
+		declaredNames := make(map[int]string)
+		for localIdx, name := range localNames {
+			if localIdx >= len(function.Signature.ParamsTypes) {
+				declaredNames[localIdx-len(function.Signature.ParamsTypes)] = name
+			}
+		}
+
+		if len(function.Code.Locals) > 0 {
+			p.printf("\n   %s", valueTypesText("local", function.Code.Locals, declaredNames))
+		}
+
+		if err := p.printInstructions(function.Code.Instructions, funcIdx, 2); err != nil {
+			return fmt.Errorf("cannot print function %d: %w", funcIdx, err)
+		}
+
+		p.printf(")")
+	}
+
+	return nil
+}
+
+// printInstructions writes one instruction per line, the body final end is left out
+func (p *printer) printInstructions(instructions []parser.Instruction, funcIdx, depth int) error {
+	if len(instructions) > 0 && instructions[len(instructions)-1].Opcode == opcodes.End {
+		instructions = instructions[:len(instructions)-1]
+	}
+
+	for _, inst := range instructions {
+		if inst.Opcode == opcodes.End || inst.Opcode == opcodes.Else {
+			depth--
+		}
+
+		text, err := p.instructionText(inst, funcIdx)
+		if err != nil {
+			return err
+		}
+
+		p.printf("\n%s%s", strings.Repeat("  ", depth), text)
+
+		switch inst.Opcode {
+		case opcodes.Block, opcodes.Loop, opcodes.If, opcodes.Else:
+			depth++
+		}
+	}
+
+	return nil
+}
+
+// instructionText returns the instruction and its immediates, funcIdx is
+// used to name the locals and it is -1 for the constant expressions
+func (p *printer) instructionText(inst parser.Instruction, funcIdx int) (string, error) {
+	name := inst.Opcode.String()
+
+	switch inst.Opcode {
+	case opcodes.Block, opcodes.Loop, opcodes.If:
+		switch {
+		case inst.TypeIndexed:
+			return fmt.Sprintf("%s (type %d)", name, inst.Index), nil
+		case len(inst.Results) == 0:
+			return name, nil
+		}
+		return fmt.Sprintf("%s (result %s)", name, inst.Results[0]), nil
+	case opcodes.LocalGet, opcodes.LocalSet, opcodes.LocalTee:
+		if id, ok := p.localNames[funcIdx][int(inst.Index)]; ok {
+			return name + " " + id, nil
+		}
+		return fmt.Sprintf("%s %d", name, inst.Index), nil
+	case opcodes.Call, opcodes.RefFunc:
+		return name + " " + p.funcRef(int(inst.Index)), nil
+	case opcodes.GlobalGet, opcodes.GlobalSet, opcodes.MemoryInit, opcodes.DataDrop,
+		opcodes.Br, opcodes.BrIf:
+		return fmt.Sprintf("%s %d", name, inst.Index), nil
+	case opcodes.BrTable:
+		text := name
+		for _, label := range inst.Labels {
+			text += fmt.Sprintf(" %d", label)
+		}
+		return fmt.Sprintf("%s %d", text, inst.Index), nil
+	case opcodes.CallIndirect:
+		if inst.Table != 0 {
+			return fmt.Sprintf("%s %d (type %d)", name, inst.Table, inst.Index), nil
+		}
+		return fmt.Sprintf("%s (type %d)", name, inst.Index), nil
+	case opcodes.SelectTyped:
+		return fmt.Sprintf("%s (result %s)", name, inst.Types[0]), nil
+	case opcodes.RefNull:
+		return name + " " + strings.TrimSuffix(inst.Types[0].String(), "ref"), nil
+	case opcodes.I32Const:
+		return fmt.Sprintf("%s %d", name, inst.I32), nil
+	case opcodes.I64Const:
+		return fmt.Sprintf("%s %d", name, inst.I64), nil
+	case opcodes.F32Const:
+		return name + " " + floatText(uint64(inst.F32), 32), nil
+	case opcodes.F64Const:
+		return name + " " + floatText(inst.F64, 64), nil
+	}
+
+	if align, ok := naturalAlignment[inst.Opcode]; ok {
+		text := name
+		if inst.MemArg.Offset != 0 {
+			text += fmt.Sprintf(" offset=%d", inst.MemArg.Offset)
+		}
+		if inst.MemArg.Align != align {
+			text += fmt.Sprintf(" align=%d", uint64(1)<<inst.MemArg.Align)
+		}
+		return text, nil
+	}
+
+	return name, nil
+}
+
+// floatText returns the float with the given bits as an exact
+// hexadecimal float, NaNs are written with their payload
+func floatText(bits uint64, size int) string {
+	mantissaBits, exponentMask := uint64(52), uint64(0x7FF0000000000000)
+	if size == 32 {
+		mantissaBits, exponentMask = 23, 0x7F800000
+	}
+
+	sign := ""
+	if bits>>(size-1) != 0 {
+		sign = "-"
+	}
+
+	mantissa := bits & (1<<mantissaBits - 1)
+	if bits&exponentMask == exponentMask {
+		switch {
+		case mantissa == 0:
+			return sign + "inf"
+		case mantissa == 1<<(mantissaBits-1):
+			return sign + "nan"
+		}
+		return fmt.Sprintf("%snan:0x%x", sign, mantissa)
+	}
+
+	if size == 32 {
+		return strconv.FormatFloat(float64(math.Float32frombits(uint32(bits))), 'x', -1, 32)
+	}
+
+	return strconv.FormatFloat(math.Float64frombits(bits), 'x', -1, 64)
+}
+
+// constantExpressionText returns the instructions of a constant
+// expression folded, e.g. `(i32.const 0)`, without the final end
+func (p *printer) constantExpressionText(expr []byte) (string, error) {
+	instructions, err := parser.DecodeInstructions(expr)
+	if err != nil {
+		return "", fmt.Errorf("cannot decode constant expression: %w", err)
+	}
+
+	texts := make([]string, 0, len(instructions))
+	for _, inst := range instructions {
+		if inst.Opcode == opcodes.End {
+			continue
+		}
+
+		text, err := p.instructionText(inst, -1)
+		if err != nil {
+			return "", err
+		}
+		texts = append(texts, "("+text+")")
+	}
+
+	return strings.Join(texts, " "), nil
+}
+
+func limitsText(limits parser.Limits) string {
+	if limits.HasMax {
+		return fmt.Sprintf("%d %d", limits.Min, limits.Max)
+	}
+
+	return strconv.Itoa(int(limits.Min))
+}
+
+func tableTypeText(table *parser.TableType) string {
+	return limitsText(table.Limits) + " " + table.ElemType.String()
+}
+
+func globalTypeText(global *parser.GlobalType) string {
+	if global.Mutable {
+		return fmt.Sprintf("(mut %s)", global.ValType)
+	}
+
+	return global.ValType.String()
+}
+
+func (p *printer) importedCount(kind parser.ImportedType) int {
+	count := 0
+	for _, imported := range p.module.Imports {
+		if imported.Type == kind {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (p *printer) printTables() error {
+	imported := p.importedCount(parser.ImportedTable)
+	for idx, table := range p.module.Tables {
+		p.printf("\n  (table (;%d;) %s)", imported+idx, tableTypeText(table))
+	}
+
+	return nil
+}
+
+func (p *printer) printMemories() error {
+	imported := p.importedCount(parser.ImportedMem)
+	for idx, memory := range p.module.Memories {
+		p.printf("\n  (memory (;%d;) %s)", imported+idx, limitsText(memory.Limits))
+	}
+
+	return nil
+}
+
+func (p *printer) printGlobals() error {
+	imported := p.importedCount(parser.ImportedGlobal)
+	for idx, global := range p.module.Globals {
+		init, err := p.constantExpressionText(global.Init)
+		if err != nil {
+			return fmt.Errorf("global %d: %w", imported+idx, err)
+		}
+
+		p.printf("\n  (global (;%d;) %s %s)", imported+idx, globalTypeText(global.Type), init)
+	}
+
+	return nil
+}
+
+func (p *printer) printExports() error {
+	for _, export := range p.module.Exports {
+		var desc string
+		switch export.Type {
+		case parser.ExportedFunc:
+			desc = "func " + p.funcRef(export.Index)
+		case parser.ExportedTable:
+			desc = fmt.Sprintf("table %d", export.Index)
+		case parser.ExportedMem:
+			desc = fmt.Sprintf("memory %d", export.Index)
+		case parser.ExportedGlobal:
+			desc = fmt.Sprintf("global %d", export.Index)
+		default:
+			return fmt.Errorf("%w: export %s kind 0x%x", ErrUnexpectedToken, export.Name, export.Type)
+		}
+
+		p.printf("\n  (export %s (%s))", quote([]byte(export.Name)), desc)
+	}
+
+	return nil
+}
+
+func (p *printer) printStart() error {
+	if p.module.Start == nil {
+		return nil
+	}
+
+	p.printf("\n  (start %s)", p.funcRef(*p.module.Start))
+	return nil
+}
+
+func (p *printer) printElements() error {
+	for idx, element := range p.module.Elements {
+		p.printf("\n  (elem (;%d;)", idx)
+
+		switch element.Mode {
+		case parser.ElementActive:
+			if element.Flags&0x02 != 0 {
+				p.printf(" (table %d)", element.Table)
+			}
+
+			offset, err := p.constantExpressionText(element.Offset)
+			if err != nil {
+				return fmt.Errorf("element segment %d: %w", idx, err)
+			}
+			p.printf(" %s", offset)
+		case parser.ElementDeclarative:
+			p.printf(" declare")
+		}
+
+		if element.Init == nil {
+			p.printf(" func")
+			for _, funcIdx := range element.FuncIndices {
+				p.printf(" %s", p.funcRef(funcIdx))
+			}
+			p.printf(")")
+			continue
+		}
+
+		p.printf(" %s", element.Type)
+		for _, expr := range element.Init {
+			item, err := p.constantExpressionText(expr)
+			if err != nil {
+				return fmt.Errorf("element segment %d: %w", idx, err)
+			}
+			p.printf(" (item %s)", item)
+		}
+		p.printf(")")
+	}
+
+	return nil
+}
+
+func (p *printer) printData() error {
+	for idx, segment := range p.module.Data {
+		p.printf("\n  (data (;%d;)", idx)
+
+		if segment.Mode == parser.DataActive {
+			if segment.Memory != 0 {
+				p.printf(" (memory %d)", segment.Memory)
+			}
+
+			offset, err := p.constantExpressionText(segment.Offset)
+			if err != nil {
+				return fmt.Errorf("data segment %d: %w", idx, err)
+			}
+			p.printf(" %s", offset)
+		}
+
+		p.printf(" %s)", quote(segment.Init))
+	}
+
+	return nil
+}
+
+// printCustoms writes the custom sections as @custom annotations, except
+// for the name section which is already written as the identifiers
+func (p *printer) printCustoms() error {
+	for _, custom := range p.module.Customs {
+		if custom.Name == "name" {
+			continue
+		}
+
+		p.printf("\n  (@custom %s %s)", quote([]byte(custom.Name)), quote(custom.Data))
+	}
+
+	return nil
+}
+
+// quote returns the bytes as a string literal, the bytes that are
+// not printable ASCII characters are written as \hh escapes
+func quote(data []byte) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+
+	for _, b := range data {
+		switch {
+		case b == '"' || b == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(b)
+		case b >= 0x20 && b < 0x7F:
+			sb.WriteByte(b)
+		default:
+			fmt.Fprintf(&sb, "\\%02x", b)
+		}
+	}
+
+	sb.WriteByte('"')
+	return sb.String()
+}
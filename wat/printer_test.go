@@ -0,0 +1,99 @@
+package wat_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/EclesioMeloJunior/wasvm/parser"
+	"github.com/EclesioMeloJunior/wasvm/wat"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrint_RoundTripResources(t *testing.T) {
+	resources, err := filepath.Glob("../resources/*.wasm")
+	require.NoError(t, err)
+	require.NotEmpty(t, resources)
+
+	for _, resource := range resources {
+		resource := resource
+		t.Run(filepath.Base(resource), func(t *testing.T) {
+			bp, err := parser.BinaryFormat(resource)
+			require.NoError(t, err)
+
+			text, err := wat.Print(bp)
+			require.NoError(t, err)
+
+			fromText, err := wat.Parse([]byte(text))
+			require.NoError(t, err, text)
+
+			expected, err := os.ReadFile(resource)
+			require.NoError(t, err)
+
+			encoded, err := parser.Encode(fromText)
+			require.NoError(t, err)
+			require.Equal(t, withoutNameSection(t, expected), encoded, text)
+		})
+	}
+}
+
+func TestPrint_Names(t *testing.T) {
+	bp, err := parser.BinaryFormat("../resources/names.wasm")
+	require.NoError(t, err)
+
+	text, err := wat.Print(bp)
+	require.NoError(t, err)
+
+	fromText, err := wat.Parse([]byte(text))
+	require.NoError(t, err)
+
+	binaryNames := bp.Module.Names
+	textNames := fromText.Module.Names
+	require.Equal(t, binaryNames, textNames)
+}
+
+func TestPrint_Text(t *testing.T) {
+	bp, err := wat.Parse([]byte(`(module
+		(memory 1)
+		(func $add (export "add") (param $a i32) (param $b i32) (result i32) (local f64)
+			local.get $a
+			local.get $b
+			i32.add
+			(if (result i32) (then (i32.const 1)) (else (i32.load offset=4 align=1 (i32.const 0))))
+			f64.const -0x1.8p+1
+			local.set 2)
+		(data (i32.const 8) "hi\00\n"))`))
+	require.NoError(t, err)
+
+	encoded, err := parser.Encode(bp)
+	require.NoError(t, err)
+
+	// decodes the module so the printer has no access to the identifiers
+	decoded, err := parser.Decode(encoded)
+	require.NoError(t, err)
+
+	text, err := wat.Print(decoded)
+	require.NoError(t, err)
+
+	const expected = `(module
+  (type (;0;) (func (param i32 i32) (result i32)))
+  (func (;0;) (type 0) (param i32 i32) (result i32)
+    (local f64)
+    local.get 0
+    local.get 1
+    i32.add
+    if (result i32)
+      i32.const 1
+    else
+      i32.const 0
+      i32.load offset=4 align=1
+    end
+    f64.const -0x1.8p+01
+    local.set 2)
+  (memory (;0;) 1)
+  (export "add" (func 0))
+  (data (;0;) (i32.const 8) "hi\00\0a"))
+`
+	require.Equal(t, expected, text)
+}
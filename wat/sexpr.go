@@ -0,0 +1,172 @@
+package wat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// node is an atom, a string or a parenthesized list of nodes
+type node struct {
+	token    token
+	list     bool
+	children []*node
+}
+
+// readNodes groups the tokens into the list of top level nodes
+func readNodes(tokens []token) ([]*node, error) {
+	root := &node{list: true}
+	stack := []*node{root}
+
+	for _, tok := range tokens {
+		current := stack[len(stack)-1]
+
+		switch tok.kind {
+		case tokenLParen:
+			list := &node{token: tok, list: true}
+			current.children = append(current.children, list)
+			stack = append(stack, list)
+		case tokenRParen:
+			if len(stack) == 1 {
+				return nil, newSyntaxError(tok.pos, fmt.Errorf("%w: )", ErrUnexpectedToken))
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			current.children = append(current.children, &node{token: tok})
+		}
+	}
+
+	if len(stack) > 1 {
+		unclosed := stack[len(stack)-1]
+		return nil, newSyntaxError(unclosed.token.pos, fmt.Errorf("%w: unclosed (", ErrUnexpectedEnd))
+	}
+
+	return root.children, nil
+}
+
+// isAtom tells if the node is the atom text
+func (n *node) isAtom(text string) bool {
+	return n != nil && !n.list && n.token.kind == tokenAtom && n.token.text == text
+}
+
+// isID tells if the node is a symbolic identifier such as $name
+func (n *node) isID() bool {
+	return n != nil && !n.list && n.token.kind == tokenAtom && strings.HasPrefix(n.token.text, "$")
+}
+
+func (n *node) isString() bool {
+	return n != nil && !n.list && n.token.kind == tokenString
+}
+
+// head returns the keyword that starts a list, such as `func` for `(func ...)`
+func (n *node) head() string {
+	if n == nil || !n.list || len(n.children) == 0 || n.children[0].list ||
+		n.children[0].token.kind != tokenAtom {
+		return ""
+	}
+
+	return n.children[0].token.text
+}
+
+func (n *node) isList(keyword string) bool {
+	return n.head() == keyword
+}
+
+func (n *node) String() string {
+	switch {
+	case n.list:
+		if head := n.head(); head != "" {
+			return "(" + head + " ...)"
+		}
+		return "(...)"
+	case n.token.kind == tokenString:
+		return fmt.Sprintf("%q", n.token.text)
+	}
+
+	return n.token.text
+}
+
+// cursor walks the nodes of a list one by one
+type cursor struct {
+	nodes []*node
+	pos   int
+	// end is where a missing node is reported
+	end position
+}
+
+func newCursor(list *node) *cursor {
+	return &cursor{nodes: list.children, end: list.token.pos}
+}
+
+func (c *cursor) peek() *node {
+	if c.pos >= len(c.nodes) {
+		return nil
+	}
+
+	return c.nodes[c.pos]
+}
+
+func (c *cursor) next() *node {
+	n := c.peek()
+	if n != nil {
+		c.pos++
+	}
+
+	return n
+}
+
+func (c *cursor) done() bool {
+	return c.pos >= len(c.nodes)
+}
+
+// position returns the position of the next node or of the list
+func (c *cursor) position() position {
+	if n := c.peek(); n != nil {
+		return n.token.pos
+	}
+
+	return c.end
+}
+
+// optionalID consumes the symbolic identifier if it is the next node
+func (c *cursor) optionalID() string {
+	if c.peek().isID() {
+		return c.next().token.text
+	}
+
+	return ""
+}
+
+func (c *cursor) expectString(what string) (string, error) {
+	n := c.peek()
+	if !n.isString() {
+		return "", c.unexpected(what)
+	}
+
+	return c.next().token.text, nil
+}
+
+func (c *cursor) expectAtom(what string) (*node, error) {
+	n := c.peek()
+	if n == nil || n.list || n.token.kind != tokenAtom {
+		return nil, c.unexpected(what)
+	}
+
+	return c.next(), nil
+}
+
+func (c *cursor) expectEnd() error {
+	if !c.done() {
+		return c.unexpected("end of list")
+	}
+
+	return nil
+}
+
+func (c *cursor) unexpected(expected string) error {
+	n := c.peek()
+	if n == nil {
+		return newSyntaxError(c.end, fmt.Errorf("%w: expected %s", ErrUnexpectedEnd, expected))
+	}
+
+	return newSyntaxError(n.token.pos, fmt.Errorf("%w: %s, expected %s", ErrUnexpectedToken, n, expected))
+}
@@ -0,0 +1,1304 @@
+// Package wat parses the WebAssembly text format into the
+// same module model the binary decoder of the parser produces
+package wat
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/EclesioMeloJunior/wasvm/parser"
+)
+
+var (
+	ErrUnexpectedCharacter   = errors.New("unexpected character")
+	ErrUnexpectedToken       = errors.New("unexpected token")
+	ErrUnexpectedEnd         = errors.New("unexpected end")
+	ErrInvalidEscape         = errors.New("invalid escape sequence")
+	ErrInvalidNumber         = errors.New("invalid number")
+	ErrUnknownIdentifier     = errors.New("unknown identifier")
+	ErrDuplicateIdentifier   = errors.New("duplicate identifier")
+	ErrUnknownInstruction    = errors.New("unknown instruction")
+	ErrUnknownType           = errors.New("unknown value type")
+	ErrImportAfterDefinition = errors.New("import after definition")
+	ErrTypeUseMismatch       = errors.New("inline function type does not match the type use")
+	ErrLabelMismatch         = errors.New("mismatching label")
+)
+
+// SyntaxError tells where in the source the module is malformed
+type SyntaxError struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Err)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+func newSyntaxError(pos position, err error) error {
+	var syntaxErr *SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return err
+	}
+
+	return &SyntaxError{Line: pos.line, Column: pos.column, Err: err}
+}
+
+// ParseFile reads and parses the text format module located at filepath
+func ParseFile(filepath string) (*parser.BinaryParser, error) {
+	source, err := os.ReadFile(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read file: %w", err)
+	}
+
+	return Parse(source)
+}
+
+// Parse parses a module in the text format, the source can either be a
+// `(module ...)` or only the module fields. Errors are *SyntaxError
+// telling the line and column where the module is malformed
+func Parse(source []byte) (*parser.BinaryParser, error) {
+	tokens, err := newLexer(source).tokens()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := readNodes(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nodes) == 1 && nodes[0].isList("module") {
+		return parseModule(nodes[0])
+	}
+
+	return parseModule(&node{list: true, children: append([]*node{{token: token{kind: tokenAtom, text: "module"}}}, nodes...)})
+}
+
+func parseModule(module *node) (*parser.BinaryParser, error) {
+	c := newCursor(module)
+	c.next()
+
+	moduleID := c.optionalID()
+
+	// (module binary "...") and (module quote "...") hold the module as strings
+	if c.peek().isAtom("binary") || c.peek().isAtom("quote") {
+		format := c.next().token.text
+
+		var contents bytes.Buffer
+		for !c.done() {
+			str, err := c.expectString("string")
+			if err != nil {
+				return nil, err
+			}
+			contents.WriteString(str)
+		}
+
+		if format == "binary" {
+			return parser.Decode(contents.Bytes())
+		}
+
+		return Parse(contents.Bytes())
+	}
+
+	m := newModuleContext()
+	if moduleID != "" {
+		m.names.Module = moduleID[1:]
+	}
+
+	fields := c.nodes[c.pos:]
+	for _, field := range fields {
+		if err := m.declare(field); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, field := range fields {
+		if err := m.define(field); err != nil {
+			return nil, err
+		}
+	}
+
+	return m.binaryParser(), nil
+}
+
+// indexSpace assigns the indexes of an entity kind and resolves their identifiers
+type indexSpace struct {
+	kind  string
+	ids   map[string]uint32
+	count uint32
+}
+
+func newIndexSpace(kind string) *indexSpace {
+	return &indexSpace{kind: kind, ids: make(map[string]uint32)}
+}
+
+// add returns the index of the new entity, registering its identifier if any
+func (s *indexSpace) add(id string, pos position) (uint32, error) {
+	idx := s.count
+	if id != "" {
+		if _, ok := s.ids[id]; ok {
+			return 0, newSyntaxError(pos, fmt.Errorf("%w: %s %s", ErrDuplicateIdentifier, s.kind, id))
+		}
+		s.ids[id] = idx
+	}
+
+	s.count++
+	return idx, nil
+}
+
+// resolve returns the index referenced by the node, either numeric or an identifier
+func (s *indexSpace) resolve(n *node) (uint32, error) {
+	if n == nil || n.list || n.token.kind != tokenAtom {
+		return 0, newSyntaxError(nodePosition(n), fmt.Errorf("%w: expected %s index", ErrUnexpectedToken, s.kind))
+	}
+
+	if n.isID() {
+		idx, ok := s.ids[n.token.text]
+		if !ok {
+			return 0, newSyntaxError(n.token.pos, fmt.Errorf("%w: %s %s", ErrUnknownIdentifier, s.kind, n.token.text))
+		}
+		return idx, nil
+	}
+
+	idx, err := parseUint(n.token.text, 32)
+	if err != nil {
+		return 0, newSyntaxError(n.token.pos, err)
+	}
+
+	return uint32(idx), nil
+}
+
+func nodePosition(n *node) position {
+	if n == nil {
+		return position{}
+	}
+
+	return n.token.pos
+}
+
+// isIndex tells if the node can be an index, numeric or an identifier
+func isIndex(n *node) bool {
+	return n.isID() || (n != nil && !n.list && n.token.kind == tokenAtom &&
+		n.token.text[0] >= '0' && n.token.text[0] <= '9')
+}
+
+// moduleContext holds the module being built, the fields are read in two passes
+// where the first one assigns the indexes, so the fields can reference others
+// defined later, and the second one reads the definitions
+type moduleContext struct {
+	typeSpace, funcSpace, tableSpace, memorySpace,
+	globalSpace, elemSpace, dataSpace *indexSpace
+
+	// defined is set once a function, table, memory or global
+	// is defined, after that no imports are allowed
+	defined bool
+
+	types         []*parser.FunctionSignatureParser
+	imports       []*parser.Import
+	importedFuncs []*parser.Function
+	funcs         []*parser.Function
+	tables        []*parser.TableType
+	memories      []*parser.MemoryType
+	globals       []*parser.Global
+	exports       []*parser.Export
+	elements      []*parser.Element
+	data          []*parser.Data
+	start         *int
+	customs       []*parser.Custom
+	names         *parser.NameSection
+
+	// usesDataCount is set by memory.init and data.drop
+	usesDataCount bool
+}
+
+func newModuleContext() *moduleContext {
+	return &moduleContext{
+		typeSpace:   newIndexSpace("type"),
+		funcSpace:   newIndexSpace("function"),
+		tableSpace:  newIndexSpace("table"),
+		memorySpace: newIndexSpace("memory"),
+		globalSpace: newIndexSpace("global"),
+		elemSpace:   newIndexSpace("elem"),
+		dataSpace:   newIndexSpace("data"),
+		names: &parser.NameSection{
+			Functions: make(map[int]string),
+			Locals:    make(map[int]map[int]string),
+		},
+	}
+}
+
+// declare assigns the indexes of the entities defined by the field, the
+// explicit types are read right away as they come first in the type section
+func (m *moduleContext) declare(field *node) error {
+	if !field.list {
+		return newSyntaxError(field.token.pos, fmt.Errorf("%w: %s, expected module field", ErrUnexpectedToken, field))
+	}
+
+	c := newCursor(field)
+	keyword := field.head()
+	c.next()
+
+	switch keyword {
+	case "type":
+		return m.declareType(c)
+	case "import":
+		if _, err := c.expectString("module name"); err != nil {
+			return err
+		}
+		if _, err := c.expectString("import name"); err != nil {
+			return err
+		}
+
+		desc := c.next()
+		if desc == nil || !desc.list {
+			return c.unexpected("import description")
+		}
+
+		space, err := m.importSpace(desc)
+		if err != nil {
+			return err
+		}
+
+		dc := newCursor(desc)
+		dc.next()
+		return m.declareImport(space, dc.optionalID(), desc.token.pos)
+	case "func", "table", "memory", "global":
+		space, _ := m.importSpace(field)
+		id := c.optionalID()
+		for c.peek().isList("export") {
+			c.next()
+		}
+
+		if c.peek().isList("import") {
+			return m.declareImport(space, id, field.token.pos)
+		}
+
+		m.defined = true
+		if _, err := space.add(id, field.token.pos); err != nil {
+			return err
+		}
+
+		// the abbreviations (table (elem ...)) and (memory (data ...)) define a segment
+		if keyword == "table" || keyword == "memory" {
+			for !c.done() {
+				n := c.next()
+				if (keyword == "table" && n.isList("elem")) || (keyword == "memory" && n.isList("data")) {
+					segments := m.elemSpace
+					if keyword == "memory" {
+						segments = m.dataSpace
+					}
+					if _, err := segments.add("", n.token.pos); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		return nil
+	case "elem":
+		_, err := m.elemSpace.add(c.optionalID(), field.token.pos)
+		return err
+	case "data":
+		_, err := m.dataSpace.add(c.optionalID(), field.token.pos)
+		return err
+	case "export", "start":
+		return nil
+	}
+
+	if strings.HasPrefix(keyword, "@") {
+		// annotations, only @custom is used and it is read by define
+		return nil
+	}
+
+	return newSyntaxError(field.token.pos, fmt.Errorf("%w: %s, expected module field", ErrUnexpectedToken, field))
+}
+
+func (m *moduleContext) importSpace(desc *node) (*indexSpace, error) {
+	switch desc.head() {
+	case "func":
+		return m.funcSpace, nil
+	case "table":
+		return m.tableSpace, nil
+	case "memory":
+		return m.memorySpace, nil
+	case "global":
+		return m.globalSpace, nil
+	}
+
+	return nil, newSyntaxError(desc.token.pos, fmt.Errorf("%w: %s, expected import description", ErrUnexpectedToken, desc))
+}
+
+func (m *moduleContext) declareImport(space *indexSpace, id string, pos position) error {
+	if m.defined {
+		return newSyntaxError(pos, fmt.Errorf("%w: imports must come before the definitions",
+			ErrImportAfterDefinition))
+	}
+
+	_, err := space.add(id, pos)
+	return err
+}
+
+func (m *moduleContext) declareType(c *cursor) error {
+	id := c.optionalID()
+
+	funcType := c.next()
+	if !funcType.isList("func") {
+		return c.unexpected("(func ...)")
+	}
+
+	if err := c.expectEnd(); err != nil {
+		return err
+	}
+
+	fc := newCursor(funcType)
+	fc.next()
+
+	params, _, err := parseParams(fc)
+	if err != nil {
+		return err
+	}
+
+	results, err := parseResults(fc)
+	if err != nil {
+		return err
+	}
+
+	if err := fc.expectEnd(); err != nil {
+		return err
+	}
+
+	if _, err := m.typeSpace.add(id, funcType.token.pos); err != nil {
+		return err
+	}
+
+	m.types = append(m.types, parser.NewFunctionSignature(params, results))
+	return nil
+}
+
+// define reads the field definition, the indexes were already assigned by declare
+func (m *moduleContext) define(field *node) error {
+	c := newCursor(field)
+	keyword := field.head()
+	c.next()
+
+	switch keyword {
+	case "type":
+		return nil
+	case "import":
+		module, _ := c.expectString("module name")
+		name, _ := c.expectString("import name")
+		desc := c.next()
+		if err := c.expectEnd(); err != nil {
+			return err
+		}
+
+		dc := newCursor(desc)
+		dc.next()
+		return m.defineImport(module, name, desc.head(), dc.optionalID(), dc)
+	case "func":
+		return m.defineFunc(c)
+	case "table":
+		return m.defineTable(c)
+	case "memory":
+		return m.defineMemory(c)
+	case "global":
+		return m.defineGlobal(c)
+	case "export":
+		return m.defineExport(c)
+	case "start":
+		funcIdx, err := m.funcSpace.resolve(c.next())
+		if err != nil {
+			return err
+		}
+
+		start := int(funcIdx)
+		m.start = &start
+		return c.expectEnd()
+	case "elem":
+		return m.defineElem(c)
+	case "data":
+		return m.defineData(c)
+	case "@custom":
+		return m.defineCustom(c)
+	}
+
+	return nil
+}
+
+// inlineExports reads the `(export "name")` abbreviations of a definition
+func (m *moduleContext) inlineExports(c *cursor, kind parser.ExportedType, idx uint32) error {
+	for c.peek().isList("export") {
+		ec := newCursor(c.next())
+		ec.next()
+
+		name, err := ec.expectString("export name")
+		if err != nil {
+			return err
+		}
+
+		if err := ec.expectEnd(); err != nil {
+			return err
+		}
+
+		m.exports = append(m.exports, &parser.Export{Name: name, Type: kind, Index: int(idx)})
+	}
+
+	return nil
+}
+
+// inlineImport reads the `(import "module" "name")` abbreviation of a definition
+func inlineImport(c *cursor) (module, name string, ok bool, err error) {
+	if !c.peek().isList("import") {
+		return "", "", false, nil
+	}
+
+	ic := newCursor(c.next())
+	ic.next()
+
+	if module, err = ic.expectString("module name"); err != nil {
+		return "", "", false, err
+	}
+
+	if name, err = ic.expectString("import name"); err != nil {
+		return "", "", false, err
+	}
+
+	return module, name, true, ic.expectEnd()
+}
+
+// defineImport reads the import description after its identifier
+func (m *moduleContext) defineImport(module, name, kind, id string, c *cursor) error {
+	imported := &parser.Import{Module: module, Name: name}
+
+	switch kind {
+	case "func":
+		typeIdx, paramIDs, err := m.parseTypeUse(c)
+		if err != nil {
+			return err
+		}
+
+		imported.Type = parser.ImportedFunc
+		imported.TypeIndex = int(typeIdx)
+
+		funcIdx := len(m.importedFuncs)
+		m.nameFunction(funcIdx, id, paramIDs)
+		m.importedFuncs = append(m.importedFuncs, &parser.Function{
+			TypeIndex: int(typeIdx),
+			Signature: m.types[typeIdx],
+			Import:    imported,
+		})
+	case "table":
+		tableType, err := parseTableType(c)
+		if err != nil {
+			return err
+		}
+
+		imported.Type = parser.ImportedTable
+		imported.Table = tableType
+	case "memory":
+		limits, err := parseLimits(c)
+		if err != nil {
+			return err
+		}
+
+		imported.Type = parser.ImportedMem
+		imported.Memory = &parser.MemoryType{Limits: limits}
+	case "global":
+		globalType, err := parseGlobalType(c)
+		if err != nil {
+			return err
+		}
+
+		imported.Type = parser.ImportedGlobal
+		imported.Global = globalType
+	}
+
+	m.imports = append(m.imports, imported)
+	return c.expectEnd()
+}
+
+func (m *moduleContext) hasNames() bool {
+	for _, localNames := range m.names.Locals {
+		if len(localNames) > 0 {
+			return true
+		}
+	}
+
+	return m.names.Module != "" || len(m.names.Functions) > 0
+}
+
+func (m *moduleContext) nameFunction(funcIdx int, id string, localIDs []string) {
+	if id != "" {
+		m.names.Functions[funcIdx] = id[1:]
+	}
+
+	for localIdx, localID := range localIDs {
+		if localID == "" {
+			continue
+		}
+
+		if m.names.Locals[funcIdx] == nil {
+			m.names.Locals[funcIdx] = make(map[int]string)
+		}
+		m.names.Locals[funcIdx][localIdx] = localID[1:]
+	}
+}
+
+func (m *moduleContext) defineFunc(c *cursor) error {
+	id := c.optionalID()
+	funcIdx := uint32(len(m.importedFuncs) + len(m.funcs))
+
+	if err := m.inlineExports(c, parser.ExportedFunc, funcIdx); err != nil {
+		return err
+	}
+
+	if module, name, ok, err := inlineImport(c); err != nil || ok {
+		if err != nil {
+			return err
+		}
+
+		return m.defineImport(module, name, "func", id, c)
+	}
+
+	typeIdx, paramIDs, err := m.parseTypeUse(c)
+	if err != nil {
+		return err
+	}
+
+	localIDs := paramIDs
+	if len(localIDs) < len(m.types[typeIdx].ParamsTypes) {
+		localIDs = make([]string, len(m.types[typeIdx].ParamsTypes))
+	}
+
+	locals := make([]parser.Type, 0)
+	for c.peek().isList("local") {
+		lc := newCursor(c.next())
+		lc.next()
+
+		if localID := lc.optionalID(); localID != "" {
+			localType, err := parseValueType(lc.next(), lc)
+			if err != nil {
+				return err
+			}
+
+			locals = append(locals, localType)
+			localIDs = append(localIDs, localID)
+
+			if err := lc.expectEnd(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for !lc.done() {
+			localType, err := parseValueType(lc.next(), lc)
+			if err != nil {
+				return err
+			}
+
+			locals = append(locals, localType)
+			localIDs = append(localIDs, "")
+		}
+	}
+
+	fc, err := newFuncContext(m, localIDs, c.end)
+	if err != nil {
+		return err
+	}
+
+	if err := fc.parseInstructions(c); err != nil {
+		return err
+	}
+
+	body, err := fc.finish(c.end)
+	if err != nil {
+		return err
+	}
+
+	code, err := parser.NewCode(locals, body)
+	if err != nil {
+		return newSyntaxError(c.end, fmt.Errorf("%w: %s", ErrUnknownInstruction, err))
+	}
+
+	// as wat2wasm does, every defined function has a local names map even when empty
+	m.names.Locals[int(funcIdx)] = make(map[int]string)
+	m.nameFunction(int(funcIdx), id, localIDs)
+	m.funcs = append(m.funcs, &parser.Function{
+		TypeIndex: int(typeIdx),
+		Signature: m.types[typeIdx],
+		Code:      code,
+	})
+
+	return nil
+}
+
+func (m *moduleContext) defineTable(c *cursor) error {
+	id := c.optionalID()
+	tableIdx := m.importedCount(parser.ImportedTable) + uint32(len(m.tables))
+
+	if err := m.inlineExports(c, parser.ExportedTable, tableIdx); err != nil {
+		return err
+	}
+
+	if module, name, ok, err := inlineImport(c); err != nil || ok {
+		if err != nil {
+			return err
+		}
+
+		return m.defineImport(module, name, "table", id, c)
+	}
+
+	// (table reftype (elem ...)) defines a table with
+	// the exact size of the segment placed at offset 0
+	if refType, err := parseValueType(c.peek(), c); err == nil && refType.SpecType == parser.RefType {
+		c.next()
+
+		elem := c.next()
+		if !elem.isList("elem") {
+			return c.unexpected("(elem ...)")
+		}
+
+		ec := newCursor(elem)
+		ec.next()
+
+		element := &parser.Element{
+			Mode:   parser.ElementActive,
+			Type:   refType,
+			Table:  int(tableIdx),
+			Offset: constantI32(0),
+		}
+
+		if err := m.parseElemList(ec, element, true); err != nil {
+			return err
+		}
+
+		if tableIdx != 0 {
+			element.Flags |= 0x02
+		}
+
+		m.elements = append(m.elements, element)
+
+		size := uint32(element.Len())
+		m.tables = append(m.tables, &parser.TableType{
+			ElemType: refType,
+			Limits:   parser.Limits{Min: size, Max: size, HasMax: true},
+		})
+		return c.expectEnd()
+	}
+
+	tableType, err := parseTableType(c)
+	if err != nil {
+		return err
+	}
+
+	m.tables = append(m.tables, tableType)
+	return c.expectEnd()
+}
+
+// pageSize is the size of a memory page in bytes
+const pageSize = 65536
+
+func (m *moduleContext) defineMemory(c *cursor) error {
+	id := c.optionalID()
+	memIdx := m.importedCount(parser.ImportedMem) + uint32(len(m.memories))
+
+	if err := m.inlineExports(c, parser.ExportedMem, memIdx); err != nil {
+		return err
+	}
+
+	if module, name, ok, err := inlineImport(c); err != nil || ok {
+		if err != nil {
+			return err
+		}
+
+		return m.defineImport(module, name, "memory", id, c)
+	}
+
+	// (memory (data ...)) defines a memory with enough
+	// pages for the segment placed at offset 0
+	if c.peek().isList("data") {
+		dc := newCursor(c.next())
+		dc.next()
+
+		init, err := parseDataStrings(dc)
+		if err != nil {
+			return err
+		}
+
+		segment := &parser.Data{
+			Mode:   parser.DataActive,
+			Memory: int(memIdx),
+			Offset: constantI32(0),
+			Init:   init,
+		}
+
+		if memIdx != 0 {
+			segment.Flags = 0x02
+		}
+
+		m.data = append(m.data, segment)
+
+		pages := uint32((len(init) + pageSize - 1) / pageSize)
+		m.memories = append(m.memories, &parser.MemoryType{
+			Limits: parser.Limits{Min: pages, Max: pages, HasMax: true},
+		})
+		return c.expectEnd()
+	}
+
+	limits, err := parseLimits(c)
+	if err != nil {
+		return err
+	}
+
+	m.memories = append(m.memories, &parser.MemoryType{Limits: limits})
+	return c.expectEnd()
+}
+
+func (m *moduleContext) defineGlobal(c *cursor) error {
+	id := c.optionalID()
+	globalIdx := m.importedCount(parser.ImportedGlobal) + uint32(len(m.globals))
+
+	if err := m.inlineExports(c, parser.ExportedGlobal, globalIdx); err != nil {
+		return err
+	}
+
+	if module, name, ok, err := inlineImport(c); err != nil || ok {
+		if err != nil {
+			return err
+		}
+
+		return m.defineImport(module, name, "global", id, c)
+	}
+
+	globalType, err := parseGlobalType(c)
+	if err != nil {
+		return err
+	}
+
+	init, err := m.parseConstantExpression(c)
+	if err != nil {
+		return err
+	}
+
+	m.globals = append(m.globals, &parser.Global{Type: globalType, Init: init})
+	return nil
+}
+
+func (m *moduleContext) importedCount(kind parser.ImportedType) uint32 {
+	count := uint32(0)
+	for _, imported := range m.imports {
+		if imported.Type == kind {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (m *moduleContext) defineExport(c *cursor) error {
+	name, err := c.expectString("export name")
+	if err != nil {
+		return err
+	}
+
+	desc := c.next()
+	if desc == nil || !desc.list {
+		return c.unexpected("export description")
+	}
+
+	dc := newCursor(desc)
+	dc.next()
+
+	var (
+		kind  parser.ExportedType
+		space *indexSpace
+	)
+
+	switch desc.head() {
+	case "func":
+		kind, space = parser.ExportedFunc, m.funcSpace
+	case "table":
+		kind, space = parser.ExportedTable, m.tableSpace
+	case "memory":
+		kind, space = parser.ExportedMem, m.memorySpace
+	case "global":
+		kind, space = parser.ExportedGlobal, m.globalSpace
+	default:
+		return newSyntaxError(desc.token.pos, fmt.Errorf("%w: %s, expected export description", ErrUnexpectedToken, desc))
+	}
+
+	idx, err := space.resolve(dc.next())
+	if err != nil {
+		return err
+	}
+
+	if err := dc.expectEnd(); err != nil {
+		return err
+	}
+
+	m.exports = append(m.exports, &parser.Export{Name: name, Type: kind, Index: int(idx)})
+	return c.expectEnd()
+}
+
+// defineElem reads the element segments, the flags are chosen as:
+// passive (0x01) or declarative (0x03) when there is no offset, the explicit
+// table index (0x02) when `(table x)` is given and the expressions (0x04) when
+// the elements are given as expressions instead of function indexes
+func (m *moduleContext) defineElem(c *cursor) error {
+	c.optionalID()
+
+	element := &parser.Element{
+		Mode: parser.ElementPassive,
+		Type: parser.Type{SpecType: parser.RefType, SpecByte: parser.FUNC_REF_TYPE},
+	}
+
+	switch {
+	case c.peek().isAtom("declare"):
+		c.next()
+		element.Mode = parser.ElementDeclarative
+		element.Flags = 0x03
+	case c.peek() != nil && c.peek().list:
+		element.Mode = parser.ElementActive
+
+		if c.peek().isList("table") {
+			tc := newCursor(c.next())
+			tc.next()
+
+			tableIdx, err := m.tableSpace.resolve(tc.next())
+			if err != nil {
+				return err
+			}
+
+			if err := tc.expectEnd(); err != nil {
+				return err
+			}
+
+			element.Table = int(tableIdx)
+			element.Flags |= 0x02
+		}
+
+		offset, err := m.parseOffset(c)
+		if err != nil {
+			return err
+		}
+		element.Offset = offset
+	default:
+		element.Flags = 0x01
+	}
+
+	if err := m.parseElemList(c, element, element.Mode == parser.ElementActive); err != nil {
+		return err
+	}
+
+	m.elements = append(m.elements, element)
+	return nil
+}
+
+// parseElemList reads either `func idx*`, `reftype expr*` or, for the
+// active segments, only the function indexes `idx*`
+func (m *moduleContext) parseElemList(c *cursor, element *parser.Element, allowBareIndexes bool) error {
+	switch {
+	case c.peek().isAtom("func"):
+		c.next()
+	case c.peek().isAtom("funcref") || c.peek().isAtom("externref"):
+		refType, err := parseValueType(c.next(), c)
+		if err != nil {
+			return err
+		}
+
+		element.Type = refType
+		element.Flags |= 0x04
+		element.Init = make([][]byte, 0)
+
+		for !c.done() {
+			item := c.next()
+			if !item.list {
+				return newSyntaxError(item.token.pos, fmt.Errorf("%w: %s, expected element expression", ErrUnexpectedToken, item))
+			}
+
+			ic := &cursor{nodes: []*node{item}, end: item.token.pos}
+			if item.isList("item") {
+				ic = newCursor(item)
+				ic.next()
+			}
+
+			expr, err := m.parseConstantExpression(ic)
+			if err != nil {
+				return err
+			}
+
+			element.Init = append(element.Init, expr)
+		}
+
+		return nil
+	case !allowBareIndexes && !c.done():
+		return c.unexpected("func or reference type")
+	}
+
+	element.FuncIndices = make([]int, 0)
+	for !c.done() {
+		funcIdx, err := m.funcSpace.resolve(c.next())
+		if err != nil {
+			return err
+		}
+
+		element.FuncIndices = append(element.FuncIndices, int(funcIdx))
+	}
+
+	return nil
+}
+
+// parseOffset reads `(offset instr*)` or its abbreviation, a single folded instruction
+func (m *moduleContext) parseOffset(c *cursor) ([]byte, error) {
+	offset := c.next()
+	if offset == nil || !offset.list {
+		return nil, c.unexpected("offset expression")
+	}
+
+	oc := &cursor{nodes: []*node{offset}, end: offset.token.pos}
+	if offset.isList("offset") {
+		oc = newCursor(offset)
+		oc.next()
+	}
+
+	return m.parseConstantExpression(oc)
+}
+
+func (m *moduleContext) defineData(c *cursor) error {
+	c.optionalID()
+
+	segment := &parser.Data{Mode: parser.DataPassive, Flags: 0x01}
+
+	if c.peek() != nil && c.peek().list {
+		segment.Mode = parser.DataActive
+		segment.Flags = 0x00
+
+		if c.peek().isList("memory") {
+			mc := newCursor(c.next())
+			mc.next()
+
+			memIdx, err := m.memorySpace.resolve(mc.next())
+			if err != nil {
+				return err
+			}
+
+			if err := mc.expectEnd(); err != nil {
+				return err
+			}
+
+			segment.Memory = int(memIdx)
+			if memIdx != 0 {
+				segment.Flags = 0x02
+			}
+		}
+
+		offset, err := m.parseOffset(c)
+		if err != nil {
+			return err
+		}
+		segment.Offset = offset
+	}
+
+	init, err := parseDataStrings(c)
+	if err != nil {
+		return err
+	}
+
+	segment.Init = init
+	m.data = append(m.data, segment)
+	return nil
+}
+
+func parseDataStrings(c *cursor) ([]byte, error) {
+	init := make([]byte, 0)
+	for !c.done() {
+		str, err := c.expectString("data string")
+		if err != nil {
+			return nil, err
+		}
+
+		init = append(init, str...)
+	}
+
+	return init, nil
+}
+
+// defineCustom reads the `(@custom "name" (placement)? "data"*)` annotation,
+// the custom sections are always placed at the end of the module
+func (m *moduleContext) defineCustom(c *cursor) error {
+	name, err := c.expectString("custom section name")
+	if err != nil {
+		return err
+	}
+
+	if c.peek().isList("before") || c.peek().isList("after") {
+		c.next()
+	}
+
+	data, err := parseDataStrings(c)
+	if err != nil {
+		return err
+	}
+
+	m.customs = append(m.customs, &parser.Custom{Name: name, Data: data})
+	return nil
+}
+
+// parseTypeUse reads `(type x)? (param ...)* (result ...)*`, without an explicit type
+// the first equal type of the module is used or a new one is added to the module
+func (m *moduleContext) parseTypeUse(c *cursor) (uint32, []string, error) {
+	explicit := false
+	typeIdx := uint32(0)
+
+	if c.peek().isList("type") {
+		tc := newCursor(c.next())
+		tc.next()
+
+		idx, err := m.typeSpace.resolve(tc.next())
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if err := tc.expectEnd(); err != nil {
+			return 0, nil, err
+		}
+
+		if int(idx) >= len(m.types) {
+			return 0, nil, newSyntaxError(tc.end, fmt.Errorf("%w: type %d", ErrUnknownIdentifier, idx))
+		}
+
+		explicit, typeIdx = true, idx
+	}
+
+	usePos := c.position()
+	params, paramIDs, err := parseParams(c)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	results, err := parseResults(c)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	signature := parser.NewFunctionSignature(params, results)
+
+	if explicit {
+		hasInline := len(params) > 0 || len(results) > 0
+		if hasInline && !m.types[typeIdx].Equal(signature) {
+			return 0, nil, newSyntaxError(usePos, fmt.Errorf("%w: %s, type %d is %s",
+				ErrTypeUseMismatch, signature, typeIdx, m.types[typeIdx]))
+		}
+
+		return typeIdx, paramIDs, nil
+	}
+
+	return m.typeIndex(signature), paramIDs, nil
+}
+
+// typeIndex returns the index of the first type equal to signature, adding it when missing
+func (m *moduleContext) typeIndex(signature *parser.FunctionSignatureParser) uint32 {
+	for idx, t := range m.types {
+		if t.Equal(signature) {
+			return uint32(idx)
+		}
+	}
+
+	m.types = append(m.types, signature)
+	m.typeSpace.count++
+	return uint32(len(m.types) - 1)
+}
+
+// parseParams reads the `(param $id type)` and `(param type*)` declarations
+func parseParams(c *cursor) ([]parser.Type, []string, error) {
+	params := make([]parser.Type, 0)
+	ids := make([]string, 0)
+
+	for c.peek().isList("param") {
+		pc := newCursor(c.next())
+		pc.next()
+
+		if id := pc.optionalID(); id != "" {
+			paramType, err := parseValueType(pc.next(), pc)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if err := pc.expectEnd(); err != nil {
+				return nil, nil, err
+			}
+
+			params = append(params, paramType)
+			ids = append(ids, id)
+			continue
+		}
+
+		for !pc.done() {
+			paramType, err := parseValueType(pc.next(), pc)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			params = append(params, paramType)
+			ids = append(ids, "")
+		}
+	}
+
+	return params, ids, nil
+}
+
+func parseResults(c *cursor) ([]parser.Type, error) {
+	results := make([]parser.Type, 0)
+
+	for c.peek().isList("result") {
+		rc := newCursor(c.next())
+		rc.next()
+
+		for !rc.done() {
+			resultType, err := parseValueType(rc.next(), rc)
+			if err != nil {
+				return nil, err
+			}
+
+			results = append(results, resultType)
+		}
+	}
+
+	return results, nil
+}
+
+// parseValueType returns the type named by the node, c is used to report a missing node
+func parseValueType(n *node, c *cursor) (parser.Type, error) {
+	if n == nil {
+		return parser.Type{}, c.unexpected("value type")
+	}
+
+	if !n.list && n.token.kind == tokenAtom {
+		switch n.token.text {
+		case "i32":
+			return parser.I32, nil
+		case "i64":
+			return parser.I64, nil
+		case "f32":
+			return parser.F32, nil
+		case "f64":
+			return parser.F64, nil
+		case "v128":
+			return parser.Type{SpecType: parser.VecType, SpecByte: parser.VEC_TYPE}, nil
+		case "funcref":
+			return parser.Type{SpecType: parser.RefType, SpecByte: parser.FUNC_REF_TYPE}, nil
+		case "externref":
+			return parser.Type{SpecType: parser.RefType, SpecByte: parser.EXTERN_REF_TYPE}, nil
+		}
+	}
+
+	return parser.Type{}, newSyntaxError(n.token.pos, fmt.Errorf("%w: %s", ErrUnknownType, n))
+}
+
+func parseLimits(c *cursor) (parser.Limits, error) {
+	minNode, err := c.expectAtom("limits min")
+	if err != nil {
+		return parser.Limits{}, err
+	}
+
+	min, err := parseUint(minNode.token.text, 32)
+	if err != nil {
+		return parser.Limits{}, newSyntaxError(minNode.token.pos, err)
+	}
+
+	limits := parser.Limits{Min: uint32(min)}
+
+	if isIndex(c.peek()) && !c.peek().isID() {
+		maxNode := c.next()
+		max, err := parseUint(maxNode.token.text, 32)
+		if err != nil {
+			return parser.Limits{}, newSyntaxError(maxNode.token.pos, err)
+		}
+
+		limits.Max = uint32(max)
+		limits.HasMax = true
+	}
+
+	return limits, nil
+}
+
+func parseTableType(c *cursor) (*parser.TableType, error) {
+	limits, err := parseLimits(c)
+	if err != nil {
+		return nil, err
+	}
+
+	elemType, err := parseValueType(c.next(), c)
+	if err != nil {
+		return nil, err
+	}
+
+	if elemType.SpecType != parser.RefType {
+		return nil, newSyntaxError(c.nodes[c.pos-1].token.pos,
+			fmt.Errorf("%w: expected reference type, got %s", ErrUnknownType, elemType))
+	}
+
+	return &parser.TableType{ElemType: elemType, Limits: limits}, nil
+}
+
+// parseGlobalType reads `type` or `(mut type)`
+func parseGlobalType(c *cursor) (*parser.GlobalType, error) {
+	if c.peek().isList("mut") {
+		mc := newCursor(c.next())
+		mc.next()
+
+		valType, err := parseValueType(mc.next(), mc)
+		if err != nil {
+			return nil, err
+		}
+
+		return &parser.GlobalType{ValType: valType, Mutable: true}, mc.expectEnd()
+	}
+
+	valType, err := parseValueType(c.next(), c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parser.GlobalType{ValType: valType}, nil
+}
+
+// binaryParser assembles the parsed module in the same model the binary decoder
+// produces, the names are only kept in the name section model and the data count
+// section is only defined when there is a memory.init or a data.drop
+func (m *moduleContext) binaryParser() *parser.BinaryParser {
+	bp := parser.NewBinaryReaderParser(bytes.NewReader(nil))
+	bp.Module.Magic = parser.MagicNumber
+	bp.Module.Version = parser.Version
+
+	bp.Module.Types = m.types
+	bp.Module.Imports = m.imports
+	bp.Module.Funcs = append(append([]*parser.Function{}, m.importedFuncs...), m.funcs...)
+	bp.Module.Tables = m.tables
+	bp.Module.Memories = m.memories
+	bp.Module.Globals = m.globals
+	bp.Module.Exports = m.exports
+	bp.Module.Start = m.start
+	bp.Module.Elements = m.elements
+	bp.Module.Data = m.data
+	bp.Module.Customs = m.customs
+
+	if m.hasNames() {
+		bp.Module.Names = m.names
+	}
+
+	if m.usesDataCount {
+		count := uint32(len(m.data))
+		bp.Module.DataCount = &count
+	}
+
+	return bp
+}
@@ -0,0 +1,288 @@
+package wat_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/EclesioMeloJunior/wasvm/leb128"
+	"github.com/EclesioMeloJunior/wasvm/parser"
+	"github.com/EclesioMeloJunior/wasvm/wat"
+
+	"github.com/stretchr/testify/require"
+)
+
+// withoutNameSection removes the name section as the
+// text format names are not encoded by the parser
+func withoutNameSection(t *testing.T, wasmBytes []byte) []byte {
+	stripped := append([]byte{}, wasmBytes[:8]...)
+
+	for offset := 8; offset < len(wasmBytes); {
+		reader := strings.NewReader(string(wasmBytes[offset+1:]))
+		read, sectionLen, err := leb128.DecodeUint32(reader)
+		require.NoError(t, err)
+
+		contentsStart := offset + 1 + read
+		sectionEnd := contentsStart + int(sectionLen)
+
+		isNameSection := false
+		if wasmBytes[offset] == parser.CustomSection {
+			_, nameLen, err := leb128.DecodeUint32(strings.NewReader(string(wasmBytes[contentsStart:])))
+			require.NoError(t, err)
+			isNameSection = nameLen == 4 && string(wasmBytes[contentsStart+1:contentsStart+5]) == "name"
+		}
+
+		if !isNameSection {
+			stripped = append(stripped, wasmBytes[offset:sectionEnd]...)
+		}
+
+		offset = sectionEnd
+	}
+
+	return stripped
+}
+
+func TestParse_Resources(t *testing.T) {
+	resources, err := filepath.Glob("../resources/*.wat")
+	require.NoError(t, err)
+	require.NotEmpty(t, resources)
+
+	for _, resource := range resources {
+		resource := resource
+		t.Run(filepath.Base(resource), func(t *testing.T) {
+			bp, err := wat.ParseFile(resource)
+			require.NoError(t, err)
+			require.NoError(t, parser.Validate(bp))
+
+			expected, err := os.ReadFile(strings.TrimSuffix(resource, ".wat") + ".wasm")
+			require.NoError(t, err)
+
+			encoded, err := parser.Encode(bp)
+			require.NoError(t, err)
+			require.Equal(t, withoutNameSection(t, expected), encoded)
+		})
+	}
+}
+
+func TestParse_Names(t *testing.T) {
+	fromText, err := wat.ParseFile("../resources/names.wat")
+	require.NoError(t, err)
+
+	fromBinary, err := parser.BinaryFormat("../resources/names.wasm")
+	require.NoError(t, err)
+
+	textNames := fromText.Module.Names
+	binaryNames := fromBinary.Module.Names
+	require.Equal(t, binaryNames, textNames)
+}
+
+func encodeText(t *testing.T, source string) []byte {
+	bp, err := wat.Parse([]byte(source))
+	require.NoError(t, err)
+	require.NoError(t, parser.Validate(bp))
+
+	encoded, err := parser.Encode(bp)
+	require.NoError(t, err)
+	return encoded
+}
+
+// each source must produce the same module as its expanded form
+func TestParse_Abbreviations(t *testing.T) {
+	tests := map[string]struct {
+		source   string
+		expanded string
+	}{
+		"folded instructions": {
+			source: `(module (func (param $a i32) (result i32)
+				(i32.add (local.get $a) (i32.mul (local.get 0) (i32.const 2)))))`,
+			expanded: `(module (func (param i32) (result i32)
+				local.get 0 local.get 0 i32.const 2 i32.mul i32.add))`,
+		},
+		"folded if": {
+			source: `(module (func (param i32) (result i32)
+				(if (result i32) (i32.lt_s (local.get 0) (i32.const 0))
+					(then (i32.const -1))
+					(else (i32.const 1)))))`,
+			expanded: `(module (func (param i32) (result i32)
+				local.get 0 i32.const 0 i32.lt_s
+				if $l (result i32) i32.const -1 else $l i32.const 1 end $l))`,
+		},
+		"inline block types with params": {
+			source: `(module (func (param i32) (result i32 i32)
+				local.get 0 (loop (param i32) (result i32 i32) (local.get 0))))`,
+			expanded: `(module
+				(type (func (param i32) (result i32 i32)))
+				(func (type 0) local.get 0 loop (type 0) local.get 0 end))`,
+		},
+		"branch labels": {
+			source: `(module (func (param i32)
+				(block $out (loop $again
+					(br_if $out (local.get 0))
+					(br_table $again $out (local.get 0))))))`,
+			expanded: `(module (func (param i32)
+				block loop local.get 0 br_if 1 local.get 0 br_table 0 1 end end))`,
+		},
+		"without the module wrapper": {
+			source:   `(func (export "f")) ;; comment (; block (; nested ;) comment ;)`,
+			expanded: `(module (func) (export "f" (func 0)))`,
+		},
+		"inline imports and exports": {
+			source: `(module
+				(func $log (import "env" "log") (param i32))
+				(global (import "env" "g") i32)
+				(memory (export "mem") 1)
+				(global (export "counter") (mut i32) (global.get 0)))`,
+			expanded: `(module
+				(type (func (param i32)))
+				(import "env" "log" (func (type 0)))
+				(import "env" "g" (global i32))
+				(memory 1)
+				(global (mut i32) global.get 0)
+				(export "mem" (memory 0))
+				(export "counter" (global 1)))`,
+		},
+		"locals declarations": {
+			source:   `(module (func (local i32 i64) (local $x i64)))`,
+			expanded: `(module (func (local i32) (local i64) (local i64)))`,
+		},
+		"memory with data": {
+			source:   `(module (memory (data "hello" "\00\ff")))`,
+			expanded: `(module (memory 1 1) (data (i32.const 0) "hello\00\ff"))`,
+		},
+		"table with elements": {
+			source:   `(module (func $f) (table funcref (elem $f $f)))`,
+			expanded: `(module (func) (table 2 2 funcref) (elem (offset i32.const 0) 0 0))`,
+		},
+		"numbers": {
+			source: `(module (func
+				i32.const 0xFFFF_FFFF drop
+				i64.const -0x8000_0000_0000_0000 drop
+				f32.const 0x1.8 drop
+				f64.const -inf drop
+				f32.const nan:0x1 drop))`,
+			expanded: `(module (func
+				i32.const -1 drop
+				i64.const -9223372036854775808 drop
+				f32.const 1.5 drop
+				f64.const -inf drop
+				(drop (f32.const nan:0x000001))))`,
+		},
+		"memory arguments": {
+			source:   `(module (memory 1) (func (i64.store offset=8 align=4 (i32.const 0) (i64.const 1))))`,
+			expanded: `(module (memory 1) (func i32.const 0 i64.const 1 i64.store align=4 offset=8))`,
+		},
+		"binary module": {
+			source:   `(module binary "\00asm" "\01\00\00\00" "\01\04\01\60\00\00" "\03\02\01\00" "\0a\04\01\02\00\0b")`,
+			expanded: `(module (func))`,
+		},
+	}
+
+	for tname, tt := range tests {
+		tt := tt
+		t.Run(tname, func(t *testing.T) {
+			require.Equal(t, encodeText(t, tt.expanded), encodeText(t, tt.source))
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := map[string]struct {
+		source string
+		err    error
+		line   int
+		column int
+	}{
+		"unclosed list": {
+			source: "(module\n  (func",
+			err:    wat.ErrUnexpectedEnd,
+			line:   2, column: 3,
+		},
+		"unknown instruction": {
+			source: "(module (func\n  i32.foo))",
+			err:    wat.ErrUnknownInstruction,
+			line:   2, column: 3,
+		},
+		"unknown local": {
+			source: "(module (func (param $a i32)\n  local.get $b drop))",
+			err:    wat.ErrUnknownIdentifier,
+			line:   2, column: 13,
+		},
+		"unknown label": {
+			source: "(module (func block $a end\n  br $a))",
+			err:    wat.ErrUnknownIdentifier,
+			line:   2, column: 6,
+		},
+		"duplicate function": {
+			source: "(module (func $f)\n(func $f))",
+			err:    wat.ErrDuplicateIdentifier,
+			line:   2, column: 1,
+		},
+		"import after definition": {
+			source: "(module (func)\n  (import \"env\" \"f\" (func)))",
+			err:    wat.ErrImportAfterDefinition,
+			line:   2, column: 21,
+		},
+		"i32 out of range": {
+			source: "(module (func i32.const 0x1_0000_0000 drop))",
+			err:    wat.ErrInvalidNumber,
+			line:   1, column: 25,
+		},
+		"type use mismatch": {
+			source: "(module (type (func))\n  (func (type 0) (param i32)))",
+			err:    wat.ErrTypeUseMismatch,
+			line:   2, column: 18,
+		},
+		"mismatching label": {
+			source: "(module (func block $a end $b))",
+			err:    wat.ErrLabelMismatch,
+			line:   1, column: 28,
+		},
+		"invalid escape": {
+			source: `(module (data "\q"))`,
+			err:    wat.ErrInvalidEscape,
+			line:   1, column: 17,
+		},
+	}
+
+	for tname, tt := range tests {
+		tt := tt
+		t.Run(tname, func(t *testing.T) {
+			_, err := wat.Parse([]byte(tt.source))
+			require.ErrorIs(t, err, tt.err)
+
+			var syntaxErr *wat.SyntaxError
+			require.ErrorAs(t, err, &syntaxErr)
+			require.Equal(t, tt.line, syntaxErr.Line)
+			require.Equal(t, tt.column, syntaxErr.Column)
+		})
+	}
+}
+
+// FuzzParse makes sure malformed sources are reported as errors instead of panics
+func FuzzParse(f *testing.F) {
+	resources, err := filepath.Glob("../resources/*.wat")
+	require.NoError(f, err)
+
+	for _, resource := range resources {
+		source, err := os.ReadFile(resource)
+		require.NoError(f, err)
+		f.Add(source)
+	}
+
+	f.Fuzz(func(t *testing.T, source []byte) {
+		bp, err := wat.Parse(source)
+		if err != nil {
+			var syntaxErr *wat.SyntaxError
+			var decodeErr *parser.DecodeError
+			require.True(t, errors.As(err, &syntaxErr) || errors.As(err, &decodeErr), err.Error())
+			return
+		}
+
+		if parser.Validate(bp) == nil {
+			_, err = parser.Encode(bp)
+			require.NoError(t, err)
+		}
+	})
+}